@@ -0,0 +1,122 @@
+package rest
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/go-chassis/go-chassis/v2/core/client"
+	"github.com/go-chassis/openlog"
+)
+
+// setNoDelay is replaced in test to verify socket option is set
+var setNoDelay = func(c *net.TCPConn, noDelay bool) error {
+	return c.SetNoDelay(noDelay)
+}
+
+// dialer sets tcp socket options on new connections
+type dialer struct {
+	net.Dialer
+	noDelay bool
+	// network pins address family, tcp4 or tcp6, empty means dual stack
+	network string
+	dial    func(ctx context.Context, network, addr string) (net.Conn, error)
+	// maxDials limits dials in progress to each address, 0 means no limit
+	maxDials  int
+	dialingMu sync.Mutex
+	dialing   map[string]chan struct{}
+}
+
+func newDialer(opts client.Options) *dialer {
+	d := &dialer{
+		Dialer: net.Dialer{
+			KeepAlive: DefaultKeepAliveSecond,
+			Timeout:   DefaultTimeoutBySecond,
+		},
+		// go enables TCP_NODELAY by default
+		noDelay: !opts.DisableTCPNoDelay,
+	}
+	if opts.TCPKeepAlive != 0 {
+		d.KeepAlive = opts.TCPKeepAlive
+	}
+	switch opts.Network {
+	case "tcp4", "tcp6":
+		d.network = opts.Network
+	case "", "tcp":
+	default:
+		openlog.Warn(fmt.Sprintf("unknown network [%s], use dual stack tcp", opts.Network))
+	}
+	if opts.MaxConcurrentDials > 0 {
+		d.maxDials = opts.MaxConcurrentDials
+		d.dialing = make(map[string]chan struct{})
+	}
+	d.dial = d.Dialer.DialContext
+	return d
+}
+
+// acquire waits for a dial slot of addr until ctx is done,
+// meanwhile transport can give the request a connection which another dial makes
+func (d *dialer) acquire(ctx context.Context, addr string) (release func(), err error) {
+	if d.maxDials <= 0 {
+		return func() {}, nil
+	}
+	d.dialingMu.Lock()
+	slots, ok := d.dialing[addr]
+	if !ok {
+		slots = make(chan struct{}, d.maxDials)
+		d.dialing[addr] = slots
+	}
+	d.dialingMu.Unlock()
+	select {
+	case slots <- struct{}{}:
+		return func() { <-slots }, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// connectTimeoutKey is the context key of connect timeout of a call
+type connectTimeoutKey struct{}
+
+// withConnectTimeout makes dials for request of ctx fail if connection is not established in timeout,
+// it includes the wait for a dial slot
+func withConnectTimeout(ctx context.Context, timeout time.Duration) context.Context {
+	return context.WithValue(ctx, connectTimeoutKey{}, timeout)
+}
+
+// DialContext dials addr and sets socket options, a connection dialed in advance is used if there is one
+func (d *dialer) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	if conn := takeWarmed(addr); conn != nil {
+		return conn, nil
+	}
+	return d.dialConn(ctx, network, addr)
+}
+
+func (d *dialer) dialConn(ctx context.Context, network, addr string) (net.Conn, error) {
+	if d.network != "" && network == "tcp" {
+		network = d.network
+	}
+	if timeout, ok := ctx.Value(connectTimeoutKey{}).(time.Duration); ok && timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	release, err := d.acquire(ctx, addr)
+	if err != nil {
+		return nil, err
+	}
+	conn, err := d.dial(ctx, network, addr)
+	release()
+	if err != nil {
+		return nil, asDNSError(err)
+	}
+	if tc, ok := conn.(*net.TCPConn); ok {
+		if err := setNoDelay(tc, d.noDelay); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+	return newCountedConn(conn, addr), nil
+}
@@ -0,0 +1,317 @@
+package rest
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-chassis/go-archaius"
+	"github.com/go-chassis/go-chassis/v2/core/client"
+	"github.com/go-chassis/go-chassis/v2/core/common"
+	"github.com/go-chassis/go-chassis/v2/core/invocation"
+	"github.com/go-chassis/go-chassis/v2/pkg/metrics"
+	"github.com/go-chassis/go-chassis/v2/pkg/util/httputil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewDialer(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	defer l.Close()
+	go func() {
+		for {
+			c, err := l.Accept()
+			if err != nil {
+				return
+			}
+			c.Close()
+		}
+	}()
+	var got []bool
+	old := setNoDelay
+	setNoDelay = func(c *net.TCPConn, noDelay bool) error {
+		got = append(got, noDelay)
+		return old(c, noDelay)
+	}
+	defer func() { setNoDelay = old }()
+
+	t.Run("defaults keep current behavior", func(t *testing.T) {
+		got = nil
+		d := newDialer(client.Options{})
+		assert.Equal(t, DefaultKeepAliveSecond, d.KeepAlive)
+		c, err := d.DialContext(context.TODO(), "tcp", l.Addr().String())
+		assert.NoError(t, err)
+		c.Close()
+		assert.Equal(t, []bool{true}, got)
+	})
+	t.Run("disable no delay and set keep alive", func(t *testing.T) {
+		got = nil
+		d := newDialer(client.Options{DisableTCPNoDelay: true, TCPKeepAlive: 15 * time.Second})
+		assert.Equal(t, 15*time.Second, d.KeepAlive)
+		c, err := d.DialContext(context.TODO(), "tcp", l.Addr().String())
+		assert.NoError(t, err)
+		c.Close()
+		assert.Equal(t, []bool{false}, got)
+	})
+	t.Run("negative keep alive disables it", func(t *testing.T) {
+		d := newDialer(client.Options{TCPKeepAlive: -1})
+		assert.True(t, d.KeepAlive < 0)
+	})
+}
+
+func TestNewDialer_Network(t *testing.T) {
+	dialed := func(opts client.Options) string {
+		var network string
+		d := newDialer(opts)
+		d.dial = func(ctx context.Context, n, addr string) (net.Conn, error) {
+			network = n
+			return nil, errors.New("mock dialer")
+		}
+		_, err := d.DialContext(context.TODO(), "tcp", "example.com:80")
+		assert.Error(t, err)
+		return network
+	}
+	assert.Equal(t, "tcp", dialed(client.Options{}))
+	assert.Equal(t, "tcp", dialed(client.Options{Network: "tcp"}))
+	assert.Equal(t, "tcp4", dialed(client.Options{Network: "tcp4"}))
+	assert.Equal(t, "tcp6", dialed(client.Options{Network: "tcp6"}))
+	assert.Equal(t, "tcp", dialed(client.Options{Network: "udp"}), "unknown network falls back to dual stack")
+
+	t.Run("tcp4 never dials ipv6 address", func(t *testing.T) {
+		d := newDialer(client.Options{Network: "tcp4"})
+		_, err := d.DialContext(context.TODO(), "tcp", "[::1]:80")
+		if assert.Error(t, err) {
+			assert.Contains(t, err.Error(), "no suitable address")
+		}
+		d = newDialer(client.Options{})
+		_, err = d.DialContext(context.TODO(), "tcp", "[::1]:80")
+		if err != nil {
+			assert.NotContains(t, err.Error(), "no suitable address", "dual stack dials ipv6 address")
+		}
+	})
+}
+
+func TestClient_MaxConcurrentDials(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer s.Close()
+	addr := strings.TrimPrefix(s.URL, "http://")
+	c, _ := NewRestClient(client.Options{MaxConcurrentDials: 2})
+	var dialing, maxDialing, dials int32
+	d := c.(*Client).dialer
+	d.dial = func(ctx context.Context, network, addr string) (net.Conn, error) {
+		n := atomic.AddInt32(&dialing, 1)
+		defer atomic.AddInt32(&dialing, -1)
+		atomic.AddInt32(&dials, 1)
+		for {
+			m := atomic.LoadInt32(&maxDialing)
+			if n <= m || atomic.CompareAndSwapInt32(&maxDialing, m, n) {
+				break
+			}
+		}
+		time.Sleep(50 * time.Millisecond)
+		return net.Dial(network, addr)
+	}
+
+	var wg sync.WaitGroup
+	for n := 0; n < 20; n++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			r, _ := NewRequest("GET", "http://Server/", nil)
+			resp := NewResponse()
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			err := c.Call(ctx, addr, &invocation.Invocation{MicroServiceName: "Server", Args: r}, resp)
+			assert.NoError(t, err)
+			resp.Body.Close()
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, int32(2), atomic.LoadInt32(&maxDialing))
+	assert.True(t, atomic.LoadInt32(&dials) < 20, "requests reuse connections of finished dials")
+
+	t.Run("waiting dial gives up when context is done", func(t *testing.T) {
+		d := newDialer(client.Options{MaxConcurrentDials: 1})
+		release, err := d.acquire(context.TODO(), "example.com:80")
+		assert.NoError(t, err)
+		defer release()
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+		_, err = d.DialContext(ctx, "tcp", "example.com:80")
+		assert.Equal(t, context.DeadlineExceeded, err)
+	})
+}
+
+func TestDialer_DNSError(t *testing.T) {
+	d := newDialer(client.Options{})
+	dial := func(dnsErr *net.DNSError) *DNSError {
+		d.dial = func(ctx context.Context, network, addr string) (net.Conn, error) {
+			return nil, &net.OpError{Op: "dial", Net: network, Err: dnsErr}
+		}
+		_, err := d.DialContext(context.TODO(), "tcp", "host.local:80")
+		var got *DNSError
+		assert.True(t, errors.As(err, &got), "got %v", err)
+		return got
+	}
+	nx := dial(&net.DNSError{Err: "no such host", Name: "host.local", IsNotFound: true})
+	assert.True(t, nx.Permanent)
+	assert.True(t, nx.NotFound())
+	assert.False(t, nx.Temporary())
+	servfail := dial(&net.DNSError{Err: "server misbehaving", Name: "host.local", IsTemporary: true})
+	assert.False(t, servfail.Permanent)
+	assert.True(t, servfail.Temporary())
+	assert.Equal(t, "host.local", servfail.Host)
+
+	d.dial = func(ctx context.Context, network, addr string) (net.Conn, error) {
+		return nil, errors.New("connection refused")
+	}
+	_, err := d.DialContext(context.TODO(), "tcp", "host.local:80")
+	var dnsErr *DNSError
+	assert.False(t, errors.As(err, &dnsErr))
+}
+
+func TestClient_ConnectTimeout(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(300 * time.Millisecond)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer s.Close()
+	c, _ := NewRestClient(client.Options{})
+	d := c.(*Client).dialer
+	d.dial = func(ctx context.Context, network, addr string) (net.Conn, error) {
+		if addr == "10.0.0.99:8080" {
+			// packets to unreachable host are dropped, dial waits until it times out
+			<-ctx.Done()
+			return nil, &net.OpError{Op: "dial", Net: network, Err: ctx.Err()}
+		}
+		return net.Dial(network, addr)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	call := func(addr string) error {
+		r, _ := NewRequest("GET", "http://Server/", nil)
+		inv := &invocation.Invocation{MicroServiceName: "Server", Args: r}
+		inv.SetMetadata(common.ConnectTimeoutKey, 100*time.Millisecond)
+		resp := NewResponse()
+		err := c.Call(ctx, addr, inv, resp)
+		if err == nil {
+			resp.Body.Close()
+		}
+		return err
+	}
+	start := time.Now()
+	err := call("10.0.0.99:8080")
+	assert.True(t, time.Since(start) < time.Second, "connect fails fast")
+	var timeoutErr *TimeoutError
+	if assert.True(t, errors.As(err, &timeoutErr), "got %v", err) {
+		assert.Equal(t, PhaseDial, timeoutErr.Phase)
+		assert.True(t, timeoutErr.DialFailed())
+	}
+	assert.NoError(t, call(strings.TrimPrefix(s.URL, "http://")), "slow call is served within total budget")
+}
+
+func TestClient_OpenConnections(t *testing.T) {
+	archaius.Init(archaius.WithMemorySource())
+	if !metrics.Enabled() {
+		assert.NoError(t, metrics.Init())
+	}
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(50 * time.Millisecond)
+		w.Write([]byte("ok"))
+	}))
+	defer s.Close()
+	addr := strings.TrimPrefix(s.URL, "http://")
+	c, _ := NewRestClient(client.Options{})
+	call := func(close bool) {
+		r, _ := NewRequest("GET", "http://Server/", nil)
+		httputil.SetConnectionClose(r, close)
+		resp := NewResponse()
+		assert.NoError(t, c.Call(context.TODO(), addr, &invocation.Invocation{MicroServiceName: "Server", Args: r}, resp))
+		httputil.ReadBody(resp)
+	}
+	gauge := func(name string) float64 {
+		mfs, err := metrics.GetSystemPrometheusRegistry().Gather()
+		assert.NoError(t, err)
+		for _, mf := range mfs {
+			if mf.GetName() != name {
+				continue
+			}
+			for _, m := range mf.GetMetric() {
+				if name == MetricOpenConnectionsAll || m.GetLabel()[0].GetValue() == addr {
+					return m.GetGauge().GetValue()
+				}
+			}
+		}
+		return -1
+	}
+	open := func(n int64) func() bool {
+		return func() bool { return OpenConnections(addr) == n }
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 2; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			call(false)
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, int64(2), OpenConnections(addr), "concurrent calls dial 2 connections")
+	assert.Equal(t, float64(2), gauge(MetricOpenConnections))
+	// connections of other tests may be open too
+	assert.True(t, OpenConnections("") >= 2)
+	assert.True(t, gauge(MetricOpenConnectionsAll) >= 2)
+
+	call(true)
+	assert.Eventually(t, open(1), time.Second, 10*time.Millisecond, "connection is closed after call")
+	assert.Equal(t, float64(1), gauge(MetricOpenConnections))
+
+	c.(*Client).c.CloseIdleConnections()
+	assert.Eventually(t, open(0), time.Second, 10*time.Millisecond, "idle connections are evicted")
+	assert.Equal(t, float64(0), gauge(MetricOpenConnections))
+}
+
+func TestWarmer_ConcurrentWarm(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	defer l.Close()
+	var accepted int32
+	go func() {
+		for {
+			c, err := l.Accept()
+			if err != nil {
+				return
+			}
+			atomic.AddInt32(&accepted, 1)
+			defer c.Close()
+		}
+	}()
+	addr := l.Addr().String()
+	defer dropWarmed(addr)
+
+	w := &warmer{d: newDialer(client.Options{}), n: 2}
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			w.warm(addr)
+		}()
+	}
+	wg.Wait()
+	warmedMu.Lock()
+	assert.Len(t, warmed[addr], 2, "concurrent adds of endpoint do not warm more than n connections")
+	assert.Zero(t, dialing[addr])
+	warmedMu.Unlock()
+	assert.Eventually(t, func() bool { return atomic.LoadInt32(&accepted) == 2 }, time.Second, 10*time.Millisecond)
+}
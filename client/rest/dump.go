@@ -0,0 +1,61 @@
+package rest
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httputil"
+)
+
+// redacted replaces values of sensitive headers in dump
+const redacted = "******"
+
+// SensitiveHeaders will be redacted in request and response dump
+var SensitiveHeaders = []string{"Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie"}
+
+func redactHeader(h http.Header) http.Header {
+	clone := make(http.Header, len(h))
+	for k, v := range h {
+		clone[k] = v
+	}
+	for _, k := range SensitiveHeaders {
+		if _, ok := clone[http.CanonicalHeaderKey(k)]; ok {
+			clone.Set(k, redacted)
+		}
+	}
+	return clone
+}
+
+// dumpRequest dumps request in wire format, request body is kept for sending,
+// if redact is true, values of SensitiveHeaders are replaced
+func dumpRequest(req *http.Request, redact bool) ([]byte, error) {
+	clone := req.Clone(req.Context())
+	if redact {
+		clone.Header = redactHeader(req.Header)
+	}
+	if req.Body != nil && req.Body != http.NoBody {
+		body, err := ioutil.ReadAll(req.Body)
+		if err != nil {
+			return nil, err
+		}
+		req.Body.Close()
+		req.Body = ioutil.NopCloser(bytes.NewReader(body))
+		clone.Body = ioutil.NopCloser(bytes.NewReader(body))
+	}
+	return httputil.DumpRequestOut(clone, true)
+}
+
+// dumpResponse dumps response in wire format, response body is kept for reading,
+// if redact is true, values of SensitiveHeaders are replaced
+func dumpResponse(resp *http.Response, redact bool) ([]byte, error) {
+	if resp == nil || resp.StatusCode == 0 {
+		return nil, nil
+	}
+	clone := *resp
+	if redact {
+		clone.Header = redactHeader(resp.Header)
+	}
+	b, err := httputil.DumpResponse(&clone, true)
+	resp.Body = clone.Body
+	return b, err
+}
@@ -0,0 +1,159 @@
+package rest
+
+import (
+	"compress/gzip"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// ErrUnsupportedContentEncoding means no decoder is installed for a content encoding
+var ErrUnsupportedContentEncoding = errors.New("unsupported content encoding")
+
+// ErrDecompressedBodyTooLarge is returned by reading a decompressed body which exceeds the limit of call
+var ErrDecompressedBodyTooLarge = errors.New("decompressed body is too large")
+
+// ContentDecoder returns the decoded reader of a compressed body
+type ContentDecoder func(r io.Reader) (io.ReadCloser, error)
+
+var (
+	// gzip and deflate are built in, br and zstd need a third party decoder, see InstallContentDecoder
+	contentDecoders = map[string]ContentDecoder{
+		"gzip": func(r io.Reader) (io.ReadCloser, error) {
+			return gzip.NewReader(r)
+		},
+		"deflate": func(r io.Reader) (io.ReadCloser, error) {
+			return zlib.NewReader(r)
+		},
+	}
+	contentDecodersMu sync.RWMutex
+)
+
+// InstallContentDecoder installs decoder of a content encoding, such as br or zstd,
+// so that it can be used in core.WithAcceptEncodings
+func InstallContentDecoder(encoding string, d ContentDecoder) {
+	contentDecodersMu.Lock()
+	contentDecoders[strings.ToLower(encoding)] = d
+	contentDecodersMu.Unlock()
+}
+
+func getContentDecoder(encoding string) ContentDecoder {
+	contentDecodersMu.RLock()
+	d := contentDecoders[strings.ToLower(encoding)]
+	contentDecodersMu.RUnlock()
+	return d
+}
+
+// setAcceptEncoding sets Accept-Encoding of request, every encoding must have a decoder.
+// since header is set explicitly, transport does not decompress response, decodeBody does
+func setAcceptEncoding(req *http.Request, encodings []string) error {
+	for _, e := range encodings {
+		if getContentDecoder(e) == nil {
+			return fmt.Errorf("%w [%s]", ErrUnsupportedContentEncoding, e)
+		}
+	}
+	req.Header.Set("Accept-Encoding", strings.Join(encodings, ", "))
+	return nil
+}
+
+// decodeBody decodes body of response by its Content-Encoding, encodings are undone in reverse order.
+// server may not follow Accept-Encoding, any encoding with a decoder is decoded,
+// if an encoding has no decoder, body is closed and ErrUnsupportedContentEncoding is returned
+func decodeBody(resp *http.Response) error {
+	ce := resp.Header.Get("Content-Encoding")
+	if ce == "" || resp.Body == nil || resp.Body == http.NoBody {
+		return nil
+	}
+	encodings := strings.Split(ce, ",")
+	decoders := make([]ContentDecoder, 0, len(encodings))
+	for n := len(encodings) - 1; n >= 0; n-- {
+		e := strings.TrimSpace(encodings[n])
+		if e == "" || strings.EqualFold(e, "identity") {
+			continue
+		}
+		d := getContentDecoder(e)
+		if d == nil {
+			resp.Body.Close()
+			return fmt.Errorf("%w [%s] of response", ErrUnsupportedContentEncoding, e)
+		}
+		decoders = append(decoders, d)
+	}
+	body := &decodedBody{raw: resp.Body}
+	var r io.Reader = resp.Body
+	for _, d := range decoders {
+		rc, err := d(r)
+		if err != nil {
+			body.Close()
+			return err
+		}
+		body.decoders = append(body.decoders, rc)
+		r = rc
+	}
+	body.r = r
+	resp.Body = body
+	resp.Header.Del("Content-Encoding")
+	resp.Header.Del("Content-Length")
+	resp.ContentLength = -1
+	resp.Uncompressed = true
+	return nil
+}
+
+// decodedBody reads decoded body, and closes decoders and raw body
+type decodedBody struct {
+	r        io.Reader
+	raw      io.ReadCloser
+	decoders []io.ReadCloser
+}
+
+func (b *decodedBody) Read(p []byte) (int, error) {
+	return b.r.Read(p)
+}
+
+func (b *decodedBody) Close() error {
+	for _, d := range b.decoders {
+		d.Close()
+	}
+	return b.raw.Close()
+}
+
+// limitDecodedBody limits body of response to limit bytes if it is decompressed,
+// by decodeBody or by transport, so that a small compressed body can not expand without bound
+func limitDecodedBody(resp *http.Response, limit int64) {
+	if !resp.Uncompressed || resp.Body == nil || resp.Body == http.NoBody {
+		return
+	}
+	resp.Body = &limitedBody{rc: resp.Body, limit: limit, remaining: limit}
+}
+
+// limitedBody fails with ErrDecompressedBodyTooLarge once more than limit bytes are read
+type limitedBody struct {
+	rc        io.ReadCloser
+	limit     int64
+	remaining int64
+}
+
+func (b *limitedBody) Read(p []byte) (int, error) {
+	if b.remaining < 0 {
+		return 0, fmt.Errorf("%w, limit is %d bytes", ErrDecompressedBodyTooLarge, b.limit)
+	}
+	// read one more byte to tell if body exceeds limit
+	if int64(len(p)) > b.remaining+1 {
+		p = p[:b.remaining+1]
+	}
+	n, err := b.rc.Read(p)
+	if int64(n) <= b.remaining {
+		b.remaining -= int64(n)
+		return n, err
+	}
+	n = int(b.remaining)
+	b.remaining = -1
+	return n, fmt.Errorf("%w, limit is %d bytes", ErrDecompressedBodyTooLarge, b.limit)
+}
+
+func (b *limitedBody) Close() error {
+	return b.rc.Close()
+}
@@ -0,0 +1,138 @@
+package rest
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chassis/go-chassis/v2/core/client"
+	"github.com/go-chassis/go-chassis/v2/core/common"
+	"github.com/go-chassis/go-chassis/v2/core/invocation"
+	"github.com/go-chassis/go-chassis/v2/pkg/util/httputil"
+	"github.com/stretchr/testify/assert"
+)
+
+// upperCase decodes fake encoding x-upper, it is installed as br or zstd decoders would be
+func upperCase(r io.Reader) (io.ReadCloser, error) {
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	return ioutil.NopCloser(bytes.NewReader(bytes.ToLower(b))), nil
+}
+
+func TestClient_AcceptEncodings(t *testing.T) {
+	const body = "hello, compressed world"
+	var acceptEncoding string
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		acceptEncoding = r.Header.Get("Accept-Encoding")
+		encoding := r.URL.Query().Get("encoding")
+		w.Header().Set("Content-Encoding", encoding)
+		switch encoding {
+		case "gzip":
+			zw := gzip.NewWriter(w)
+			zw.Write([]byte(body))
+			zw.Close()
+		case "deflate":
+			zw := zlib.NewWriter(w)
+			zw.Write([]byte(body))
+			zw.Close()
+		default:
+			w.Write([]byte(strings.ToUpper(body)))
+		}
+	}))
+	defer s.Close()
+	addr := strings.TrimPrefix(s.URL, "http://")
+	InstallContentDecoder("x-upper", upperCase)
+	c, _ := NewRestClient(client.Options{})
+	call := func(encoding string, accept []string) (*http.Response, error) {
+		r, _ := NewRequest("GET", "http://Server/?encoding="+encoding, nil)
+		inv := &invocation.Invocation{MicroServiceName: "Server", Args: r}
+		inv.SetMetadata(common.AcceptEncodingsKey, accept)
+		resp := NewResponse()
+		return resp, c.Call(context.TODO(), addr, inv, resp)
+	}
+
+	for _, encoding := range []string{"gzip", "deflate", "x-upper"} {
+		t.Run(encoding, func(t *testing.T) {
+			resp, err := call(encoding, []string{encoding, "gzip"})
+			assert.NoError(t, err)
+			assert.Equal(t, encoding+", gzip", acceptEncoding)
+			assert.Equal(t, body, string(httputil.ReadBody(resp)))
+			assert.Empty(t, resp.Header.Get("Content-Encoding"))
+			assert.True(t, resp.Uncompressed)
+		})
+	}
+	t.Run("server ignores preference", func(t *testing.T) {
+		resp, err := call("deflate", []string{"gzip"})
+		assert.NoError(t, err, "known encoding is decoded")
+		assert.Equal(t, body, string(httputil.ReadBody(resp)))
+	})
+	t.Run("unknown encoding of response", func(t *testing.T) {
+		_, err := call("compress", []string{"gzip"})
+		assert.True(t, errors.Is(err, ErrUnsupportedContentEncoding), "got %v", err)
+		var codecErr *CodecError
+		assert.True(t, errors.As(err, &codecErr))
+		assert.Contains(t, err.Error(), "[compress]")
+	})
+	t.Run("no decoder for accepted encoding", func(t *testing.T) {
+		acceptEncoding = ""
+		_, err := call("gzip", []string{"zstd"})
+		assert.True(t, errors.Is(err, ErrUnsupportedContentEncoding), "got %v", err)
+		assert.Empty(t, acceptEncoding, "request should not be sent")
+	})
+}
+
+func TestClient_MaxDecompressedSize(t *testing.T) {
+	// 64MB of zeros is about 64KB compressed
+	var bomb bytes.Buffer
+	zw := gzip.NewWriter(&bomb)
+	zw.Write(make([]byte, 64<<20))
+	zw.Close()
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Encoding", "gzip")
+		if r.URL.Query().Get("small") != "" {
+			zw := gzip.NewWriter(w)
+			zw.Write([]byte("small"))
+			zw.Close()
+			return
+		}
+		w.Write(bomb.Bytes())
+	}))
+	defer s.Close()
+	addr := strings.TrimPrefix(s.URL, "http://")
+	c, _ := NewRestClient(client.Options{})
+	read := func(path string, accept []string) (int, error) {
+		r, _ := NewRequest("GET", "http://Server"+path, nil)
+		inv := &invocation.Invocation{MicroServiceName: "Server", Args: r}
+		if accept != nil {
+			inv.SetMetadata(common.AcceptEncodingsKey, accept)
+		}
+		inv.SetMetadata(common.MaxDecompressedSizeKey, int64(1<<20))
+		resp := NewResponse()
+		if err := c.Call(context.TODO(), addr, inv, resp); err != nil {
+			return 0, err
+		}
+		defer resp.Body.Close()
+		b, err := ioutil.ReadAll(resp.Body)
+		return len(b), err
+	}
+	for name, accept := range map[string][]string{"decoded by client": {"gzip"}, "decoded by transport": nil} {
+		t.Run(name, func(t *testing.T) {
+			n, err := read("/", accept)
+			assert.True(t, errors.Is(err, ErrDecompressedBodyTooLarge), "got %v", err)
+			assert.Equal(t, 1<<20, n, "body is read up to limit")
+			n, err = read("/?small=1", accept)
+			assert.NoError(t, err)
+			assert.Equal(t, 5, n)
+		})
+	}
+}
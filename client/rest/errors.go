@@ -0,0 +1,132 @@
+package rest
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+
+	"github.com/go-chassis/go-chassis/v2/pkg/util/httputil"
+)
+
+// HTTPError is returned if response status is defined as failure, see StatusError
+type HTTPError = StatusError
+
+// TransportError is returned if request could not be sent or response could not be received,
+// such as connection refused, reset or timeout, Err is the error of transport, it may be a TimeoutError.
+// error message is the one of Err
+type TransportError struct {
+	Addr string
+	Err  error
+}
+
+// Error returns error message
+func (e *TransportError) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap returns the error of transport
+func (e *TransportError) Unwrap() error {
+	return e.Err
+}
+
+// CodecError is returned if body transformer fails, Body is "request" or "response",
+// request is not sent if it fails to transform request body
+type CodecError struct {
+	Body string
+	Err  error
+}
+
+// Error returns error message
+func (e *CodecError) Error() string {
+	return fmt.Sprintf("can not transform %s body: %s", e.Body, e.Err)
+}
+
+// Unwrap returns the error of body transformer
+func (e *CodecError) Unwrap() error {
+	return e.Err
+}
+
+// DNSError is returned if host of endpoint can not be resolved, Permanent is true if host does not exist,
+// otherwise resolving failed temporarily, such as SERVFAIL or timeout. Err is the error of dial
+type DNSError struct {
+	Host      string
+	Permanent bool
+	Err       error
+}
+
+// Error returns error message
+func (e *DNSError) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap returns the error of dial
+func (e *DNSError) Unwrap() error {
+	return e.Err
+}
+
+// NotFound tells if host does not exist, load balancer ejects the endpoint at once
+func (e *DNSError) NotFound() bool {
+	return e.Permanent
+}
+
+// Timeout tells if resolving timed out
+func (e *DNSError) Timeout() bool {
+	var dnsErr *net.DNSError
+	return errors.As(e.Err, &dnsErr) && dnsErr.IsTimeout
+}
+
+// Temporary tells if host may be resolved later
+func (e *DNSError) Temporary() bool {
+	return !e.Permanent
+}
+
+// asDNSError wraps err as DNSError if it is caused by resolving host, otherwise err is returned
+func asDNSError(err error) error {
+	var dnsErr *net.DNSError
+	if !errors.As(err, &dnsErr) {
+		return err
+	}
+	return &DNSError{Host: dnsErr.Name, Permanent: dnsErr.IsNotFound, Err: err}
+}
+
+// ErrorPeek classifies a response by the first Size bytes of its body, such as an error envelope in front of a stream,
+// Classify returns nil if response is a success, Permanent(err) if it is a failure which must not be retried,
+// any other error is a failure which can be retried. body of response still returns all bytes
+type ErrorPeek struct {
+	Size     int
+	Classify func(resp *http.Response, head []byte) error
+}
+
+func (p ErrorPeek) classify(resp *http.Response) error {
+	head, err := httputil.PeekBody(resp, p.Size)
+	if err != nil {
+		return &TransportError{Err: err}
+	}
+	return p.Classify(resp, head)
+}
+
+// PermanentError is a failure which must not be retried, even if retry is enabled
+type PermanentError struct {
+	Err error
+}
+
+// Error returns error message
+func (e *PermanentError) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap returns the failure
+func (e *PermanentError) Unwrap() error {
+	return e.Err
+}
+
+// Permanent is always true, retry stops once it sees it
+func (e *PermanentError) Permanent() bool {
+	return true
+}
+
+// Permanent marks err as a failure which must not be retried
+func Permanent(err error) error {
+	return &PermanentError{Err: err}
+}
@@ -0,0 +1,138 @@
+package rest
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chassis/go-chassis/v2/core/client"
+	"github.com/go-chassis/go-chassis/v2/core/common"
+	"github.com/go-chassis/go-chassis/v2/core/invocation"
+	"github.com/go-chassis/go-chassis/v2/pkg/util/httputil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClient_ErrorTypes(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/fail":
+			w.WriteHeader(http.StatusInternalServerError)
+		case "/slow":
+			time.Sleep(time.Second)
+		default:
+			w.Write([]byte("{not json"))
+		}
+	}))
+	defer s.Close()
+	addr := strings.TrimPrefix(s.URL, "http://")
+	c, _ := NewRestClient(client.Options{Failure: map[string]client.FailureKind{"http_500": client.RetryableFailure}})
+	call := func(ctx context.Context, addr, path string) error {
+		r, _ := NewRequest("GET", "http://ErrorServer"+path, nil)
+		return c.Call(ctx, addr, &invocation.Invocation{MicroServiceName: "ErrorServer", Args: r}, NewResponse())
+	}
+
+	t.Run("transport", func(t *testing.T) {
+		l, _ := net.Listen("tcp", "127.0.0.1:0")
+		closed := l.Addr().String()
+		l.Close()
+		err := call(context.TODO(), closed, "/")
+		var transportErr *TransportError
+		assert.True(t, errors.As(err, &transportErr), "got %v", err)
+		var httpErr *HTTPError
+		var codecErr *CodecError
+		assert.False(t, errors.As(err, &httpErr))
+		assert.False(t, errors.As(err, &codecErr))
+	})
+	t.Run("transport timeout", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		err := call(ctx, addr, "/slow")
+		var transportErr *TransportError
+		var timeoutErr *TimeoutError
+		assert.True(t, errors.As(err, &transportErr), "got %v", err)
+		assert.True(t, errors.As(err, &timeoutErr))
+		assert.True(t, errors.Is(err, client.ErrCanceled))
+	})
+	t.Run("http", func(t *testing.T) {
+		err := call(context.TODO(), addr, "/fail")
+		var httpErr *HTTPError
+		if assert.True(t, errors.As(err, &httpErr), "got %v", err) {
+			assert.Equal(t, http.StatusInternalServerError, httpErr.StatusCode)
+		}
+		var transportErr *TransportError
+		assert.False(t, errors.As(err, &transportErr))
+	})
+	t.Run("codec", func(t *testing.T) {
+		InstallBodyTransformer("ErrorServer", &BodyTransformer{
+			Response: func(contentType string, body []byte) ([]byte, error) {
+				var v interface{}
+				if err := json.Unmarshal(body, &v); err != nil {
+					return nil, err
+				}
+				return body, nil
+			},
+		})
+		defer RemoveBodyTransformer("ErrorServer")
+		err := call(context.TODO(), addr, "/")
+		var codecErr *CodecError
+		if assert.True(t, errors.As(err, &codecErr), "got %v", err) {
+			assert.Equal(t, "response", codecErr.Body)
+		}
+		var syntaxErr *json.SyntaxError
+		assert.True(t, errors.As(err, &syntaxErr))
+		var transportErr *TransportError
+		assert.False(t, errors.As(err, &transportErr))
+	})
+}
+
+func TestClient_ErrorPeek(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.URL.Query().Get("marker") + "\n"))
+		w.(http.Flusher).Flush()
+		for n := 0; n < 3; n++ {
+			w.Write([]byte(`{"record":1}` + "\n"))
+			w.(http.Flusher).Flush()
+		}
+	}))
+	defer s.Close()
+	addr := strings.TrimPrefix(s.URL, "http://")
+	c, _ := NewRestClient(client.Options{})
+	errUpstream := errors.New("upstream error envelope")
+	peek := ErrorPeek{Size: 4, Classify: func(resp *http.Response, head []byte) error {
+		if string(head) == "ERR:" {
+			return errUpstream
+		}
+		return nil
+	}}
+	call := func(marker string) (*http.Response, error) {
+		r, _ := NewRequest("GET", "http://Server/?marker="+marker, nil)
+		inv := &invocation.Invocation{MicroServiceName: "Server", Args: r}
+		inv.SetMetadata(common.ErrorPeekKey, peek)
+		resp := NewResponse()
+		return resp, c.Call(context.TODO(), addr, inv, resp)
+	}
+
+	resp, err := call("ERR:42")
+	assert.Equal(t, errUpstream, err)
+	body := string(httputil.ReadBody(resp))
+	assert.True(t, strings.HasPrefix(body, "ERR:42\n"), body)
+
+	resp, err = call("OK:0")
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+	r := bufio.NewReader(resp.Body)
+	line, _ := r.ReadString('\n')
+	assert.Equal(t, "OK:0\n", line, "peeked bytes are read first")
+	for n := 0; n < 3; n++ {
+		line, err = r.ReadString('\n')
+		assert.NoError(t, err)
+		assert.Equal(t, `{"record":1}`+"\n", line)
+	}
+}
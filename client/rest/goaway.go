@@ -0,0 +1,78 @@
+package rest
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/go-chassis/go-chassis/v2/pkg/util/httputil"
+	"github.com/go-chassis/openlog"
+)
+
+// GoAwayError means server sent HTTP/2 GOAWAY while request was in flight,
+// request is not retried because it is not idempotent, caller can decide to send it again
+type GoAwayError struct {
+	Err error
+}
+
+// Error returns error message
+func (e *GoAwayError) Error() string {
+	return "connection is going away: " + e.Err.Error()
+}
+
+// Unwrap returns the transport error
+func (e *GoAwayError) Unwrap() error {
+	return e.Err
+}
+
+// Temporary tells request can be retried on a new connection
+func (e *GoAwayError) Temporary() bool {
+	return true
+}
+
+// isGoAway tells if the error is caused by a HTTP/2 GOAWAY frame,
+// http2 errors are not exported by net/http, so it checks error message
+func isGoAway(err error) bool {
+	return err != nil && strings.Contains(err.Error(), "GOAWAY")
+}
+
+// do sends request, if server sends GOAWAY, the connection is drained by transport
+// and idempotent request is retried once on a new connection, see httputil.IsIdempotent.
+// if server closed the connection request is sent on after it was idle beyond threshold of
+// traceIdleConn, idle connections are closed and request is sent once again on a new one
+func (c *Client) do(hc *http.Client, req *http.Request) (*http.Response, error) {
+	resp, err := hc.Do(req)
+	if lostIdleConn(req, err) && rewindBody(req) {
+		openlog.Warn(fmt.Sprintf("idle connection to [%s] is closed by server, retry %s %s on a new connection", req.URL.Host, req.Method, req.URL.Path))
+		hc.CloseIdleConnections()
+		resp, err = hc.Do(req)
+	}
+	if !isGoAway(err) {
+		return resp, err
+	}
+	if !httputil.IsIdempotent(req) || !rewindBody(req) {
+		return nil, &GoAwayError{Err: err}
+	}
+	openlog.Warn(fmt.Sprintf("server [%s] is going away, retry %s %s on a new connection", req.URL.Host, req.Method, req.URL.Path))
+	resp, err = hc.Do(req)
+	if isGoAway(err) {
+		return nil, &GoAwayError{Err: err}
+	}
+	return resp, err
+}
+
+// rewindBody sets body of request to be sent again, it fails if body can not be got again
+func rewindBody(req *http.Request) bool {
+	if req.Body == nil || req.Body == http.NoBody {
+		return true
+	}
+	if req.GetBody == nil {
+		return false
+	}
+	body, err := req.GetBody()
+	if err != nil {
+		return false
+	}
+	req.Body = body
+	return true
+}
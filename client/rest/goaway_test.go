@@ -0,0 +1,83 @@
+package rest
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/go-chassis/go-chassis/v2/core/client"
+	"github.com/go-chassis/go-chassis/v2/core/invocation"
+	"github.com/stretchr/testify/assert"
+)
+
+// goAwayTransport fails the first request as if server sent GOAWAY mid stream
+type goAwayTransport struct {
+	calls  int
+	bodies []string
+}
+
+func (t *goAwayTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.calls++
+	if req.Body != nil {
+		b, _ := ioutil.ReadAll(req.Body)
+		t.bodies = append(t.bodies, string(b))
+	}
+	if t.calls == 1 {
+		return nil, errors.New(`http2: server sent GOAWAY and closed the connection; LastStreamID=1, ErrCode=NO_ERROR, debug=""`)
+	}
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{},
+		Body:       ioutil.NopCloser(bytes.NewReader([]byte("ok"))),
+		Request:    req,
+	}, nil
+}
+
+func TestClient_GoAway(t *testing.T) {
+	newClient := func() (*Client, *goAwayTransport) {
+		c, _ := NewRestClient(client.Options{})
+		tp := &goAwayTransport{}
+		c.(*Client).c.Transport = tp
+		return c.(*Client), tp
+	}
+	call := func(c *Client, method string) (*http.Response, error) {
+		r, err := NewRequest(method, "http://Server/goaway", []byte("body"))
+		assert.NoError(t, err)
+		resp := NewResponse()
+		err = c.Call(context.TODO(), "127.0.0.1:8080", &invocation.Invocation{MicroServiceName: "Server", Args: r}, resp)
+		return resp, err
+	}
+	t.Run("idempotent request is retried on new connection", func(t *testing.T) {
+		c, tp := newClient()
+		resp, err := call(c, http.MethodPut)
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, 2, tp.calls)
+		assert.Equal(t, []string{"body", "body"}, tp.bodies)
+	})
+	t.Run("non idempotent request fails with retryable error", func(t *testing.T) {
+		c, tp := newClient()
+		_, err := call(c, http.MethodPost)
+		var goAway *GoAwayError
+		assert.True(t, errors.As(err, &goAway))
+		assert.True(t, goAway.Temporary())
+		assert.True(t, strings.Contains(err.Error(), "GOAWAY"))
+		assert.Equal(t, 1, tp.calls)
+	})
+	t.Run("replayed request is transformed", func(t *testing.T) {
+		InstallBodyTransformer("Server", &BodyTransformer{
+			Request: func(contentType string, body []byte) ([]byte, error) {
+				return append([]byte("envelope:"), body...), nil
+			},
+		})
+		defer RemoveBodyTransformer("Server")
+		c, tp := newClient()
+		_, err := call(c, http.MethodPut)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"envelope:body", "envelope:body"}, tp.bodies)
+	})
+}
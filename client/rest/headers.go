@@ -0,0 +1,44 @@
+package rest
+
+import (
+	"net/http"
+	"sync"
+)
+
+var (
+	serviceHeaders   = make(map[string]map[string]string)
+	serviceHeadersMu sync.RWMutex
+)
+
+// WithServiceHeaders sets constant headers of all rest calls to a micro service, such as API key or version pin.
+// headers of a call are decided in this order: headers set on request or context of call,
+// then headers of service, then client.Options.Headers. call with nil headers removes headers of service
+func WithServiceHeaders(service string, headers map[string]string) {
+	serviceHeadersMu.Lock()
+	defer serviceHeadersMu.Unlock()
+	if headers == nil {
+		delete(serviceHeaders, service)
+		return
+	}
+	m := make(map[string]string, len(headers))
+	for k, v := range headers {
+		m[http.CanonicalHeaderKey(k)] = v
+	}
+	serviceHeaders[service] = m
+}
+
+// setDefaultHeaders sets headers of service and client on request, headers request already has are kept
+func (c *Client) setDefaultHeaders(req *http.Request, service string) {
+	serviceHeadersMu.RLock()
+	setAbsent(req.Header, serviceHeaders[service])
+	serviceHeadersMu.RUnlock()
+	setAbsent(req.Header, c.opts.Headers)
+}
+
+func setAbsent(h http.Header, headers map[string]string) {
+	for k, v := range headers {
+		if _, ok := h[http.CanonicalHeaderKey(k)]; !ok {
+			h.Set(k, v)
+		}
+	}
+}
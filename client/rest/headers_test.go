@@ -0,0 +1,42 @@
+package rest
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chassis/go-chassis/v2/core/client"
+	"github.com/go-chassis/go-chassis/v2/core/invocation"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithServiceHeaders(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Got", strings.Join([]string{r.Header.Get("X-Key"), r.Header.Get("X-Version"), r.Header.Get("X-Tenant")}, ","))
+	}))
+	defer s.Close()
+	addr := strings.TrimPrefix(s.URL, "http://")
+	c, _ := NewRestClient(client.Options{Headers: map[string]string{"X-Key": "client", "X-Version": "client", "x-tenant": "client"}})
+	WithServiceHeaders("Pinned", map[string]string{"x-version": "service", "X-Tenant": "service"})
+	defer WithServiceHeaders("Pinned", nil)
+
+	got := func(service string, header http.Header) string {
+		r, _ := NewRequest("GET", "http://"+service+"/", nil)
+		for k, v := range header {
+			r.Header[k] = v
+		}
+		resp := NewResponse()
+		err := c.Call(context.TODO(), addr, &invocation.Invocation{MicroServiceName: service, Args: r}, resp)
+		assert.NoError(t, err)
+		resp.Body.Close()
+		return resp.Header.Get("X-Got")
+	}
+	assert.Equal(t, "client,service,service", got("Pinned", nil), "service headers are over client headers")
+	assert.Equal(t, "client,service,call", got("Pinned", http.Header{"X-Tenant": {"call"}}), "call headers are over service headers")
+	assert.Equal(t, "client,client,client", got("Other", nil))
+
+	WithServiceHeaders("Pinned", nil)
+	assert.Equal(t, "client,client,client", got("Pinned", nil), "service headers are removed")
+}
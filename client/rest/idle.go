@@ -0,0 +1,46 @@
+package rest
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptrace"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// idleConnCheckKey is the context key of idle connection check of a request
+type idleConnCheckKey struct{}
+
+// idleConnCheck records if request is sent on a pooled connection which was idle beyond threshold
+type idleConnCheck struct {
+	threshold time.Duration
+	idle      bool
+}
+
+// traceIdleConn returns a request which records if it is sent on a connection idle beyond threshold.
+// transport drops an idle connection once it reads EOF of it, but a connection which server closes
+// just before it is reused still gets the request, and transport does not resend a non idempotent one
+func traceIdleConn(req *http.Request, threshold time.Duration) *http.Request {
+	check := &idleConnCheck{threshold: threshold}
+	ctx := context.WithValue(req.Context(), idleConnCheckKey{}, check)
+	return req.WithContext(httptrace.WithClientTrace(ctx, &httptrace.ClientTrace{
+		GotConn: func(info httptrace.GotConnInfo) {
+			check.idle = info.Reused && info.WasIdle && info.IdleTime >= check.threshold
+		},
+	}))
+}
+
+// lostIdleConn tells if request failed because server closed the idle connection it is sent on,
+// it is only checked for request traced by traceIdleConn
+func lostIdleConn(req *http.Request, err error) bool {
+	check, ok := req.Context().Value(idleConnCheckKey{}).(*idleConnCheck)
+	if !ok || !check.idle || err == nil {
+		return false
+	}
+	return errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.EPIPE) ||
+		strings.Contains(err.Error(), "server closed idle connection")
+}
@@ -0,0 +1,102 @@
+package rest
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-chassis/go-chassis/v2/core/client"
+	"github.com/go-chassis/go-chassis/v2/core/common"
+	"github.com/go-chassis/go-chassis/v2/core/invocation"
+	"github.com/stretchr/testify/assert"
+)
+
+// closeIdleServer answers the first request of each connection, and closes the connection
+// without answering when the next request arrives, as if it closed the idle connection just then
+func closeIdleServer(t *testing.T) (net.Listener, *int32) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	var accepted int32
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			atomic.AddInt32(&accepted, 1)
+			go func(conn net.Conn) {
+				defer conn.Close()
+				r := bufio.NewReader(conn)
+				for n := 1; ; n++ {
+					req, err := http.ReadRequest(r)
+					if err != nil {
+						return
+					}
+					io.Copy(ioutil.Discard, req.Body)
+					if n > 1 {
+						return
+					}
+					io.WriteString(conn, "HTTP/1.1 200 OK\r\nContent-Length: 2\r\n\r\nok")
+				}
+			}(conn)
+		}
+	}()
+	return l, &accepted
+}
+
+func TestClient_ValidateIdleConns(t *testing.T) {
+	l, accepted := closeIdleServer(t)
+	defer l.Close()
+	call := func(c client.ProtocolClient, threshold time.Duration) (*http.Response, error) {
+		r, err := NewRequest(http.MethodPost, "http://Server/orders", []byte("order"))
+		assert.NoError(t, err)
+		inv := &invocation.Invocation{MicroServiceName: "Server", Args: r}
+		if threshold > 0 {
+			inv.SetMetadata(common.ValidateIdleConnsKey, threshold)
+		}
+		resp := NewResponse()
+		if err := c.Call(context.TODO(), l.Addr().String(), inv, resp); err != nil {
+			return nil, err
+		}
+		// connection is put back to pool once body is read
+		io.Copy(ioutil.Discard, resp.Body)
+		resp.Body.Close()
+		return resp, nil
+	}
+
+	t.Run("non idempotent request fails on closed idle connection", func(t *testing.T) {
+		c, err := NewRestClient(client.Options{})
+		assert.NoError(t, err)
+		_, err = call(c, 0)
+		assert.NoError(t, err)
+		time.Sleep(50 * time.Millisecond)
+		_, err = call(c, 0)
+		assert.Error(t, err)
+	})
+	t.Run("request is sent on a new connection if idle connection is closed", func(t *testing.T) {
+		c, err := NewRestClient(client.Options{})
+		assert.NoError(t, err)
+		before := atomic.LoadInt32(accepted)
+		_, err = call(c, 20*time.Millisecond)
+		assert.NoError(t, err)
+		time.Sleep(50 * time.Millisecond)
+		resp, err := call(c, 20*time.Millisecond)
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, before+2, atomic.LoadInt32(accepted), "request is sent again on a new connection")
+	})
+	t.Run("connection idle below threshold is not suspected", func(t *testing.T) {
+		c, err := NewRestClient(client.Options{})
+		assert.NoError(t, err)
+		_, err = call(c, time.Hour)
+		assert.NoError(t, err)
+		_, err = call(c, time.Hour)
+		assert.Error(t, err)
+	})
+}
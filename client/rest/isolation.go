@@ -0,0 +1,85 @@
+package rest
+
+import (
+	"crypto/tls"
+	"net"
+	"net/http"
+
+	"github.com/go-chassis/go-chassis/v2/core/common"
+	"github.com/go-chassis/go-chassis/v2/core/invocation"
+)
+
+// poolKey tells which connection pool sends a request
+type poolKey struct {
+	isolation      string
+	maxHeaderBytes int64
+	// http1 pool never uses HTTP/2
+	http1 bool
+}
+
+// httpClient returns the client which sends request of invocation to host,
+// if invocation has an isolation key, each key has a connection pool of its own,
+// if host has its own response header limit, it is sent by a pool of the limit,
+// if an attempt of invocation is told to fall back to HTTP/1.1, it is sent by a pool without HTTP/2
+func (c *Client) httpClient(inv *invocation.Invocation, host string) *http.Client {
+	key := poolKey{maxHeaderBytes: c.opts.MaxResponseHeaderBytes}
+	key.isolation, _ = inv.Metadata[common.IsolationKey].(string)
+	if n, ok := c.hostMaxHeaderBytes(host); ok {
+		key.maxHeaderBytes = n
+	}
+	key.http1, _ = inv.Metadata[common.HTTP1FallbackKey].(bool)
+	if key.isolation == "" && key.maxHeaderBytes == c.opts.MaxResponseHeaderBytes && !key.http1 {
+		return c.c
+	}
+	c.poolMu.Lock()
+	defer c.poolMu.Unlock()
+	if hc, ok := c.pools[key]; ok {
+		return hc
+	}
+	if c.pools == nil {
+		c.pools = make(map[poolKey]*http.Client)
+	}
+	tp := newTransport(c.opts, c.dialer)
+	tp.MaxIdleConnsPerHost = MaxIdleConnsPerHost
+	tp.MaxResponseHeaderBytes = key.maxHeaderBytes
+	if key.http1 {
+		tp.ForceAttemptHTTP2 = false
+		tp.TLSNextProto = map[string]func(string, *tls.Conn) http.RoundTripper{}
+		if tp.TLSClientConfig != nil {
+			tp.TLSClientConfig = tp.TLSClientConfig.Clone()
+			tp.TLSClientConfig.NextProtos = []string{"http/1.1"}
+		}
+	}
+	hc := &http.Client{
+		Timeout:       c.c.Timeout,
+		Transport:     tp,
+		CheckRedirect: c.checkRedirect,
+	}
+	c.pools[key] = hc
+	return hc
+}
+
+// hostMaxHeaderBytes returns response header limit of host:port, or of host if host:port has none
+func (c *Client) hostMaxHeaderBytes(host string) (int64, bool) {
+	if len(c.opts.HostMaxResponseHeaderBytes) == 0 {
+		return 0, false
+	}
+	if n, ok := c.opts.HostMaxResponseHeaderBytes[host]; ok {
+		return n, true
+	}
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		n, ok := c.opts.HostMaxResponseHeaderBytes[h]
+		return n, ok
+	}
+	return 0, false
+}
+
+// resetPools drops connection pools of isolation keys and hosts, they are created again with new options
+func (c *Client) resetPools() {
+	c.poolMu.Lock()
+	defer c.poolMu.Unlock()
+	for _, hc := range c.pools {
+		hc.CloseIdleConnections()
+	}
+	c.pools = nil
+}
@@ -0,0 +1,87 @@
+package rest
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chassis/go-chassis/v2/core/client"
+	"github.com/go-chassis/go-chassis/v2/core/common"
+	"github.com/go-chassis/go-chassis/v2/core/invocation"
+	"github.com/go-chassis/go-chassis/v2/pkg/util/httputil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClient_IsolatedConnection(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.RemoteAddr))
+	}))
+	defer s.Close()
+	addr := strings.TrimPrefix(s.URL, "http://")
+	c, _ := NewRestClient(client.Options{})
+
+	remoteAddr := func(tenant string) string {
+		r, err := NewRequest("GET", "http://Server/", nil)
+		assert.NoError(t, err)
+		inv := &invocation.Invocation{MicroServiceName: "Server", Args: r}
+		if tenant != "" {
+			inv.SetMetadata(common.IsolationKey, tenant)
+		}
+		resp := NewResponse()
+		err = c.Call(context.TODO(), addr, inv, resp)
+		assert.NoError(t, err)
+		return string(httputil.ReadBody(resp))
+	}
+	shared := remoteAddr("")
+	a := remoteAddr("tenant-a")
+	b := remoteAddr("tenant-b")
+	assert.NotEqual(t, a, b, "tenants should not share connection")
+	assert.NotEqual(t, shared, a)
+	assert.NotEqual(t, shared, b)
+	assert.Equal(t, a, remoteAddr("tenant-a"), "connection is reused by same tenant")
+	assert.Equal(t, shared, remoteAddr(""))
+
+	c.ReloadConfigs(client.Options{})
+	assert.NotEqual(t, a, remoteAddr("tenant-a"), "pools are created again after reload")
+}
+
+func TestClient_HostMaxResponseHeaderBytes(t *testing.T) {
+	bigCookie := func(w http.ResponseWriter, r *http.Request) {
+		http.SetCookie(w, &http.Cookie{Name: "session", Value: strings.Repeat("s", 16*1024)})
+		w.WriteHeader(http.StatusOK)
+	}
+	chatty := httptest.NewServer(http.HandlerFunc(bigCookie))
+	defer chatty.Close()
+	other := httptest.NewServer(http.HandlerFunc(bigCookie))
+	defer other.Close()
+	chattyAddr := strings.TrimPrefix(chatty.URL, "http://")
+	otherAddr := strings.TrimPrefix(other.URL, "http://")
+
+	c, _ := NewRestClient(client.Options{
+		MaxResponseHeaderBytes:     4 * 1024,
+		HostMaxResponseHeaderBytes: map[string]int64{chattyAddr: 64 * 1024},
+	})
+	call := func(addr, tenant string) error {
+		r, _ := NewRequest("GET", "http://Server/", nil)
+		inv := &invocation.Invocation{MicroServiceName: "Server", Args: r}
+		if tenant != "" {
+			inv.SetMetadata(common.IsolationKey, tenant)
+		}
+		return c.Call(context.TODO(), addr, inv, NewResponse())
+	}
+	assert.NoError(t, call(chattyAddr, ""), "configured host accepts large headers")
+	assert.NoError(t, call(chattyAddr, "tenant-a"), "limit of host applies to isolated pools")
+	err := call(otherAddr, "")
+	if assert.Error(t, err, "default limit applies to other hosts") {
+		assert.Contains(t, err.Error(), "exceeded")
+	}
+	assert.Error(t, call(otherAddr, "tenant-a"))
+
+	c, _ = NewRestClient(client.Options{
+		MaxResponseHeaderBytes:     4 * 1024,
+		HostMaxResponseHeaderBytes: map[string]int64{"127.0.0.1": 64 * 1024},
+	})
+	assert.NoError(t, call(otherAddr, ""), "limit of host without port applies to all ports")
+}
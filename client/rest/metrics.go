@@ -0,0 +1,124 @@
+package rest
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	"strconv"
+	"sync"
+
+	"github.com/go-chassis/go-chassis/v2/pkg/metrics"
+	"github.com/go-chassis/openlog"
+)
+
+// MetricConnections counts connections got for requests by host,
+// label reused tells if it is a pooled connection or a new dialed one,
+// reuse ratio is reused="true" divided by total
+const MetricConnections = "rest_client_connections_total"
+
+var connMetricOnce sync.Once
+
+// traceConnReuse returns a request which reports connection reuse,
+// req is returned as it is if metrics registry is not initialized
+func traceConnReuse(req *http.Request) *http.Request {
+	if !metrics.Enabled() {
+		return req
+	}
+	connMetricOnce.Do(func() {
+		err := metrics.CreateCounter(metrics.CounterOpts{
+			Name:   MetricConnections,
+			Help:   "count of connections got by rest client, reused or new dialed",
+			Labels: []string{"host", "reused"},
+		})
+		if err != nil {
+			openlog.Error(err.Error())
+		}
+	})
+	host := req.URL.Host
+	return req.WithContext(httptrace.WithClientTrace(req.Context(), &httptrace.ClientTrace{
+		GotConn: func(info httptrace.GotConnInfo) {
+			labels := map[string]string{"host": host, "reused": strconv.FormatBool(info.Reused)}
+			if err := metrics.CounterAdd(MetricConnections, 1, labels); err != nil {
+				openlog.Error("can not report connection reuse: " + err.Error())
+			}
+		},
+	}))
+}
+
+// gauges of open connections, they are updated when a connection is dialed or closed,
+// including idle connections closed by pool
+const (
+	// MetricOpenConnections is the number of open connections by host
+	MetricOpenConnections = "rest_client_open_connections"
+	// MetricOpenConnectionsAll is the number of open connections of all hosts
+	MetricOpenConnectionsAll = "rest_client_open_connections_all"
+)
+
+var (
+	openConnsOnce sync.Once
+	openConnsMu   sync.Mutex
+	openConns     = make(map[string]int64)
+	openConnsAll  int64
+)
+
+// OpenConnections returns the number of open connections to host, empty host means all hosts
+func OpenConnections(host string) int64 {
+	openConnsMu.Lock()
+	defer openConnsMu.Unlock()
+	if host == "" {
+		return openConnsAll
+	}
+	return openConns[host]
+}
+
+// addOpenConnections changes count of open connections to host by delta and reports gauges
+func addOpenConnections(host string, delta int64) {
+	openConnsMu.Lock()
+	openConns[host] += delta
+	n := openConns[host]
+	if n == 0 {
+		delete(openConns, host)
+	}
+	openConnsAll += delta
+	all := openConnsAll
+	openConnsMu.Unlock()
+	if !metrics.Enabled() {
+		return
+	}
+	openConnsOnce.Do(func() {
+		if err := metrics.CreateGauge(metrics.GaugeOpts{Name: MetricOpenConnections,
+			Help: "number of open connections of rest client by host", Labels: []string{"host"}}); err != nil {
+			openlog.Error(err.Error())
+		}
+		if err := metrics.CreateGauge(metrics.GaugeOpts{Name: MetricOpenConnectionsAll,
+			Help: "number of open connections of rest client"}); err != nil {
+			openlog.Error(err.Error())
+		}
+	})
+	if err := metrics.GaugeSet(MetricOpenConnections, float64(n), map[string]string{"host": host}); err != nil {
+		openlog.Error("can not report open connections: " + err.Error())
+	}
+	if err := metrics.GaugeSet(MetricOpenConnectionsAll, float64(all), nil); err != nil {
+		openlog.Error("can not report open connections: " + err.Error())
+	}
+}
+
+// countedConn decrements open connections once it is closed
+type countedConn struct {
+	net.Conn
+	host  string
+	close sync.Once
+}
+
+func newCountedConn(conn net.Conn, host string) net.Conn {
+	addOpenConnections(host, 1)
+	return &countedConn{Conn: conn, host: host}
+}
+
+// Close closes connection, only the first call changes count
+func (c *countedConn) Close() error {
+	c.close.Do(func() {
+		addOpenConnections(c.host, -1)
+	})
+	return c.Conn.Close()
+}
@@ -0,0 +1,43 @@
+package rest
+
+import (
+	"context"
+	"net/http"
+)
+
+type redirectPolicyKey struct{}
+
+// RedirectHeaderPolicy decides which headers of request are sent when following a redirect.
+// a redirect is cross host if host and port of its url differ from the ones of the first request.
+// net/http already removes Authorization, WWW-Authenticate, Cookie and Cookie2 if hostname of redirect
+// is neither the one of first request nor its subdomain
+type RedirectHeaderPolicy struct {
+	// DropCrossHost headers are removed too on cross host redirects, such as custom tokens
+	DropCrossHost []string
+	// Keep headers are always sent as first request has them, even on cross host redirects
+	Keep []string
+}
+
+// withRedirectHeaderPolicy returns a context which carries policy to checkRedirect,
+// net/http gives requests of redirects the context of first request
+func withRedirectHeaderPolicy(ctx context.Context, p RedirectHeaderPolicy) context.Context {
+	return context.WithValue(ctx, redirectPolicyKey{}, p)
+}
+
+// applyRedirectHeaderPolicy changes headers of req, which follows a redirect of first request
+func applyRedirectHeaderPolicy(req, first *http.Request) {
+	p, ok := req.Context().Value(redirectPolicyKey{}).(RedirectHeaderPolicy)
+	if !ok {
+		return
+	}
+	if req.URL.Host != first.URL.Host {
+		for _, h := range p.DropCrossHost {
+			req.Header.Del(h)
+		}
+	}
+	for _, h := range p.Keep {
+		if v, ok := first.Header[http.CanonicalHeaderKey(h)]; ok {
+			req.Header[http.CanonicalHeaderKey(h)] = append([]string(nil), v...)
+		}
+	}
+}
@@ -6,13 +6,17 @@ import (
 	"fmt"
 	"net"
 	"net/http"
+	"net/url"
+	"os"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/go-chassis/go-chassis/v2/core/client"
 	"github.com/go-chassis/go-chassis/v2/core/common"
 	"github.com/go-chassis/go-chassis/v2/core/invocation"
 	"github.com/go-chassis/go-chassis/v2/pkg/util/httputil"
+	"github.com/go-chassis/openlog"
 )
 
 const (
@@ -26,16 +30,28 @@ const (
 	DefaultKeepAliveSecond = 60 * time.Second
 	//DefaultMaxConnsPerHost defines the maximum number of concurrent connections
 	DefaultMaxConnsPerHost = 512
+	//MaxIdleConnsPerHost defines the maximum number of idle connections per host while sending requests
+	MaxIdleConnsPerHost = 512 * 20
 	//SchemaHTTP represents the http schema
 	SchemaHTTP = "http"
 	//SchemaHTTPS represents the https schema
 	SchemaHTTPS = "https"
+	// EnvTimeout is the environment variable of default timeout, it is a duration string such as 3s,
+	// it applies to clients created without timeout in options
+	EnvTimeout = "CHASSIS_REST_TIMEOUT"
+	// DefaultTimeout is the timeout of client if neither options nor EnvTimeout set one
+	DefaultTimeout = 5 * time.Second
+	// HeaderEarlyData marks a request sent in TLS early data, see RFC 8470,
+	// it is removed from requests retried after 425 Too Early
+	HeaderEarlyData = "Early-Data"
 )
 
 var (
 
 	//ErrInvalidResp invalid input
 	ErrInvalidResp = errors.New("rest consumer response arg is not *rest.Response type")
+	//ErrInvalidEndpoint means addr is not a valid host:port
+	ErrInvalidEndpoint = errors.New("invalid endpoint")
 )
 
 func init() {
@@ -44,19 +60,69 @@ func init() {
 
 //NewRestClient is a function
 func NewRestClient(opts client.Options) (client.ProtocolClient, error) {
-	tp := newTransport(opts)
+	d := newDialer(opts)
+	tp := newTransport(opts, d)
 	rc := &Client{
-		opts: opts,
+		opts:   opts,
+		dialer: d,
 
 		c: &http.Client{
-			Timeout:   opts.Timeout,
+			Timeout:   timeoutOf(opts),
 			Transport: tp,
 		},
 	}
+	rc.c.CheckRedirect = rc.checkRedirect
+	enableAutoWarm(opts.Service, opts.AutoWarmNewEndpoints, d)
 	return rc, nil
 }
 
-func newTransport(opts client.Options) *http.Transport {
+// timeoutOf returns timeout of opts, if it has none, the one of EnvTimeout is returned,
+// DefaultTimeout is returned if EnvTimeout is not set or invalid
+func timeoutOf(opts client.Options) time.Duration {
+	if opts.Timeout > 0 {
+		return opts.Timeout
+	}
+	v, ok := os.LookupEnv(EnvTimeout)
+	if !ok {
+		return DefaultTimeout
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil || d <= 0 {
+		openlog.Warn(fmt.Sprintf("invalid %s [%s], use default timeout %s", EnvTimeout, v, DefaultTimeout))
+		return DefaultTimeout
+	}
+	return d
+}
+
+// checkRedirect stops following redirects if DisableRedirect is set,
+// the 3xx response will be returned and classified by failure map.
+// Location is resolved against url of last request as RFC 3986 says, the url has endpoint as host,
+// if Location points to micro service name, which is Host header of first request, the endpoint is used too,
+// because service name can not be resolved by DNS.
+// headers of redirected request are changed by RedirectHeaderPolicy of the call
+func (c *Client) checkRedirect(req *http.Request, via []*http.Request) error {
+	if c.opts.DisableRedirect {
+		return http.ErrUseLastResponse
+	}
+	if len(via) >= 10 {
+		return errors.New("stopped after 10 redirects")
+	}
+	if req.Response != nil {
+		// protocol relative Location has authority, Host header must follow it like absolute Location
+		if loc, err := url.Parse(req.Response.Header.Get("Location")); err == nil && loc.Host != "" {
+			req.Host = ""
+		}
+	}
+	first, last := via[0], via[len(via)-1]
+	if first.Host != "" && first.Host != first.URL.Host && req.URL.Host == first.Host {
+		req.Host = first.Host
+		req.URL.Host = last.URL.Host
+	}
+	applyRedirectHeaderPolicy(req, first)
+	return nil
+}
+
+func newTransport(opts client.Options, d *dialer) *http.Transport {
 	poolSize := DefaultMaxConnsPerHost
 	if opts.PoolSize != 0 {
 		poolSize = opts.PoolSize
@@ -65,18 +131,18 @@ func newTransport(opts client.Options) *http.Transport {
 	tp := &http.Transport{
 		MaxIdleConns:        poolSize,
 		MaxIdleConnsPerHost: poolSize,
-		DialContext: (&net.Dialer{
-			KeepAlive: DefaultKeepAliveSecond,
-			Timeout:   DefaultTimeoutBySecond,
-		}).DialContext}
+		DialContext:         d.DialContext}
 	if opts.TLSConfig != nil {
 		tp.TLSClientConfig = opts.TLSConfig
 	}
+	tp.MaxResponseHeaderBytes = opts.MaxResponseHeaderBytes
+	// custom dialer disables HTTP/2 unless it is forced
+	tp.ForceAttemptHTTP2 = opts.EnableHTTP2
 	return tp
 }
 
-// If a request fails, we generate an error.
-func (c *Client) failure2Error(e error, r *http.Response, addr string) error {
+// If a request fails, we generate an error, includeBody makes error carry response body
+func (c *Client) failure2Error(e error, r *http.Response, addr string, includeBody bool) error {
 	if e != nil {
 		return e
 	}
@@ -86,19 +152,35 @@ func (c *Client) failure2Error(e error, r *http.Response, addr string) error {
 	if r == nil {
 		return nil
 	}
+	if r.StatusCode == http.StatusMultiStatus {
+		// 207 succeeds even if failure map has it, statuses of resources are in body, see httputil.DecodeMultiStatus
+		return nil
+	}
 
 	codeStr := strconv.Itoa(r.StatusCode)
 	// The Failure map defines whether or not a request fail.
-	if c.opts.Failure["http_"+codeStr] {
-		return fmt.Errorf("http error status [%d], server addr: [%s], will not print response body, to protect service sensitive data", r.StatusCode, addr)
+	kind := c.opts.Failure[FailureTypePrefix+codeStr]
+	if kind == 0 {
+		return nil
 	}
-
-	return nil
+	statusErr := &StatusError{StatusCode: r.StatusCode, Addr: addr}
+	if includeBody {
+		statusErr.Body = readErrorBody(r)
+	}
+	if kind == client.PermanentFailure || kind == client.HTTP1FallbackFailure && r.ProtoMajor < 2 {
+		// a request which is already sent over HTTP/1.x has nothing to fall back to
+		return Permanent(statusErr)
+	}
+	return statusErr
 }
 
 //Call is a method which uses client struct object
 func (c *Client) Call(ctx context.Context, addr string, inv *invocation.Invocation, rsp interface{}) error {
 	var err error
+	addr, err = validateAddr(addr)
+	if err != nil {
+		return err
+	}
 	reqSend, err := httputil.HTTPRequest(inv)
 	if err != nil {
 		return err
@@ -109,6 +191,11 @@ func (c *Client) Call(ctx context.Context, addr string, inv *invocation.Invocati
 	}
 
 	c.contextToHeader(ctx, reqSend)
+	c.setDefaultHeaders(reqSend, inv.MicroServiceName)
+	if rejected, _ := inv.Metadata[common.EarlyDataRejectedKey].(bool); rejected {
+		// golang tls client never sends early data, an intermediary which does marks request by Early-Data
+		reqSend.Header.Del(HeaderEarlyData)
+	}
 
 	ctx, cancel := context.WithCancel(ctx)
 	defer cancel()
@@ -120,26 +207,157 @@ func (c *Client) Call(ctx context.Context, addr string, inv *invocation.Invocati
 	if addr != "" {
 		reqSend.URL.Host = addr
 	}
+	encodings, accepted := inv.Metadata[common.AcceptEncodingsKey].([]string)
+	if accepted {
+		if err := setAcceptEncoding(reqSend, encodings); err != nil {
+			return err
+		}
+	}
+	transformer := getBodyTransformer(inv.MicroServiceName)
+	if transformer != nil {
+		if err := transformer.transformRequest(reqSend); err != nil {
+			return &CodecError{Body: "request", Err: err}
+		}
+	}
+
+	if timeout, ok := inv.Metadata[common.ConnectTimeoutKey].(time.Duration); ok {
+		reqSend = reqSend.WithContext(withConnectTimeout(reqSend.Context(), timeout))
+	}
+	if threshold, ok := inv.Metadata[common.ValidateIdleConnsKey].(time.Duration); ok {
+		reqSend = traceIdleConn(reqSend, threshold)
+	}
+	if p, ok := inv.Metadata[common.RedirectHeaderPolicyKey].(RedirectHeaderPolicy); ok {
+		reqSend = reqSend.WithContext(withRedirectHeaderPolicy(reqSend.Context(), p))
+	}
+	reqSend = traceConnReuse(reqSend)
+	reqSend, phase := tracePhase(reqSend)
+
+	dumpOnError, _ := inv.Metadata[common.DumpOnErrorKey].(bool)
+	var reqDump []byte
+	if dumpOnError {
+		var dumpErr error
+		if reqDump, dumpErr = dumpRequest(reqSend, true); dumpErr != nil {
+			openlog.Warn("can not dump request: " + dumpErr.Error())
+		}
+	}
+	capture, _ := inv.Metadata[common.CaptureKey].(bool)
+	if capture {
+		captured, dumpErr := dumpRequest(reqSend, false)
+		if dumpErr != nil {
+			openlog.Warn("can not capture request: " + dumpErr.Error())
+		}
+		inv.SetMetadata(common.CapturedRequestKey, captured)
+	}
 
 	//increase the max connection per host to prevent error "no free connection available" error while sending more requests.
-	c.c.Transport.(*http.Transport).MaxIdleConnsPerHost = 512 * 20
+	if tp, ok := c.c.Transport.(*http.Transport); ok {
+		tp.MaxIdleConnsPerHost = MaxIdleConnsPerHost
+	}
 	var temp *http.Response
 	errChan := make(chan error, 1)
 	go func() {
-		temp, err = c.c.Do(reqSend)
+		temp, err = c.do(c.httpClient(inv, reqSend.URL.Host), reqSend)
 		errChan <- err
 	}()
 
 	select {
 	case <-ctx.Done():
 		err = client.ErrCanceled
+		if ctx.Err() == context.DeadlineExceeded {
+			err = &TransportError{Addr: reqSend.URL.Host,
+				Err: &TimeoutError{Phase: phase.Phase(), Addr: reqSend.URL.Host, Err: client.ErrCanceled}}
+			reportTimeout(reqSend.URL.Host, phase.Phase())
+		}
 	case err = <-errChan:
-		if err == nil {
+		if isTimeout(err) {
+			err = &TimeoutError{Phase: phase.Phase(), Addr: reqSend.URL.Host, Err: err}
+			reportTimeout(reqSend.URL.Host, phase.Phase())
+		}
+		if err != nil {
+			err = &TransportError{Addr: reqSend.URL.Host, Err: err}
+		} else {
 			*resp = *temp
+			if accepted {
+				if err = decodeBody(resp); err != nil {
+					err = &CodecError{Body: "response", Err: err}
+				}
+			}
+			if limit, ok := inv.Metadata[common.MaxDecompressedSizeKey].(int64); ok && err == nil {
+				limitDecodedBody(resp, limit)
+			}
+			if err == nil && transformer != nil {
+				if err = transformer.transformResponse(resp); err != nil {
+					err = &CodecError{Body: "response", Err: err}
+				}
+			}
+			if peek, ok := inv.Metadata[common.ErrorPeekKey].(ErrorPeek); ok && err == nil {
+				err = peek.classify(resp)
+			}
+		}
+	}
+
+	includeBody, _ := inv.Metadata[common.ErrorBodyKey].(bool)
+	err = c.failure2Error(err, resp, addr, includeBody)
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) {
+		switch {
+		case statusErr.StatusCode == http.StatusTooEarly:
+			// server rejects request sent in TLS early data, retries must wait for full handshake
+			inv.SetMetadata(common.EarlyDataRejectedKey, true)
+		case resp.ProtoMajor == 2 && c.opts.Failure[FailureTypePrefix+strconv.Itoa(statusErr.StatusCode)] == client.HTTP1FallbackFailure:
+			// server does not support HTTP/2 for the request, retries are sent over HTTP/1.1
+			inv.SetMetadata(common.HTTP1FallbackKey, true)
+		}
+	}
+	if err != nil && dumpOnError {
+		respDump, dumpErr := dumpResponse(resp, true)
+		if dumpErr != nil {
+			openlog.Warn("can not dump response: " + dumpErr.Error())
+		}
+		inv.SetMetadata(common.ErrorDumpKey, append(reqDump, respDump...))
+	}
+	if capture {
+		captured, dumpErr := dumpResponse(resp, false)
+		if dumpErr != nil {
+			openlog.Warn("can not capture response: " + dumpErr.Error())
 		}
+		inv.SetMetadata(common.CapturedResponseKey, captured)
 	}
+	return err
+}
+
+// validateAddr trims spaces of addr and checks it is host:port, empty addr is allowed.
+// host without port is also allowed, default port of scheme is used
+func validateAddr(addr string) (string, error) {
+	trimmed := strings.TrimSpace(addr)
+	if trimmed == "" {
+		return "", nil
+	}
+	if isBareHost(trimmed) {
+		return trimmed, nil
+	}
+	host, port, err := net.SplitHostPort(trimmed)
+	if err != nil {
+		return "", fmt.Errorf("%w [%q]: %s", ErrInvalidEndpoint, addr, err)
+	}
+	if host == "" || port == "" || strings.ContainsAny(trimmed, " \t\r\n") {
+		return "", fmt.Errorf("%w [%q]", ErrInvalidEndpoint, addr)
+	}
+	if _, err := strconv.ParseUint(port, 10, 16); err != nil {
+		return "", fmt.Errorf("%w [%q]: invalid port", ErrInvalidEndpoint, addr)
+	}
+	return trimmed, nil
+}
 
-	return c.failure2Error(err, resp, addr)
+// isBareHost tells if addr is a host name, IPv4 or bracketed IPv6 address without port
+func isBareHost(addr string) bool {
+	if strings.ContainsAny(addr, " \t\r\n/") {
+		return false
+	}
+	if strings.HasPrefix(addr, "[") && strings.HasSuffix(addr, "]") {
+		return net.ParseIP(addr[1:len(addr)-1]) != nil
+	}
+	return !strings.Contains(addr, ":")
 }
 
 func (c *Client) String() string {
@@ -154,9 +372,11 @@ func (c *Client) Close() error {
 // ReloadConfigs  reload configs for timeout and tls
 func (c *Client) ReloadConfigs(opts client.Options) {
 	c.opts = client.EqualOpts(c.opts, opts)
-	c.c.Timeout = c.opts.Timeout
-	tp := newTransport(opts)
-	c.c.Transport = tp
+	c.c.Timeout = timeoutOf(c.opts)
+	c.dialer = newDialer(c.opts)
+	c.c.Transport = newTransport(c.opts, c.dialer)
+	c.resetPools()
+	enableAutoWarm(c.opts.Service, c.opts.AutoWarmNewEndpoints, c.dialer)
 }
 
 // GetOptions method return opts
@@ -164,6 +384,29 @@ func (c *Client) GetOptions() client.Options {
 	return c.opts
 }
 
+// EffectiveOptions returns options which client actually uses, defaults are filled in.
+// Timeout is the one of EnvTimeout or DefaultTimeout if options have none. PoolSize is the limit of idle connections in total,
+// idle connections per host are limited by MaxIdleConnsPerHost once client sends requests
+func (c *Client) EffectiveOptions() client.Options {
+	opts := c.opts
+	opts.Timeout = c.c.Timeout
+	if tp, ok := c.c.Transport.(*http.Transport); ok {
+		opts.PoolSize = tp.MaxIdleConns
+		if tp.TLSClientConfig != nil {
+			opts.TLSConfig = tp.TLSClientConfig
+		}
+		opts.EnableHTTP2 = tp.ForceAttemptHTTP2
+	}
+	d := c.dialer
+	opts.TCPKeepAlive = d.KeepAlive
+	opts.DisableTCPNoDelay = !d.noDelay
+	opts.Network = d.network
+	if opts.Network == "" {
+		opts.Network = "tcp"
+	}
+	return opts
+}
+
 func (c *Client) contextToHeader(ctx context.Context, req *http.Request) {
 	for k, v := range common.FromContext(ctx) {
 		req.Header.Set(k, v)
@@ -2,6 +2,8 @@ package rest_test
 
 import (
 	"context"
+	"crypto/tls"
+	"errors"
 	"github.com/go-chassis/go-archaius"
 	"log"
 	"testing"
@@ -16,13 +18,22 @@ import (
 	_ "github.com/go-chassis/go-chassis/v2/server/restful"
 
 	"fmt"
+	"io/ioutil"
+	"net"
 	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/go-chassis/go-chassis/v2/core/common"
 	"github.com/go-chassis/go-chassis/v2/core/handler"
 	"github.com/go-chassis/go-chassis/v2/core/invocation"
+	"github.com/go-chassis/go-chassis/v2/pkg/metrics"
 	"github.com/go-chassis/go-chassis/v2/pkg/runtime"
+	"github.com/go-chassis/go-chassis/v2/pkg/util/httputil"
 	"github.com/go-chassis/go-chassis/v2/server/restful"
 	"github.com/stretchr/testify/assert"
 )
@@ -171,8 +182,8 @@ func TestNewRestClient_Call_Error_Scenarios(t *testing.T) {
 	assert.NoError(t, err)
 	err = s.Start()
 	assert.NoError(t, err)
-	fail := make(map[string]bool)
-	fail["http_500"] = true
+	fail := make(map[string]client.FailureKind)
+	fail["http_500"] = client.RetryableFailure
 	c, _ := rest.NewRestClient(client.Options{
 		Failure:  fail,
 		PoolSize: 3,
@@ -247,3 +258,466 @@ func (r *TestSchema) URLPatterns() []restful.Route {
 			Returns: []*restful.Returns{{Code: 500}}},
 	}
 }
+
+func TestNewRestClient_DisableRedirect(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/login" {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		http.Redirect(w, r, "/login", http.StatusFound)
+	}))
+	defer s.Close()
+	addr := strings.TrimPrefix(s.URL, "http://")
+	newInv := func() *invocation.Invocation {
+		r, err := rest.NewRequest("GET", "http://Server/resource", nil)
+		assert.NoError(t, err)
+		return &invocation.Invocation{MicroServiceName: "Server", Args: r}
+	}
+	t.Run("follow redirect by default", func(t *testing.T) {
+		c, _ := rest.NewRestClient(client.Options{Failure: map[string]client.FailureKind{"http_302": client.RetryableFailure}})
+		reply := rest.NewResponse()
+		err := c.Call(context.TODO(), addr, newInv(), reply)
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, reply.StatusCode)
+	})
+	t.Run("disable redirect, 302 is a failure", func(t *testing.T) {
+		c, _ := rest.NewRestClient(client.Options{
+			Failure:         map[string]client.FailureKind{"http_302": client.RetryableFailure, "http_500": client.RetryableFailure},
+			DisableRedirect: true,
+		})
+		reply := rest.NewResponse()
+		err := c.Call(context.TODO(), addr, newInv(), reply)
+		assert.Error(t, err)
+		assert.Equal(t, http.StatusFound, reply.StatusCode)
+	})
+	t.Run("disable redirect, 302 is not in failure map", func(t *testing.T) {
+		c, _ := rest.NewRestClient(client.Options{
+			Failure:         map[string]client.FailureKind{"http_500": client.RetryableFailure},
+			DisableRedirect: true,
+		})
+		reply := rest.NewResponse()
+		err := c.Call(context.TODO(), addr, newInv(), reply)
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusFound, reply.StatusCode)
+	})
+}
+
+func TestNewRestClient_RedirectLocation(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.Contains(r.URL.Path, "/target") {
+			w.Write([]byte(r.Host + " " + r.URL.Path))
+			return
+		}
+		w.Header().Set("Location", r.URL.Query().Get("loc"))
+		w.WriteHeader(http.StatusFound)
+	}))
+	defer s.Close()
+	addr := strings.TrimPrefix(s.URL, "http://")
+	c, _ := rest.NewRestClient(client.Options{})
+	follow := func(loc string) string {
+		r, err := rest.NewRequest("GET", "http://Server/v1/a/b?loc="+url.QueryEscape(loc), nil)
+		assert.NoError(t, err)
+		reply := rest.NewResponse()
+		err = c.Call(context.TODO(), addr, &invocation.Invocation{MicroServiceName: "Server", Args: r}, reply)
+		assert.NoError(t, err)
+		return string(httputil.ReadBody(reply))
+	}
+	assert.Equal(t, addr+" /target/abs", follow("http://"+addr+"/target/abs"), "absolute")
+	assert.Equal(t, "Server /target/svc", follow("http://Server/target/svc"), "absolute with service name")
+	assert.Equal(t, "Server /target/root", follow("/target/root"), "absolute path")
+	assert.Equal(t, "Server /v1/a/target/rel", follow("target/rel"), "relative path")
+	assert.Equal(t, "Server /v1/target/up", follow("../target/up"), "relative path with dot segments")
+	assert.Equal(t, "Server /target/proto", follow("//Server/target/proto"), "protocol relative with service name")
+	assert.Equal(t, addr+" /target/proto", follow("//"+addr+"/target/proto"), "protocol relative")
+}
+
+func TestNewRestClient_RedirectChain(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/a":
+			http.Redirect(w, r, "/b", http.StatusFound)
+		case "/b":
+			http.Redirect(w, r, "/c?x=1", http.StatusMovedPermanently)
+		default:
+			w.WriteHeader(http.StatusOK)
+		}
+	}))
+	defer s.Close()
+	addr := strings.TrimPrefix(s.URL, "http://")
+	c, _ := rest.NewRestClient(client.Options{})
+	chain := func(path string) []string {
+		r, err := rest.NewRequest("GET", "http://Server"+path, nil)
+		assert.NoError(t, err)
+		reply := rest.NewResponse()
+		err = c.Call(context.TODO(), addr, &invocation.Invocation{MicroServiceName: "Server", Args: r}, reply)
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, reply.StatusCode)
+		return httputil.RedirectChain(reply)
+	}
+	assert.Equal(t, []string{s.URL + "/b", s.URL + "/c?x=1"}, chain("/a"))
+	assert.Empty(t, chain("/c"), "no redirect followed")
+}
+
+func TestNewRestClient_RedirectHeaderPolicy(t *testing.T) {
+	echo := func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.Header.Get("X-Token") + "|" + r.Header.Get("Authorization") + "|" + r.Header.Get("X-Trace")))
+	}
+	other := httptest.NewServer(http.HandlerFunc(echo))
+	defer other.Close()
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/same":
+			http.Redirect(w, r, "/echo", http.StatusFound)
+		case "/cross":
+			// net/http removes Authorization only if hostname differs
+			http.Redirect(w, r, strings.Replace(other.URL, "127.0.0.1", "localhost", 1)+"/echo", http.StatusFound)
+		default:
+			echo(w, r)
+		}
+	}))
+	defer s.Close()
+	addr := strings.TrimPrefix(s.URL, "http://")
+	c, _ := rest.NewRestClient(client.Options{})
+	call := func(path string, policy *rest.RedirectHeaderPolicy) string {
+		r, err := rest.NewRequest("GET", "http://Server"+path, nil)
+		assert.NoError(t, err)
+		r.Header.Set("X-Token", "secret")
+		r.Header.Set("Authorization", "Bearer t")
+		r.Header.Set("X-Trace", "1")
+		inv := &invocation.Invocation{MicroServiceName: "Server", Args: r}
+		if policy != nil {
+			inv.SetMetadata(common.RedirectHeaderPolicyKey, *policy)
+		}
+		reply := rest.NewResponse()
+		err = c.Call(context.TODO(), addr, inv, reply)
+		assert.NoError(t, err)
+		return string(httputil.ReadBody(reply))
+	}
+	assert.Equal(t, "secret||1", call("/cross", nil), "only Authorization is removed by default")
+
+	policy := &rest.RedirectHeaderPolicy{DropCrossHost: []string{"x-token"}}
+	assert.Equal(t, "||1", call("/cross", policy), "sensitive header is removed on cross host redirect")
+	assert.Equal(t, "secret|Bearer t|1", call("/same", policy), "sensitive header is kept on same host redirect")
+
+	policy = &rest.RedirectHeaderPolicy{DropCrossHost: []string{"X-Token", "X-Trace"}, Keep: []string{"X-Trace", "Authorization"}}
+	assert.Equal(t, "|Bearer t|1", call("/cross", policy), "kept headers are always sent")
+}
+
+func TestNewRestClient_ConnectionClose(t *testing.T) {
+	var conns int32
+	s := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	s.Config.ConnState = func(c net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			atomic.AddInt32(&conns, 1)
+		}
+	}
+	s.Start()
+	defer s.Close()
+	addr := strings.TrimPrefix(s.URL, "http://")
+	c, _ := rest.NewRestClient(client.Options{})
+	call := func(close bool) {
+		r, err := rest.NewRequest("GET", "http://Server/", nil)
+		assert.NoError(t, err)
+		httputil.SetConnectionClose(r, close)
+		reply := rest.NewResponse()
+		err = c.Call(context.TODO(), addr, &invocation.Invocation{MicroServiceName: "Server", Args: r}, reply)
+		assert.NoError(t, err)
+		ioutil.ReadAll(reply.Body)
+		reply.Body.Close()
+	}
+	call(false)
+	call(false)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&conns))
+	call(true)
+	call(false)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&conns), "connection should not be reused after close")
+}
+
+func TestNewRestClient_CloseDelimitedBody(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	defer l.Close()
+	body := strings.Repeat("0123456789", 64*1024)
+	var conns int32
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			atomic.AddInt32(&conns, 1)
+			go func(conn net.Conn) {
+				defer conn.Close()
+				buf := make([]byte, 4096)
+				var req []byte
+				for !strings.Contains(string(req), "\r\n\r\n") {
+					n, err := conn.Read(buf)
+					if err != nil {
+						return
+					}
+					req = append(req, buf[:n]...)
+				}
+				// no Content-Length and no chunked encoding, body ends when connection is closed
+				conn.Write([]byte("HTTP/1.1 200 OK\r\nConnection: keep-alive\r\nContent-Type: text/plain\r\n\r\n" + body))
+			}(conn)
+		}
+	}()
+	c, _ := rest.NewRestClient(client.Options{})
+	call := func() {
+		r, err := rest.NewRequest("GET", "http://Server/", nil)
+		assert.NoError(t, err)
+		reply := rest.NewResponse()
+		err = c.Call(context.TODO(), l.Addr().String(), &invocation.Invocation{MicroServiceName: "Server", Args: r}, reply)
+		assert.NoError(t, err)
+		b, err := ioutil.ReadAll(reply.Body)
+		assert.NoError(t, err)
+		reply.Body.Close()
+		assert.Equal(t, len(body), len(b), "body is read until connection is closed")
+		assert.True(t, httputil.CloseDelimited(reply))
+		assert.True(t, reply.Close, "connection must not be reused")
+	}
+	call()
+	call()
+	assert.Equal(t, int32(2), atomic.LoadInt32(&conns), "connection is not pooled")
+}
+
+func TestNewRestClient_InvalidEndpoint(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer s.Close()
+	addr := strings.TrimPrefix(s.URL, "http://")
+	c, _ := rest.NewRestClient(client.Options{})
+	call := func(addr string) error {
+		r, err := rest.NewRequest("GET", "http://Server/", nil)
+		assert.NoError(t, err)
+		return c.Call(context.TODO(), addr, &invocation.Invocation{MicroServiceName: "Server", Args: r}, rest.NewResponse())
+	}
+	t.Run("whitespace padded address is trimmed", func(t *testing.T) {
+		assert.NoError(t, call(" "+addr+"\n"))
+		assert.NoError(t, call("\t"+addr))
+	})
+	t.Run("address without port uses default port", func(t *testing.T) {
+		for _, host := range []string{"localhost", "127.0.0.1", "[::1]"} {
+			err := call(host)
+			assert.False(t, errors.Is(err, rest.ErrInvalidEndpoint), err)
+		}
+	})
+	for _, bad := range []string{"127.0.0.1:", "[::1", "::1", ":8080", "127.0.0.1:8080:1", "127.0. 0.1:8080", "127.0.0.1:abc", "127.0.0.1:70000"} {
+		t.Run("malformed address "+bad, func(t *testing.T) {
+			err := call(bad)
+			assert.True(t, errors.Is(err, rest.ErrInvalidEndpoint), err)
+		})
+	}
+}
+
+func TestNewRestClient_DumpOnError(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := ioutil.ReadAll(r.Body)
+		if string(b) == "fail" {
+			http.SetCookie(w, &http.Cookie{Name: "session", Value: "secret-session"})
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte("internal error"))
+			return
+		}
+		w.Write(b)
+	}))
+	defer s.Close()
+	addr := strings.TrimPrefix(s.URL, "http://")
+	c, _ := rest.NewRestClient(client.Options{Failure: map[string]client.FailureKind{"http_500": client.RetryableFailure}})
+	call := func(body string) (*invocation.Invocation, *http.Response, error) {
+		r, err := rest.NewRequest("POST", "http://Server/dump", []byte(body))
+		assert.NoError(t, err)
+		r.Header.Set("Authorization", "Bearer secret-token")
+		inv := &invocation.Invocation{MicroServiceName: "Server", Args: r}
+		inv.SetMetadata(common.DumpOnErrorKey, true)
+		reply := rest.NewResponse()
+		return inv, reply, c.Call(context.TODO(), addr, inv, reply)
+	}
+	t.Run("nothing is dumped on success", func(t *testing.T) {
+		inv, reply, err := call("ok")
+		assert.NoError(t, err)
+		assert.Nil(t, inv.Metadata[common.ErrorDumpKey])
+		b, _ := ioutil.ReadAll(reply.Body)
+		assert.Equal(t, "ok", string(b), "request body must still be sent")
+	})
+	t.Run("request and response are dumped on failure", func(t *testing.T) {
+		inv, reply, err := call("fail")
+		assert.Error(t, err)
+		dump, ok := inv.Metadata[common.ErrorDumpKey].([]byte)
+		assert.True(t, ok)
+		assert.Contains(t, string(dump), "POST /dump")
+		assert.Contains(t, string(dump), "fail")
+		assert.Contains(t, string(dump), "500 Internal Server Error")
+		assert.Contains(t, string(dump), "internal error")
+		assert.NotContains(t, string(dump), "secret-token")
+		assert.NotContains(t, string(dump), "secret-session")
+		b, _ := ioutil.ReadAll(reply.Body)
+		assert.Equal(t, "internal error", string(b), "response body must still be readable")
+	})
+}
+
+func TestNewRestClient_ConnectionReuseMetric(t *testing.T) {
+	if !metrics.Enabled() {
+		assert.NoError(t, metrics.Init())
+	}
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	defer s.Close()
+	addr := strings.TrimPrefix(s.URL, "http://")
+	c, _ := rest.NewRestClient(client.Options{})
+	call := func() {
+		r, err := rest.NewRequest("GET", "http://Server/", nil)
+		assert.NoError(t, err)
+		reply := rest.NewResponse()
+		err = c.Call(context.TODO(), addr, &invocation.Invocation{MicroServiceName: "Server", Args: r}, reply)
+		assert.NoError(t, err)
+		ioutil.ReadAll(reply.Body)
+		reply.Body.Close()
+	}
+	count := func(reused string) float64 {
+		mfs, err := metrics.GetSystemPrometheusRegistry().Gather()
+		assert.NoError(t, err)
+		for _, mf := range mfs {
+			if mf.GetName() != rest.MetricConnections {
+				continue
+			}
+			for _, m := range mf.GetMetric() {
+				labels := map[string]string{}
+				for _, l := range m.GetLabel() {
+					labels[l.GetName()] = l.GetValue()
+				}
+				if labels["host"] == addr && labels["reused"] == reused {
+					return m.GetCounter().GetValue()
+				}
+			}
+		}
+		return 0
+	}
+	call()
+	assert.Equal(t, float64(1), count("false"))
+	assert.Equal(t, float64(0), count("true"))
+	call()
+	assert.Equal(t, float64(1), count("false"))
+	assert.Equal(t, float64(1), count("true"))
+}
+
+func TestNewRestClient_EffectiveOptions(t *testing.T) {
+	c, _ := rest.NewRestClient(client.Options{Service: "Server"})
+	opts := c.(*rest.Client).EffectiveOptions()
+	assert.Equal(t, "Server", opts.Service)
+	assert.Equal(t, rest.DefaultMaxConnsPerHost, opts.PoolSize)
+	assert.Equal(t, rest.DefaultKeepAliveSecond, opts.TCPKeepAlive)
+	assert.Equal(t, "tcp", opts.Network)
+	assert.False(t, opts.DisableTCPNoDelay)
+	assert.Equal(t, rest.DefaultTimeout, opts.Timeout)
+	assert.Equal(t, 0, c.GetOptions().PoolSize, "configured options are not changed")
+
+	c, _ = rest.NewRestClient(client.Options{PoolSize: 8, Timeout: 5 * time.Second, Network: "tcp4"})
+	opts = c.(*rest.Client).EffectiveOptions()
+	assert.Equal(t, 8, opts.PoolSize)
+	assert.Equal(t, 5*time.Second, opts.Timeout)
+	assert.Equal(t, "tcp4", opts.Network)
+
+	c, _ = rest.NewRestClient(client.Options{TCPKeepAlive: 10 * time.Second, Network: "tcp6"})
+	c.ReloadConfigs(client.Options{Timeout: time.Second})
+	opts = c.(*rest.Client).EffectiveOptions()
+	assert.Equal(t, 10*time.Second, opts.TCPKeepAlive, "dialer settings are kept on reload")
+	assert.Equal(t, "tcp6", opts.Network)
+	assert.Equal(t, time.Second, opts.Timeout)
+}
+
+func TestNewRestClient_EnvTimeout(t *testing.T) {
+	defer os.Unsetenv(rest.EnvTimeout)
+	timeout := func(opts client.Options) time.Duration {
+		c, _ := rest.NewRestClient(opts)
+		return c.(*rest.Client).EffectiveOptions().Timeout
+	}
+	os.Setenv(rest.EnvTimeout, "1500ms")
+	assert.Equal(t, 1500*time.Millisecond, timeout(client.Options{}))
+	assert.Equal(t, time.Second, timeout(client.Options{Timeout: time.Second}), "option wins")
+	c, _ := rest.NewRestClient(client.Options{Timeout: time.Second})
+	c.ReloadConfigs(client.Options{})
+	assert.Equal(t, 1500*time.Millisecond, c.(*rest.Client).EffectiveOptions().Timeout, "reload without timeout falls back to env")
+
+	for _, v := range []string{"abc", "-1s", "0", ""} {
+		os.Setenv(rest.EnvTimeout, v)
+		assert.Equal(t, rest.DefaultTimeout, timeout(client.Options{}), "invalid value [%s] falls back to default", v)
+	}
+}
+
+func TestNewRestClient_HTTP1Fallback(t *testing.T) {
+	var protos []string
+	s := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		protos = append(protos, r.Proto)
+		if r.ProtoMajor == 2 {
+			w.WriteHeader(http.StatusHTTPVersionNotSupported)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	s.TLS = &tls.Config{NextProtos: []string{"h2", "http/1.1"}}
+	s.StartTLS()
+	defer s.Close()
+	addr := strings.TrimPrefix(s.URL, "https://")
+	c, _ := rest.NewRestClient(client.Options{
+		TLSConfig:   &tls.Config{InsecureSkipVerify: true},
+		EnableHTTP2: true,
+		Failure:     map[string]client.FailureKind{"http_505": client.HTTP1FallbackFailure},
+	})
+	r, _ := rest.NewRequest("GET", "http://Server/", nil)
+	inv := &invocation.Invocation{MicroServiceName: "Server", Args: r}
+
+	err := c.Call(context.TODO(), addr, inv, rest.NewResponse())
+	var statusErr *rest.StatusError
+	if assert.True(t, errors.As(err, &statusErr)) {
+		assert.Equal(t, http.StatusHTTPVersionNotSupported, statusErr.StatusCode)
+	}
+	var pe *rest.PermanentError
+	assert.False(t, errors.As(err, &pe), "505 over HTTP/2 can be retried")
+	assert.Equal(t, true, inv.Metadata[common.HTTP1FallbackKey])
+
+	resp := rest.NewResponse()
+	assert.NoError(t, c.Call(context.TODO(), addr, inv, resp), "retry falls back to HTTP/1.1")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, []string{"HTTP/2.0", "HTTP/1.1"}, protos)
+
+	err = c.Call(context.TODO(), addr, inv, rest.NewResponse())
+	assert.NoError(t, err, "request of invocation keeps using HTTP/1.1")
+
+	h1 := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusHTTPVersionNotSupported)
+	}))
+	defer h1.Close()
+	c, _ = rest.NewRestClient(client.Options{
+		Failure: map[string]client.FailureKind{"http_505": client.HTTP1FallbackFailure},
+	})
+	r, _ = rest.NewRequest("GET", "http://Server/", nil)
+	err = c.Call(context.TODO(), strings.TrimPrefix(h1.URL, "http://"), &invocation.Invocation{MicroServiceName: "Server", Args: r}, rest.NewResponse())
+	assert.True(t, errors.As(err, &pe), "505 over HTTP/1.1 has nothing to fall back to")
+}
+
+func TestNewRestClient_MultiStatus(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/xml")
+		w.WriteHeader(http.StatusMultiStatus)
+		w.Write([]byte(`<d:multistatus xmlns:d="DAV:"><d:response><d:href>/files/a</d:href><d:status>HTTP/1.1 200 OK</d:status></d:response>`+
+			`<d:response><d:href>/files/b</d:href><d:status>HTTP/1.1 423 Locked</d:status></d:response></d:multistatus>`))
+	}))
+	defer s.Close()
+	c, _ := rest.NewRestClient(client.Options{Failure: map[string]client.FailureKind{"http_207": client.RetryableFailure}})
+	r, _ := rest.NewRequest("DELETE", "http://Server/files", nil)
+	resp := rest.NewResponse()
+	err := c.Call(context.TODO(), strings.TrimPrefix(s.URL, "http://"), &invocation.Invocation{MicroServiceName: "Server", Args: r}, resp)
+	assert.NoError(t, err, "207 is success even if failure map has it")
+	statuses, err := httputil.DecodeMultiStatus(resp)
+	assert.NoError(t, err)
+	assert.Equal(t, []httputil.ResourceStatus{
+		{Href: "/files/a", StatusCode: http.StatusOK},
+		{Href: "/files/b", StatusCode: http.StatusLocked},
+	}, statuses)
+}
@@ -5,6 +5,7 @@ import (
 	"github.com/go-chassis/go-chassis/v2/core/client"
 	"io"
 	"net/http"
+	"sync"
 )
 
 //NewRequest is a function which creates new request
@@ -31,6 +32,10 @@ func NewResponse() *http.Response {
 
 //Client is a struct
 type Client struct {
-	c    *http.Client
-	opts client.Options
+	c      *http.Client
+	opts   client.Options
+	dialer *dialer
+	// connection pools of isolation keys and hosts
+	poolMu sync.Mutex
+	pools  map[poolKey]*http.Client
 }
@@ -0,0 +1,39 @@
+package rest
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+)
+
+// StatusError is returned if response status is defined as failure
+type StatusError struct {
+	StatusCode int
+	Addr       string
+	// Body is only read if invocation asks for it, see common.ErrorBodyKey
+	Body []byte
+}
+
+// Error returns error message
+func (e *StatusError) Error() string {
+	if e.Body == nil {
+		return fmt.Sprintf("http error status [%d], server addr: [%s], will not print response body, to protect service sensitive data",
+			e.StatusCode, e.Addr)
+	}
+	return fmt.Sprintf("http error status [%d], server addr: [%s], body: %s", e.StatusCode, e.Addr, e.Body)
+}
+
+// readErrorBody reads body into error, body of response is kept for reading
+func readErrorBody(r *http.Response) []byte {
+	if r.Body == nil || r.Body == http.NoBody {
+		return []byte{}
+	}
+	b, err := ioutil.ReadAll(r.Body)
+	r.Body.Close()
+	r.Body = ioutil.NopCloser(bytes.NewReader(b))
+	if err != nil {
+		return []byte(fmt.Sprintf("<can not read body: %s>", err))
+	}
+	return b
+}
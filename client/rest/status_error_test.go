@@ -0,0 +1,71 @@
+package rest
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/go-chassis/go-chassis/v2/core/client"
+	"github.com/go-chassis/go-chassis/v2/core/common"
+	"github.com/go-chassis/go-chassis/v2/core/invocation"
+	"github.com/go-chassis/go-chassis/v2/pkg/util/httputil"
+	"github.com/stretchr/testify/assert"
+)
+
+// countingBody counts how many times body is read
+type countingBody struct {
+	r     *strings.Reader
+	reads int
+}
+
+func (b *countingBody) Read(p []byte) (int, error) {
+	b.reads++
+	return b.r.Read(p)
+}
+
+func (b *countingBody) Close() error { return nil }
+
+type statusTransport struct {
+	body *countingBody
+}
+
+func (t *statusTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	return &http.Response{StatusCode: http.StatusInternalServerError, Header: http.Header{}, Body: t.body, Request: req}, nil
+}
+
+func TestClient_ErrorBody(t *testing.T) {
+	call := func(include bool) (*countingBody, *http.Response, error) {
+		c, _ := NewRestClient(client.Options{Failure: map[string]client.FailureKind{"http_500": client.RetryableFailure}})
+		tp := &statusTransport{body: &countingBody{r: strings.NewReader("internal error detail")}}
+		c.(*Client).c.Transport = tp
+		r, err := NewRequest("GET", "http://Server/", nil)
+		assert.NoError(t, err)
+		inv := &invocation.Invocation{MicroServiceName: "Server", Args: r}
+		if include {
+			inv.SetMetadata(common.ErrorBodyKey, true)
+		}
+		resp := NewResponse()
+		err = c.Call(context.TODO(), "127.0.0.1:8080", inv, resp)
+		return tp.body, resp, err
+	}
+	t.Run("body is not read by default", func(t *testing.T) {
+		body, _, err := call(false)
+		var statusErr *StatusError
+		assert.True(t, errors.As(err, &statusErr))
+		assert.Equal(t, http.StatusInternalServerError, statusErr.StatusCode)
+		assert.Nil(t, statusErr.Body)
+		assert.Equal(t, 0, body.reads)
+		assert.NotContains(t, err.Error(), "internal error detail")
+	})
+	t.Run("body is attached to error", func(t *testing.T) {
+		body, resp, err := call(true)
+		var statusErr *StatusError
+		assert.True(t, errors.As(err, &statusErr))
+		assert.Equal(t, "internal error detail", string(statusErr.Body))
+		assert.Contains(t, err.Error(), "internal error detail")
+		assert.NotEqual(t, 0, body.reads)
+		assert.Equal(t, "internal error detail", string(httputil.ReadBody(resp)), "body is still readable")
+	})
+}
@@ -0,0 +1,157 @@
+package rest
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	"sync"
+	"sync/atomic"
+
+	"github.com/go-chassis/go-chassis/v2/core/client"
+	"github.com/go-chassis/go-chassis/v2/pkg/metrics"
+	"github.com/go-chassis/openlog"
+)
+
+// TimeoutPhase tells in which phase of a call the timeout happened.
+// phases are tracked by rest client only, there is no highway client in this repository
+type TimeoutPhase string
+
+// timeout phases, they are in the order of a call
+const (
+	// PhaseDial means connection could not be established in time
+	PhaseDial TimeoutPhase = "dial"
+	// PhaseTLSHandshake means connection is established, but TLS handshake is not finished
+	PhaseTLSHandshake TimeoutPhase = "tls_handshake"
+	// PhaseWrite means request headers or body could not be written in time
+	PhaseWrite TimeoutPhase = "write"
+	// PhaseRead means request is sent, but response headers are not received in time
+	PhaseRead TimeoutPhase = "read"
+)
+
+// MetricTimeouts counts timeouts of calls by host and phase
+const MetricTimeouts = "rest_client_timeouts_total"
+
+// TimeoutError is returned if a call timed out, Phase tells where the time was spent.
+// if timeout is caused by deadline of context, Err is client.ErrCanceled
+type TimeoutError struct {
+	Phase TimeoutPhase
+	Addr  string
+	Err   error
+}
+
+// Error returns error message
+func (e *TimeoutError) Error() string {
+	return fmt.Sprintf("%s timeout, server addr: [%s]: %s", e.Phase, e.Addr, e.Err)
+}
+
+// Unwrap returns the transport error
+func (e *TimeoutError) Unwrap() error {
+	return e.Err
+}
+
+// Timeout is always true, it makes TimeoutError a net.Error
+func (e *TimeoutError) Timeout() bool {
+	return true
+}
+
+// Temporary tells request may success if it is sent again
+func (e *TimeoutError) Temporary() bool {
+	return true
+}
+
+// DialFailed tells if connection could not be established in time while context of call is not done,
+// the endpoint may be unreachable, retry goes to another endpoint
+func (e *TimeoutError) DialFailed() bool {
+	return e.Phase == PhaseDial && !errors.Is(e.Err, client.ErrCanceled)
+}
+
+// stages of a phaseTracker, it only goes forward
+const (
+	stageDial int32 = iota
+	stageTLSHandshake
+	stageWrite
+	stageRead
+)
+
+// phaseTracker records how far a request went by httptrace, trace hooks may be called
+// by transport after Call returned, so stage is read and written atomically
+type phaseTracker struct {
+	stage int32
+}
+
+func (p *phaseTracker) advance(stage int32) {
+	for {
+		cur := atomic.LoadInt32(&p.stage)
+		if cur >= stage || atomic.CompareAndSwapInt32(&p.stage, cur, stage) {
+			return
+		}
+	}
+}
+
+// Phase returns the phase request is in
+func (p *phaseTracker) Phase() TimeoutPhase {
+	switch atomic.LoadInt32(&p.stage) {
+	case stageTLSHandshake:
+		return PhaseTLSHandshake
+	case stageWrite:
+		return PhaseWrite
+	case stageRead:
+		return PhaseRead
+	default:
+		return PhaseDial
+	}
+}
+
+// tracePhase returns a request which records its phase into tracker
+func tracePhase(req *http.Request) (*http.Request, *phaseTracker) {
+	p := &phaseTracker{}
+	return req.WithContext(httptrace.WithClientTrace(req.Context(), &httptrace.ClientTrace{
+		TLSHandshakeStart: func() {
+			p.advance(stageTLSHandshake)
+		},
+		TLSHandshakeDone: func(tls.ConnectionState, error) {
+			p.advance(stageWrite)
+		},
+		GotConn: func(httptrace.GotConnInfo) {
+			p.advance(stageWrite)
+		},
+		WroteRequest: func(info httptrace.WroteRequestInfo) {
+			// it is also called if writing is aborted
+			if info.Err == nil {
+				p.advance(stageRead)
+			}
+		},
+	})), p
+}
+
+// isTimeout tells if the transport error is a timeout
+func isTimeout(err error) bool {
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+var timeoutMetricOnce sync.Once
+
+// reportTimeout counts the timeout, metric is only reported when metrics registry is initialized
+func reportTimeout(host string, phase TimeoutPhase) {
+	if !metrics.Enabled() {
+		return
+	}
+	timeoutMetricOnce.Do(func() {
+		err := metrics.CreateCounter(metrics.CounterOpts{
+			Name:   MetricTimeouts,
+			Help:   "count of rest client timeouts by phase, phase can be dial, tls_handshake, write or read",
+			Labels: []string{"host", "phase"},
+		})
+		if err != nil {
+			openlog.Error(err.Error())
+		}
+	})
+	labels := map[string]string{"host": host, "phase": string(phase)}
+	if err := metrics.CounterAdd(MetricTimeouts, 1, labels); err != nil {
+		openlog.Error("can not report timeout: " + err.Error())
+	}
+}
@@ -0,0 +1,130 @@
+package rest
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"errors"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chassis/go-chassis/v2/core/client"
+	"github.com/go-chassis/go-chassis/v2/core/invocation"
+	"github.com/stretchr/testify/assert"
+)
+
+// stallListener accepts connections and stops serving them,
+// if readHeaders is true, request headers are read before it stops reading.
+// connections are closed when listener is closed
+func stallListener(t *testing.T, readHeaders bool) net.Listener {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	go func() {
+		var conns []net.Conn
+		defer func() {
+			for _, c := range conns {
+				c.Close()
+			}
+		}()
+		for {
+			c, err := l.Accept()
+			if err != nil {
+				return
+			}
+			conns = append(conns, c)
+			if readHeaders {
+				go http.ReadRequest(bufio.NewReader(c))
+			}
+		}
+	}()
+	return l
+}
+
+// endlessBody never ends, so writing it blocks once server stops reading
+type endlessBody struct{}
+
+func (endlessBody) Read(p []byte) (int, error) {
+	for i := range p {
+		p[i] = 'x'
+	}
+	return len(p), nil
+}
+
+func callTimeout(t *testing.T, c client.ProtocolClient, addr string, r *http.Request) *TimeoutError {
+	err := c.Call(context.TODO(), addr, &invocation.Invocation{MicroServiceName: "Server", Args: r}, NewResponse())
+	var timeoutErr *TimeoutError
+	if assert.True(t, errors.As(err, &timeoutErr), "got %v", err) {
+		assert.Equal(t, addr, timeoutErr.Addr)
+		assert.True(t, isTimeout(err))
+	}
+	return timeoutErr
+}
+
+func TestClient_TimeoutPhase(t *testing.T) {
+	t.Run("dial", func(t *testing.T) {
+		c, _ := NewRestClient(client.Options{Timeout: 100 * time.Millisecond})
+		c.(*Client).c.Transport.(*http.Transport).DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+			<-ctx.Done()
+			return nil, ctx.Err()
+		}
+		r, _ := NewRequest("GET", "http://Server/", nil)
+		if err := callTimeout(t, c, "127.0.0.1:8080", r); err != nil {
+			assert.Equal(t, PhaseDial, err.Phase)
+		}
+	})
+	t.Run("tls handshake", func(t *testing.T) {
+		l := stallListener(t, false)
+		defer l.Close()
+		c, _ := NewRestClient(client.Options{Timeout: 100 * time.Millisecond, TLSConfig: &tls.Config{InsecureSkipVerify: true}})
+		r, _ := NewRequest("GET", "http://Server/", nil)
+		if err := callTimeout(t, c, l.Addr().String(), r); err != nil {
+			assert.Equal(t, PhaseTLSHandshake, err.Phase)
+		}
+	})
+	t.Run("write", func(t *testing.T) {
+		l := stallListener(t, true)
+		defer l.Close()
+		c, _ := NewRestClient(client.Options{Timeout: 100 * time.Millisecond})
+		r, _ := http.NewRequest("POST", "http://Server/", ioutil.NopCloser(endlessBody{}))
+		if err := callTimeout(t, c, l.Addr().String(), r); err != nil {
+			assert.Equal(t, PhaseWrite, err.Phase)
+		}
+	})
+	t.Run("read", func(t *testing.T) {
+		release := make(chan struct{})
+		s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			<-release
+		}))
+		defer s.Close()
+		defer close(release)
+		c, _ := NewRestClient(client.Options{Timeout: 100 * time.Millisecond})
+		r, _ := NewRequest("POST", "http://Server/", []byte("sent"))
+		if err := callTimeout(t, c, strings.TrimPrefix(s.URL, "http://"), r); err != nil {
+			assert.Equal(t, PhaseRead, err.Phase)
+		}
+	})
+}
+
+func TestClient_TimeoutPhase_ContextDeadline(t *testing.T) {
+	release := make(chan struct{})
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer s.Close()
+	defer close(release)
+	c, _ := NewRestClient(client.Options{})
+	r, _ := NewRequest("GET", "http://Server/", nil)
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	err := c.Call(ctx, strings.TrimPrefix(s.URL, "http://"), &invocation.Invocation{MicroServiceName: "Server", Args: r}, NewResponse())
+	var timeoutErr *TimeoutError
+	if assert.True(t, errors.As(err, &timeoutErr), "got %v", err) {
+		assert.Equal(t, PhaseRead, timeoutErr.Phase)
+	}
+	assert.True(t, errors.Is(err, client.ErrCanceled))
+}
@@ -0,0 +1,87 @@
+package rest
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sync"
+)
+
+// BodyTransform receives content type and body bytes, returns the transformed body
+type BodyTransform func(contentType string, body []byte) ([]byte, error)
+
+// BodyTransformer transforms request body before it is sent and response body before it is returned to caller,
+// for example, wrap request body in an envelope {"data": ...} and unwrap it from response.
+// any of the transform can be nil
+type BodyTransformer struct {
+	Request  BodyTransform
+	Response BodyTransform
+}
+
+var (
+	transformers   = make(map[string]*BodyTransformer)
+	transformersMu sync.RWMutex
+)
+
+// InstallBodyTransformer install body transformer for a micro service,
+// it applies to all rest calls to this service
+func InstallBodyTransformer(service string, t *BodyTransformer) {
+	transformersMu.Lock()
+	transformers[service] = t
+	transformersMu.Unlock()
+}
+
+// RemoveBodyTransformer removes body transformer of a micro service
+func RemoveBodyTransformer(service string) {
+	transformersMu.Lock()
+	delete(transformers, service)
+	transformersMu.Unlock()
+}
+
+func getBodyTransformer(service string) *BodyTransformer {
+	transformersMu.RLock()
+	t := transformers[service]
+	transformersMu.RUnlock()
+	return t
+}
+
+func (t *BodyTransformer) transformRequest(req *http.Request) error {
+	if t.Request == nil || req.Body == nil {
+		return nil
+	}
+	body, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		return err
+	}
+	req.Body.Close()
+	body, err = t.Request(req.Header.Get("Content-Type"), body)
+	if err != nil {
+		return err
+	}
+	req.Body = ioutil.NopCloser(bytes.NewReader(body))
+	req.ContentLength = int64(len(body))
+	// replayed request must also be transformed
+	req.GetBody = func() (io.ReadCloser, error) {
+		return ioutil.NopCloser(bytes.NewReader(body)), nil
+	}
+	return nil
+}
+
+func (t *BodyTransformer) transformResponse(resp *http.Response) error {
+	if t.Response == nil || resp.Body == nil {
+		return nil
+	}
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	body, err = t.Response(resp.Header.Get("Content-Type"), body)
+	if err != nil {
+		return err
+	}
+	resp.Body = ioutil.NopCloser(bytes.NewReader(body))
+	resp.ContentLength = int64(len(body))
+	return nil
+}
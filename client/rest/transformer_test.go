@@ -0,0 +1,60 @@
+package rest_test
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chassis/go-chassis/v2/client/rest"
+	"github.com/go-chassis/go-chassis/v2/core/client"
+	"github.com/go-chassis/go-chassis/v2/core/invocation"
+	"github.com/stretchr/testify/assert"
+)
+
+type envelope struct {
+	Data json.RawMessage `json:"data"`
+}
+
+func TestInstallBodyTransformer(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		e := &envelope{}
+		if err := json.Unmarshal(body, e); err != nil || len(e.Data) == 0 {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(body)
+	}))
+	defer s.Close()
+	rest.InstallBodyTransformer("EnvelopeServer", &rest.BodyTransformer{
+		Request: func(contentType string, body []byte) ([]byte, error) {
+			return json.Marshal(&envelope{Data: body})
+		},
+		Response: func(contentType string, body []byte) ([]byte, error) {
+			e := &envelope{}
+			if err := json.Unmarshal(body, e); err != nil {
+				return nil, err
+			}
+			return e.Data, nil
+		},
+	})
+	defer rest.RemoveBodyTransformer("EnvelopeServer")
+
+	c, err := rest.NewRestClient(client.Options{})
+	assert.NoError(t, err)
+	r, err := rest.NewRequest("POST", "http://EnvelopeServer/", []byte(`{"name":"peter"}`))
+	assert.NoError(t, err)
+	reply := rest.NewResponse()
+	err = c.Call(context.TODO(), strings.TrimPrefix(s.URL, "http://"),
+		&invocation.Invocation{MicroServiceName: "EnvelopeServer", Args: r}, reply)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, reply.StatusCode)
+	body, err := ioutil.ReadAll(reply.Body)
+	assert.NoError(t, err)
+	assert.Equal(t, `{"name":"peter"}`, string(body))
+}
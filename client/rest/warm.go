@@ -0,0 +1,176 @@
+package rest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/go-chassis/go-chassis/v2/core/loadbalancer"
+	"github.com/go-chassis/go-chassis/v2/core/registry"
+	"github.com/go-chassis/openlog"
+)
+
+// WarmedConnTTL is how long a connection dialed in advance waits to be used, it is closed after that
+var WarmedConnTTL = 30 * time.Second
+
+// warmer dials connections to new endpoints of a service
+type warmer struct {
+	d *dialer
+	n int
+}
+
+type warmedConn struct {
+	net.Conn
+	expires time.Time
+}
+
+var (
+	// warmers key is service name, the latest client of service which enables warming decides how to dial
+	warmers          = make(map[string]*warmer)
+	warmersMu        sync.Mutex
+	warmObserverOnce sync.Once
+
+	// warmed key is address, connections are used by any client which dials the address.
+	// a client is created for each endpoint, a new endpoint has no client until it is called
+	warmed   = make(map[string][]warmedConn)
+	warmedMu sync.Mutex
+	// dialing key is address, it counts connections being dialed in advance, they are reserved in warmedMu
+	dialing = make(map[string]int)
+)
+
+// enableAutoWarm makes n connections to each endpoint which discovery newly finds for service be dialed by d,
+// n is 0 disables it
+func enableAutoWarm(service string, n int, d *dialer) {
+	if service == "" {
+		return
+	}
+	warmersMu.Lock()
+	if n <= 0 {
+		delete(warmers, service)
+		warmersMu.Unlock()
+		return
+	}
+	warmers[service] = &warmer{d: d, n: n}
+	warmersMu.Unlock()
+	warmObserverOnce.Do(func() {
+		loadbalancer.OnEndpointsChanged(warmEndpoints)
+	})
+}
+
+// warmEndpoints dials connections to added endpoints in background, and closes the ones to removed endpoints
+func warmEndpoints(service string, added, removed []*registry.Endpoint) {
+	for _, ep := range removed {
+		dropWarmed(ep.Address)
+	}
+	warmersMu.Lock()
+	w := warmers[service]
+	warmersMu.Unlock()
+	if w == nil {
+		return
+	}
+	for _, ep := range added {
+		go w.warm(ep.Address)
+	}
+}
+
+// warm dials connections to addr until it has n waiting or being dialed ones, failures are logged.
+// slots are reserved before dialing, so that concurrent warming of addr does not dial more than n
+func (w *warmer) warm(addr string) {
+	warmedMu.Lock()
+	need := w.n - len(warmed[addr]) - dialing[addr]
+	if need > 0 {
+		dialing[addr] += need
+	}
+	warmedMu.Unlock()
+	for i := 0; i < need; i++ {
+		conn, err := w.d.dialConn(context.Background(), "tcp", addr)
+		warmedMu.Lock()
+		if err != nil {
+			releaseDialing(addr, need-i)
+			warmedMu.Unlock()
+			openlog.Warn(fmt.Sprintf("can not warm connection to [%s]: %s", addr, err))
+			return
+		}
+		releaseDialing(addr, 1)
+		warmed[addr] = append(warmed[addr], warmedConn{Conn: conn, expires: time.Now().Add(WarmedConnTTL)})
+		warmedMu.Unlock()
+	}
+	if need > 0 {
+		time.AfterFunc(WarmedConnTTL, func() {
+			purgeWarmed(addr)
+		})
+	}
+}
+
+// releaseDialing frees n reserved slots of addr, warmedMu must be held
+func releaseDialing(addr string, n int) {
+	if dialing[addr] -= n; dialing[addr] <= 0 {
+		delete(dialing, addr)
+	}
+}
+
+// takeWarmed returns a live connection to addr which is dialed in advance, it returns nil if there is none
+func takeWarmed(addr string) net.Conn {
+	warmedMu.Lock()
+	defer warmedMu.Unlock()
+	conns := warmed[addr]
+	if len(conns) == 0 {
+		return nil
+	}
+	now := time.Now()
+	for len(conns) > 0 {
+		c := conns[0]
+		conns = conns[1:]
+		if now.Before(c.expires) && alive(c.Conn) {
+			warmed[addr] = conns
+			return c.Conn
+		}
+		c.Close()
+	}
+	delete(warmed, addr)
+	return nil
+}
+
+// purgeWarmed closes connections to addr which are not used in WarmedConnTTL
+func purgeWarmed(addr string) {
+	warmedMu.Lock()
+	defer warmedMu.Unlock()
+	now := time.Now()
+	var kept []warmedConn
+	for _, c := range warmed[addr] {
+		if now.Before(c.expires) {
+			kept = append(kept, c)
+			continue
+		}
+		c.Close()
+	}
+	if len(kept) == 0 {
+		delete(warmed, addr)
+		return
+	}
+	warmed[addr] = kept
+}
+
+func dropWarmed(addr string) {
+	warmedMu.Lock()
+	defer warmedMu.Unlock()
+	for _, c := range warmed[addr] {
+		c.Close()
+	}
+	delete(warmed, addr)
+}
+
+// alive tells if peer has not closed a connection which is never used, it reads without waiting
+func alive(conn net.Conn) bool {
+	if err := conn.SetReadDeadline(time.Now()); err != nil {
+		return false
+	}
+	var b [1]byte
+	_, err := conn.Read(b[:])
+	conn.SetReadDeadline(time.Time{})
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
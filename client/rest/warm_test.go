@@ -0,0 +1,89 @@
+package rest_test
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-chassis/go-chassis/v2/client/rest"
+	"github.com/go-chassis/go-chassis/v2/core/client"
+	"github.com/go-chassis/go-chassis/v2/core/invocation"
+	"github.com/go-chassis/go-chassis/v2/core/loadbalancer"
+	"github.com/go-chassis/go-chassis/v2/core/registry"
+	mk "github.com/go-chassis/go-chassis/v2/core/registry/mock"
+	"github.com/go-chassis/go-chassis/v2/pkg/util/tags"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewRestClient_AutoWarmNewEndpoints(t *testing.T) {
+	oldDebounce := loadbalancer.EndpointsChangedDebounce
+	loadbalancer.EndpointsChangedDebounce = 20 * time.Millisecond
+	defer func() { loadbalancer.EndpointsChangedDebounce = oldDebounce }()
+	oldDiscovery := registry.DefaultServiceDiscoveryService
+	defer func() { registry.DefaultServiceDiscoveryService = oldDiscovery }()
+
+	// accepted counts connections server accepts
+	newServer := func(accepted *int64) (*httptest.Server, string) {
+		s := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+		s.Config.ConnState = func(c net.Conn, state http.ConnState) {
+			if state == http.StateNew {
+				atomic.AddInt64(accepted, 1)
+			}
+		}
+		s.Start()
+		return s, strings.TrimPrefix(s.URL, "http://")
+	}
+	var acceptedA, acceptedB, acceptedC int64
+	a, addrA := newServer(&acceptedA)
+	defer a.Close()
+	b, addrB := newServer(&acceptedB)
+	defer b.Close()
+	cs, addrC := newServer(&acceptedC)
+	defer cs.Close()
+	newIns := func(addr string) *registry.MicroServiceInstance {
+		return &registry.MicroServiceInstance{InstanceID: addr,
+			EndpointsMap: map[string]*registry.Endpoint{"rest": {Address: addr}}}
+	}
+	d := &mk.DiscoveryMock{}
+	registry.DefaultServiceDiscoveryService = d
+	d.On("FindMicroServiceInstances", "selfServiceID", "appID", "warmService", "1.0", "").
+		Return([]*registry.MicroServiceInstance{newIns(addrA)}, nil).Once()
+	d.On("FindMicroServiceInstances", "selfServiceID", "appID", "warmService", "1.0", "").
+		Return([]*registry.MicroServiceInstance{newIns(addrA), newIns(addrB)}, nil).Once()
+	d.On("FindMicroServiceInstances", "selfServiceID", "appID", "warmService", "1.0", "").
+		Return([]*registry.MicroServiceInstance{newIns(addrA), newIns(addrB), newIns(addrC)}, nil)
+	discover := func() {
+		inv := invocation.New(context.Background())
+		inv.SourceServiceID = "selfServiceID"
+		inv.MicroServiceName = "warmService"
+		inv.RouteTags = utiltags.NewDefaultTag("1.0", "appID")
+		_, err := loadbalancer.BuildStrategy(inv, nil)
+		assert.NoError(t, err)
+	}
+	accepted := func(n *int64, want int64) func() bool {
+		return func() bool { return atomic.LoadInt64(n) == want }
+	}
+
+	c, _ := rest.NewRestClient(client.Options{Service: "warmService", AutoWarmNewEndpoints: 2})
+	discover()
+	assert.Eventually(t, accepted(&acceptedA, 2), time.Second, 10*time.Millisecond, "endpoints of first discovery are new")
+	discover()
+	assert.Eventually(t, accepted(&acceptedB, 2), time.Second, 10*time.Millisecond, "added endpoint is warmed")
+	time.Sleep(50 * time.Millisecond)
+	assert.Equal(t, int64(2), atomic.LoadInt64(&acceptedA), "known endpoint is not warmed again")
+
+	r, _ := rest.NewRequest(http.MethodGet, "http://warmService/", nil)
+	err := c.Call(context.TODO(), addrB, &invocation.Invocation{MicroServiceName: "warmService", Args: r}, rest.NewResponse())
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), atomic.LoadInt64(&acceptedB), "request is sent on warmed connection")
+
+	c.ReloadConfigs(client.Options{Service: "warmService"})
+	discover()
+	time.Sleep(100 * time.Millisecond)
+	assert.Zero(t, atomic.LoadInt64(&acceptedC), "reload disables warming")
+}
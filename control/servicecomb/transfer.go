@@ -40,11 +40,15 @@ func saveDefaultLB(raw *model.LoadBalancing) string { // return updated key
 		RetryEnabled:            raw.RetryEnabled,
 		RetryOnSame:             raw.RetryOnSame,
 		RetryOnNext:             raw.RetryOnNext,
+		RetryIdempotentOnly:     raw.RetryIdempotentOnly,
 		BackOffKind:             raw.Backoff.Kind,
 		BackOffMin:              raw.Backoff.MinMs,
 		BackOffMax:              raw.Backoff.MaxMs,
 		SessionTimeoutInSeconds: raw.SessionStickinessRule.SessionTimeoutInSeconds,
 		SuccessiveFailedTimes:   raw.SessionStickinessRule.SuccessiveFailedTimes,
+		PickSampleSize:          raw.PickSampleSize,
+		LatencyDecay:            raw.LatencyDecay,
+		LatencyMinSamples:       raw.LatencyMinSamples,
 	}
 
 	setDefaultLBValue(&c)
@@ -57,11 +61,15 @@ func saveEachLB(k string, raw model.LoadBalancingSpec) string { // return update
 		RetryEnabled:            raw.RetryEnabled,
 		RetryOnSame:             raw.RetryOnSame,
 		RetryOnNext:             raw.RetryOnNext,
+		RetryIdempotentOnly:     raw.RetryIdempotentOnly,
 		BackOffKind:             raw.Backoff.Kind,
 		BackOffMin:              raw.Backoff.MinMs,
 		BackOffMax:              raw.Backoff.MaxMs,
 		SessionTimeoutInSeconds: raw.SessionStickinessRule.SessionTimeoutInSeconds,
 		SuccessiveFailedTimes:   raw.SessionStickinessRule.SuccessiveFailedTimes,
+		PickSampleSize:          raw.PickSampleSize,
+		LatencyDecay:            raw.LatencyDecay,
+		LatencyMinSamples:       raw.LatencyMinSamples,
 	}
 	openlog.Info(fmt.Sprintf("save lb config [%s] [%v]", k, raw))
 	setDefaultLBValue(&c)
@@ -18,16 +18,25 @@ func TestSaveToLBCache(t *testing.T) {
 		Strategy: map[string]string{
 			"name": loadbalancer.StrategyRoundRobin,
 		},
+		PickSampleSize:    2,
+		LatencyDecay:      0.3,
+		LatencyMinSamples: 5,
 		AnyService: map[string]model.LoadBalancingSpec{
 			"test": {
 				Strategy: map[string]string{
 					"name": loadbalancer.StrategyRoundRobin,
 				},
+				PickSampleSize: 4,
 			},
 		},
 	})
 	c, _ := servicecomb.LBConfigCache.Get("test")
 	assert.Equal(t, loadbalancer.StrategyRoundRobin, c.(control.LoadBalancingConfig).Strategy)
+	assert.Equal(t, 4, c.(control.LoadBalancingConfig).PickSampleSize)
+	c, _ = servicecomb.LBConfigCache.Get("")
+	assert.Equal(t, 2, c.(control.LoadBalancingConfig).PickSampleSize)
+	assert.Equal(t, 0.3, c.(control.LoadBalancingConfig).LatencyDecay)
+	assert.Equal(t, 5, c.(control.LoadBalancingConfig).LatencyMinSamples)
 }
 func init() {
 	lager.Init(&lager.Options{
@@ -10,9 +10,17 @@ type LoadBalancingConfig struct {
 	BackOffKind  string
 	BackOffMin   int
 	BackOffMax   int
+	// RetryIdempotentOnly stops retrying requests which are not idempotent
+	RetryIdempotentOnly bool
 
 	SessionTimeoutInSeconds int
 	SuccessiveFailedTimes   int
+	// PickSampleSize is the number of instances which strategies scanning all instances pick from, 0 means all
+	PickSampleSize int
+	// LatencyDecay is the weight of a new latency sample in average latency of an endpoint, 0 means plain average
+	LatencyDecay float64
+	// LatencyMinSamples is the number of latencies an endpoint must have before latency strategy acts on them
+	LatencyMinSamples int
 }
 
 //RateLimitingConfig is a standardized model
@@ -0,0 +1,135 @@
+package core
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"sync"
+)
+
+// ErrAdmissionWeight means weight of call is larger than admission limit of service, it can never be admitted
+var ErrAdmissionWeight = errors.New("admission weight exceeds limit of service")
+
+var (
+	admissions   = make(map[string]*weighted)
+	admissionsMu sync.RWMutex
+)
+
+// SetAdmissionLimit sets the total weight of concurrent calls to service which use WithAdmission,
+// excess calls wait until capacity frees. size <= 0 removes the limit
+func SetAdmissionLimit(service string, size int64) {
+	admissionsMu.Lock()
+	defer admissionsMu.Unlock()
+	if size <= 0 {
+		delete(admissions, service)
+		return
+	}
+	admissions[service] = newWeighted(size)
+}
+
+func getAdmission(service string) *weighted {
+	admissionsMu.RLock()
+	defer admissionsMu.RUnlock()
+	return admissions[service]
+}
+
+// Priority decides the order calls are admitted
+type Priority int
+
+// priorities of call, high priority calls are admitted ahead of waiting normal ones
+const (
+	PriorityNormal Priority = iota
+	PriorityHigh
+)
+
+// weighted is a semaphore which admits waiters of high priority first, then others, each in FIFO order
+type weighted struct {
+	size int64
+	cur  int64
+	mu   sync.Mutex
+	// high and normal waiters
+	high   list.List
+	normal list.List
+}
+
+type waiter struct {
+	n     int64
+	ready chan struct{}
+}
+
+func newWeighted(size int64) *weighted {
+	return &weighted{size: size}
+}
+
+func (s *weighted) queue(p Priority) *list.List {
+	if p == PriorityHigh {
+		return &s.high
+	}
+	return &s.normal
+}
+
+// acquire blocks until n is admitted or ctx is done
+func (s *weighted) acquire(ctx context.Context, n int64, p Priority) error {
+	s.mu.Lock()
+	waiting := s.high.Len()
+	if p != PriorityHigh {
+		waiting += s.normal.Len()
+	}
+	if s.size-s.cur >= n && waiting == 0 {
+		s.cur += n
+		s.mu.Unlock()
+		return nil
+	}
+	if n > s.size {
+		s.mu.Unlock()
+		return ErrAdmissionWeight
+	}
+	ready := make(chan struct{})
+	q := s.queue(p)
+	elem := q.PushBack(waiter{n: n, ready: ready})
+	s.mu.Unlock()
+
+	select {
+	case <-ready:
+		return nil
+	case <-ctx.Done():
+		s.mu.Lock()
+		select {
+		case <-ready:
+			// admitted right after ctx is done, give it back
+			s.cur -= n
+		default:
+			q.Remove(elem)
+		}
+		s.notify()
+		s.mu.Unlock()
+		return ctx.Err()
+	}
+}
+
+func (s *weighted) release(n int64) {
+	s.mu.Lock()
+	s.cur -= n
+	s.notify()
+	s.mu.Unlock()
+}
+
+// notify admits waiters in order as long as there is capacity,
+// normal waiters are not admitted while a high one is waiting for capacity
+func (s *weighted) notify() {
+	for _, q := range []*list.List{&s.high, &s.normal} {
+		for {
+			next := q.Front()
+			if next == nil {
+				break
+			}
+			w := next.Value.(waiter)
+			if s.size-s.cur < w.n {
+				return
+			}
+			s.cur += w.n
+			q.Remove(next)
+			close(w.ready)
+		}
+	}
+}
@@ -26,6 +26,25 @@ var ErrClientNotExist = errors.New("client not exists")
 //DefaultPoolSize is 500
 const DefaultPoolSize = 512
 
+// FailureKind tells how a response status code of failure map is handled, zero value means it is not a failure
+type FailureKind int
+
+// kinds of failure, they are set in failure config by a suffix of status code, such as http_501:permanent
+const (
+	// RetryableFailure is retried if retry is enabled, it is the default
+	RetryableFailure FailureKind = iota + 1
+	// PermanentFailure is never retried, suffix is permanent
+	PermanentFailure
+	// HTTP1FallbackFailure is retried over HTTP/1.1 if it is got over HTTP/2, otherwise it is not retried,
+	// it is for 505 HTTP Version Not Supported, suffix is http1
+	HTTP1FallbackFailure
+)
+
+var failureSuffixes = map[string]FailureKind{
+	"permanent": PermanentFailure,
+	"http1":     HTTP1FallbackFailure,
+}
+
 //Options is configs for client creation
 type Options struct {
 	Service   string
@@ -34,18 +53,49 @@ type Options struct {
 	Endpoint  string
 	PoolTTL   time.Duration
 	TLSConfig *tls.Config
-	Failure   map[string]bool
+	Failure   map[string]FailureKind
+	// DisableRedirect makes http client return 3xx response instead of following it,
+	// so that 3xx code can be classified as failure in Failure map, for example "http_302"
+	DisableRedirect bool
+	// DisableTCPNoDelay enables Nagle's algorithm on connections, TCP_NODELAY is set by default
+	DisableTCPNoDelay bool
+	// TCPKeepAlive is the keep alive period of connections,
+	// 0 means protocol client default, negative value disables keep alive
+	TCPKeepAlive time.Duration
+	// EnableHTTP2 makes client use HTTP/2 if server supports it in TLS handshake
+	EnableHTTP2 bool
+	// Network pins address family of connections, "tcp4" or "tcp6", default "tcp" is dual stack
+	Network string
+	// MaxConcurrentDials limits dials in progress to each host, so that a burst of requests to a new host
+	// does not cause connection storm. excess requests wait for a connection until their deadline, 0 means no limit
+	MaxConcurrentDials int
+	// AutoWarmNewEndpoints is the number of connections dialed in background to each endpoint of Service
+	// which discovery newly finds, so that the first requests to it do not wait for dials. 0 means no warming
+	AutoWarmNewEndpoints int
+	// Headers are set on every request which does not have them
+	Headers map[string]string
+	// MaxResponseHeaderBytes limits size of response headers, 0 means protocol client default
+	MaxResponseHeaderBytes int64
+	// HostMaxResponseHeaderBytes overrides MaxResponseHeaderBytes for hosts, key is host:port or host,
+	// so that only hosts which send large headers, such as big cookies, use more memory
+	HostMaxResponseHeaderBytes map[string]int64
 }
 
-// GetFailureMap return failure map
-func GetFailureMap(p string) map[string]bool {
+// GetFailureMap return failure map, a failure without known kind suffix is retryable
+func GetFailureMap(p string) map[string]FailureKind {
 	failureList := strings.Split(config.GlobalDefinition.ServiceComb.Transport.Failure[p], ",")
-	failureMap := make(map[string]bool)
+	failureMap := make(map[string]FailureKind)
 	for _, v := range failureList {
 		if v == "" {
 			continue
 		}
-		failureMap[v] = true
+		kind := RetryableFailure
+		if i := strings.LastIndex(v, ":"); i >= 0 {
+			if k, ok := failureSuffixes[v[i+1:]]; ok {
+				v, kind = v[:i], k
+			}
+		}
+		failureMap[v] = kind
 	}
 	return failureMap
 }
@@ -60,6 +110,21 @@ func GetMaxIdleCon(p string) int {
 	return n
 }
 
+//GetTCPKeepAlive get keep alive period of connections you defined,
+//0 means default of protocol client
+func GetTCPKeepAlive(p string) time.Duration {
+	v, ok := config.GetTransportConf().TCPKeepAlive[p]
+	if !ok {
+		return 0
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		openlog.Warn(fmt.Sprintf("invalid tcp keep alive [%s] of [%s], use default", v, p))
+		return 0
+	}
+	return d
+}
+
 // CreateClient is for to create client based on protocol and the service name
 func CreateClient(protocol, service, endpoint string, sslEnable bool) (ProtocolClient, error) {
 	f, err := GetClientNewFunc(protocol)
@@ -93,6 +158,18 @@ func CreateClient(protocol, service, endpoint string, sslEnable bool) (ProtocolC
 		Failure:   GetFailureMap(protocol),
 		Timeout:   config.GetTimeoutDurationFromArchaius(command, common.Consumer),
 		Endpoint:  endpoint,
+
+		DisableRedirect:   config.GetTransportConf().DisableRedirect[protocol],
+		DisableTCPNoDelay: config.GetTransportConf().DisableTCPNoDelay[protocol],
+		TCPKeepAlive:      GetTCPKeepAlive(protocol),
+		EnableHTTP2:       config.GetTransportConf().EnableHTTP2[protocol],
+		Network:           config.GetTransportConf().Network[protocol],
+
+		MaxConcurrentDials:   config.GetTransportConf().MaxConcurrentDials[protocol],
+		AutoWarmNewEndpoints: config.GetTransportConf().AutoWarmNewEndpoints[protocol],
+
+		MaxResponseHeaderBytes:     config.GetTransportConf().MaxResponseHeaderBytes[protocol],
+		HostMaxResponseHeaderBytes: config.GetTransportConf().HostMaxResponseHeaderBytes[protocol],
 	})
 }
 func generateKey(protocol, service, endpoint string) string {
@@ -145,11 +222,14 @@ func SetTimeoutToClientCache(spec model.IsolationWrapper) {
 	defer sl.Unlock()
 	for _, client := range clients {
 		if client != nil {
-			if v, ok := spec.Consumer.AnyService[client.GetOptions().Service]; ok {
-				client.ReloadConfigs(Options{Timeout: time.Duration(v.TimeoutInMilliseconds) * time.Millisecond})
+			// other options are reloaded as they are, reloading timeout does not reset them
+			opts := client.GetOptions()
+			if v, ok := spec.Consumer.AnyService[opts.Service]; ok {
+				opts.Timeout = time.Duration(v.TimeoutInMilliseconds) * time.Millisecond
 			} else {
-				client.ReloadConfigs(Options{Timeout: time.Duration(spec.Consumer.TimeoutInMilliseconds) * time.Millisecond})
+				opts.Timeout = time.Duration(spec.Consumer.TimeoutInMilliseconds) * time.Millisecond
 			}
+			client.ReloadConfigs(opts)
 		}
 	}
 }
@@ -170,5 +250,27 @@ func EqualOpts(oldOpts, newOpts Options) Options {
 		oldOpts.TLSConfig = newOpts.TLSConfig
 	}
 	oldOpts.Failure = newOpts.Failure
+	oldOpts.DisableRedirect = newOpts.DisableRedirect
+	oldOpts.DisableTCPNoDelay = newOpts.DisableTCPNoDelay
+	if newOpts.TCPKeepAlive != 0 {
+		oldOpts.TCPKeepAlive = newOpts.TCPKeepAlive
+	}
+	oldOpts.EnableHTTP2 = newOpts.EnableHTTP2
+	if newOpts.Network != "" {
+		oldOpts.Network = newOpts.Network
+	}
+	if newOpts.MaxConcurrentDials != 0 {
+		oldOpts.MaxConcurrentDials = newOpts.MaxConcurrentDials
+	}
+	oldOpts.AutoWarmNewEndpoints = newOpts.AutoWarmNewEndpoints
+	if newOpts.Headers != nil {
+		oldOpts.Headers = newOpts.Headers
+	}
+	if newOpts.MaxResponseHeaderBytes != 0 {
+		oldOpts.MaxResponseHeaderBytes = newOpts.MaxResponseHeaderBytes
+	}
+	if newOpts.HostMaxResponseHeaderBytes != nil {
+		oldOpts.HostMaxResponseHeaderBytes = newOpts.HostMaxResponseHeaderBytes
+	}
 	return oldOpts
 }
@@ -32,15 +32,18 @@ func init() {
 func TestGetFailureMap(t *testing.T) {
 	config.GlobalDefinition = &model.GlobalCfg{}
 	config.GlobalDefinition.ServiceComb.Transport.Failure = map[string]string{
-		"rest": "http_500,http:502",
+		"rest": "http_500,http:502,http_501:permanent,http_505:http1",
 	}
 
 	t.Run("get failed map about protocol ",
 		func(t *testing.T) {
 			m := client.GetFailureMap("rest")
 			assert.NotEmpty(t, m)
-			assert.True(t, m["http_500"])
-			assert.False(t, m["http_540"])
+			assert.Equal(t, client.RetryableFailure, m["http_500"])
+			assert.Equal(t, client.PermanentFailure, m["http_501"])
+			assert.Equal(t, client.HTTP1FallbackFailure, m["http_505"])
+			assert.Equal(t, client.RetryableFailure, m["http:502"], "unknown suffix is part of status")
+			assert.Zero(t, m["http_540"])
 			m = client.GetFailureMap("rpc")
 			assert.Empty(t, m)
 
@@ -217,3 +220,81 @@ func TestSetTimeoutToClientCache(t *testing.T) {
 		})
 
 }
+
+func TestEqualOpts(t *testing.T) {
+	old := client.Options{
+		Service:           "rest_server",
+		PoolSize:          10,
+		DisableRedirect:   true,
+		DisableTCPNoDelay: true,
+		TCPKeepAlive:      15 * time.Second,
+		EnableHTTP2:       true,
+		Network:           "tcp4",
+
+		MaxConcurrentDials:   4,
+		AutoWarmNewEndpoints: 2,
+		Headers:              map[string]string{"X-Key": "v"},
+
+		MaxResponseHeaderBytes:     4096,
+		HostMaxResponseHeaderBytes: map[string]int64{"chatty": 65536},
+	}
+	opts := client.EqualOpts(old, client.Options{Timeout: time.Second})
+	assert.Equal(t, time.Second, opts.Timeout)
+	assert.Equal(t, 10, opts.PoolSize)
+	assert.Equal(t, 15*time.Second, opts.TCPKeepAlive)
+	assert.Equal(t, "tcp4", opts.Network)
+	assert.Equal(t, 4, opts.MaxConcurrentDials)
+	assert.Equal(t, "v", opts.Headers["X-Key"])
+	assert.Equal(t, int64(4096), opts.MaxResponseHeaderBytes)
+	assert.Equal(t, int64(65536), opts.HostMaxResponseHeaderBytes["chatty"])
+}
+
+func TestEqualOpts_Unset(t *testing.T) {
+	opts := client.EqualOpts(client.Options{}, client.Options{DisableRedirect: true})
+	assert.True(t, opts.DisableRedirect)
+	opts = client.EqualOpts(opts, client.Options{})
+	assert.False(t, opts.DisableRedirect, "reload can follow redirects again")
+
+	opts = client.EqualOpts(opts, client.Options{DisableTCPNoDelay: true})
+	assert.True(t, opts.DisableTCPNoDelay)
+	opts = client.EqualOpts(opts, client.Options{})
+	assert.False(t, opts.DisableTCPNoDelay, "reload can disable Nagle's algorithm again")
+
+	opts = client.EqualOpts(opts, client.Options{EnableHTTP2: true})
+	assert.True(t, opts.EnableHTTP2)
+	opts = client.EqualOpts(opts, client.Options{})
+	assert.False(t, opts.EnableHTTP2, "reload can turn HTTP/2 off")
+
+	opts = client.EqualOpts(opts, client.Options{AutoWarmNewEndpoints: 2})
+	assert.Equal(t, 2, opts.AutoWarmNewEndpoints)
+	opts = client.EqualOpts(opts, client.Options{})
+	assert.Equal(t, 0, opts.AutoWarmNewEndpoints, "reload can disable warming")
+}
+
+func TestSetTimeoutToClientCache_KeepOptions(t *testing.T) {
+	config.Init()
+	config.GlobalDefinition = &model.GlobalCfg{}
+	config.GlobalDefinition.ServiceComb.Protocols = map[string]model.Protocol{"rest": {}}
+	config.GlobalDefinition.ServiceComb.Transport.DisableRedirect = map[string]bool{"rest": true}
+	i := &invocation.Invocation{Protocol: "rest", MicroServiceName: "keep_server"}
+	c, err := client.GetClient(i)
+	assert.NoError(t, err)
+	assert.True(t, c.GetOptions().DisableRedirect)
+
+	client.SetTimeoutToClientCache(model.IsolationWrapper{Consumer: model.IsolationSpec{TimeoutInMilliseconds: 30}})
+	c, err = client.GetClient(i)
+	assert.NoError(t, err)
+	assert.Equal(t, 30*time.Millisecond, c.GetOptions().Timeout)
+	assert.True(t, c.GetOptions().DisableRedirect, "reloading timeout should not reset other options")
+}
+
+func TestGetTCPKeepAlive(t *testing.T) {
+	config.GlobalDefinition = &model.GlobalCfg{}
+	config.GlobalDefinition.ServiceComb.Transport.TCPKeepAlive = map[string]string{
+		"rest":    "15s",
+		"invalid": "15",
+	}
+	assert.Equal(t, 15*time.Second, client.GetTCPKeepAlive("rest"))
+	assert.Equal(t, time.Duration(0), client.GetTCPKeepAlive("invalid"))
+	assert.Equal(t, time.Duration(0), client.GetTCPKeepAlive("highway"))
+}
@@ -78,6 +78,65 @@ const (
 // SessionNameSpaceKey metadata session namespace key
 const SessionNameSpaceKey = "_Session_Namespace"
 
+// metadata keys of invocation options which are used in load balancing
+const (
+	// DiscoveryTimeoutKey saves time.Duration, it limits how long instance discovery can take
+	DiscoveryTimeoutKey = "_Discovery_Timeout"
+	// StaleInstancesMaxAgeKey saves time.Duration, it allows last known instances which are not older than it
+	// to be used when discovery fails or times out
+	StaleInstancesMaxAgeKey = "_Stale_Instances_Max_Age"
+)
+
+// metadata keys of invocation options which are used in protocol client
+const (
+	// DumpOnErrorKey saves bool, client keeps dump of request and response if call fails
+	DumpOnErrorKey = "_Dump_On_Error"
+	// ErrorDumpKey saves []byte, it is the dump of the last failed call
+	ErrorDumpKey = "_Error_Dump"
+	// ErrorBodyKey saves bool, client reads response body into error if response status is failure
+	ErrorBodyKey = "_Error_Body"
+	// CaptureKey saves bool, client keeps raw request and response of call
+	CaptureKey = "_Capture"
+	// CapturedRequestKey saves []byte, it is the raw request of last attempt
+	CapturedRequestKey = "_Captured_Request"
+	// CapturedResponseKey saves []byte, it is the raw response of last attempt
+	CapturedResponseKey = "_Captured_Response"
+	// IsolationKey saves string, client sends request with a connection pool of the key
+	IsolationKey = "_Isolation_Key"
+	// FaultInjectionKey saves model.Fault, fault of the rule is injected to call before request is sent
+	FaultInjectionKey = "_Fault_Injection"
+	// AcceptEncodingsKey saves []string, client asks for these content encodings and decodes response body
+	AcceptEncodingsKey = "_Accept_Encodings"
+	// EndpointSelectorKey saves loadbalancer.Selector, it changes instances of call after filters, before strategy picks
+	EndpointSelectorKey = "_Endpoint_Selector"
+	// ErrorPeekKey saves rest.ErrorPeek, client classifies response by first bytes of body
+	ErrorPeekKey = "_Error_Peek"
+	// AggregateErrorsKey saves bool, a call failed after retries returns failures of all attempts
+	AggregateErrorsKey = "_Aggregate_Errors"
+	// ConnectTimeoutKey saves time.Duration, a new connection of call must be established in it
+	ConnectTimeoutKey = "_Connect_Timeout"
+	// RedirectHeaderPolicyKey saves rest.RedirectHeaderPolicy, it decides headers sent when following redirects
+	RedirectHeaderPolicyKey = "_Redirect_Header_Policy"
+	// EarlyDataRejectedKey saves bool, an attempt got 425 Too Early, the following ones are not sent in early data
+	EarlyDataRejectedKey = "_Early_Data_Rejected"
+	// HTTP1FallbackKey saves bool, an attempt got a failure of client.HTTP1FallbackFailure, the following ones use HTTP/1.1
+	HTTP1FallbackKey = "_HTTP1_Fallback"
+	// MaxDecompressedSizeKey saves int64, reading decompressed body of response fails once it exceeds the size
+	MaxDecompressedSizeKey = "_Max_Decompressed_Size"
+	// ExplicitEndpointsKey saves []string, strategy picks from these addresses instead of discovered instances
+	ExplicitEndpointsKey = "_Explicit_Endpoints"
+	// SkipLatencyRecordingKey saves bool, latency of call is not recorded for load balancing
+	SkipLatencyRecordingKey = "_Skip_Latency_Recording"
+	// PickSampleSizeKey saves int, strategies which scan all instances pick from a random sample of the size
+	PickSampleSizeKey = "_Pick_Sample_Size"
+	// LatencyDecayKey saves float64, latency of call is averaged by exponentially weighted moving average with the decay
+	LatencyDecayKey = "_Latency_Decay"
+	// LatencyMinSamplesKey saves int, latency strategy acts on latencies of an endpoint once it has so many of them
+	LatencyMinSamplesKey = "_Latency_Min_Samples"
+	// ValidateIdleConnsKey saves time.Duration, a request lost on a connection idle beyond it is sent again on a new connection
+	ValidateIdleConnsKey = "_Validate_Idle_Conns"
+)
+
 // SessionNameSpaceDefaultValue default session namespace value
 const SessionNameSpaceDefaultValue = "default"
 
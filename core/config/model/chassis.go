@@ -42,6 +42,24 @@ type Transport struct {
 	MaxBodyBytes   map[string]int64  `yaml:"maxBodyBytes"`
 	MaxHeaderBytes map[string]int    `yaml:"maxHeaderBytes"`
 	Timeout        map[string]string `yaml:"timeout"`
+	// DisableRedirect makes client return 3xx response instead of following it
+	DisableRedirect map[string]bool `yaml:"disableRedirect"`
+	// DisableTCPNoDelay enables Nagle's algorithm on connections of client
+	DisableTCPNoDelay map[string]bool `yaml:"disableTCPNoDelay"`
+	// TCPKeepAlive is keep alive period of client connections, it is golang duration string
+	TCPKeepAlive map[string]string `yaml:"tcpKeepAlive"`
+	// EnableHTTP2 makes client use HTTP/2 if server supports it in TLS handshake
+	EnableHTTP2 map[string]bool `yaml:"enableHTTP2"`
+	// Network pins address family of client connections, "tcp4" or "tcp6"
+	Network map[string]string `yaml:"network"`
+	// MaxConcurrentDials limits dials in progress to each host of client
+	MaxConcurrentDials map[string]int `yaml:"maxConcurrentDials"`
+	// AutoWarmNewEndpoints is the number of connections dialed in advance to each new endpoint of client
+	AutoWarmNewEndpoints map[string]int `yaml:"autoWarmNewEndpoints"`
+	// MaxResponseHeaderBytes limits size of response headers of client
+	MaxResponseHeaderBytes map[string]int64 `yaml:"maxResponseHeaderBytes"`
+	// HostMaxResponseHeaderBytes overrides MaxResponseHeaderBytes for hosts, value key is host:port or host
+	HostMaxResponseHeaderBytes map[string]map[string]int64 `yaml:"hostMaxResponseHeaderBytes"`
 }
 
 // MetricsStruct metrics struct
@@ -16,9 +16,13 @@ type LoadBalancing struct {
 	RetryEnabled          bool                         `yaml:"retryEnabled"`
 	RetryOnNext           int                          `yaml:"retryOnNext"`
 	RetryOnSame           int                          `yaml:"retryOnSame"`
+	RetryIdempotentOnly   bool                         `yaml:"retryIdempotentOnly"`
 	Filters               string                       `yaml:"serverListFilters"`
 	Backoff               BackoffStrategy              `yaml:"backoff"`
 	SessionStickinessRule SessionStickinessRule        `yaml:"SessionStickinessRule"`
+	PickSampleSize        int                          `yaml:"pickSampleSize"`
+	LatencyDecay          float64                      `yaml:"latencyDecay"`
+	LatencyMinSamples     int                          `yaml:"latencyMinSamples"`
 	AnyService            map[string]LoadBalancingSpec `yaml:",inline"`
 }
 
@@ -29,7 +33,11 @@ type LoadBalancingSpec struct {
 	RetryEnabled          bool                  `yaml:"retryEnabled"`
 	RetryOnNext           int                   `yaml:"retryOnNext"`
 	RetryOnSame           int                   `yaml:"retryOnSame"`
+	RetryIdempotentOnly   bool                  `yaml:"retryIdempotentOnly"`
 	Backoff               BackoffStrategy       `yaml:"backoff"`
+	PickSampleSize        int                   `yaml:"pickSampleSize"`
+	LatencyDecay          float64               `yaml:"latencyDecay"`
+	LatencyMinSamples     int                   `yaml:"latencyMinSamples"`
 }
 
 // SessionStickinessRule loadbalancing structure
@@ -0,0 +1,71 @@
+package core
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sync"
+)
+
+// CallResult is the outcome of a call to one endpoint made by CallAll
+type CallResult struct {
+	Endpoint string
+	Response *http.Response
+	Err      error
+}
+
+// CallAll sends request to every endpoint concurrently and returns results in order of endpoints,
+// it is for scatter gather reads, load balancing does not apply, every endpoint is called.
+// at most concurrency calls are in flight, 0 means no limit. a call which is not started before ctx is done
+// fails with ctx.Err(). results are partial if some calls fail, caller must close body of every response
+func (ri *RestInvoker) CallAll(ctx context.Context, req *http.Request, endpoints []string, concurrency int,
+	options ...InvocationOption) []CallResult {
+	results := make([]CallResult, len(endpoints))
+	var body []byte
+	if req.Body != nil && req.Body != http.NoBody {
+		b, err := ioutil.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			for n, ep := range endpoints {
+				results[n] = CallResult{Endpoint: ep, Err: err}
+			}
+			return results
+		}
+		body = b
+	}
+	if concurrency <= 0 || concurrency > len(endpoints) {
+		concurrency = len(endpoints)
+	}
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for n, ep := range endpoints {
+		results[n].Endpoint = ep
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			results[n].Err = ctx.Err()
+			continue
+		}
+		r := req.Clone(ctx)
+		if body != nil {
+			r.Body = ioutil.NopCloser(bytes.NewReader(body))
+			r.GetBody = func() (io.ReadCloser, error) {
+				return ioutil.NopCloser(bytes.NewReader(body)), nil
+			}
+		}
+		opts := make([]InvocationOption, 0, len(options)+1)
+		opts = append(append(opts, options...), WithEndpoint(ep))
+		wg.Add(1)
+		go func(n int) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			results[n].Response, results[n].Err = ri.ContextDo(ctx, r, opts...)
+		}(n)
+	}
+	wg.Wait()
+	return results
+}
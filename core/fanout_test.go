@@ -0,0 +1,72 @@
+package core_test
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-chassis/go-chassis/v2/client/rest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRestInvoker_CallAll(t *testing.T) {
+	var inFlight, maxInFlight int32
+	newServer := func(name string) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			n := atomic.AddInt32(&inFlight, 1)
+			defer atomic.AddInt32(&inFlight, -1)
+			for {
+				max := atomic.LoadInt32(&maxInFlight)
+				if n <= max || atomic.CompareAndSwapInt32(&maxInFlight, max, n) {
+					break
+				}
+			}
+			time.Sleep(50 * time.Millisecond)
+			b, _ := ioutil.ReadAll(r.Body)
+			w.Write([]byte(name + " " + string(b)))
+		}))
+	}
+	a := newServer("a")
+	defer a.Close()
+	b := newServer("b")
+	defer b.Close()
+	down := newServer("down")
+	down.Close()
+	endpoints := []string{
+		strings.TrimPrefix(a.URL, "http://"),
+		strings.TrimPrefix(down.URL, "http://"),
+		strings.TrimPrefix(b.URL, "http://"),
+	}
+	invoker := newTransportInvoker(t)
+
+	req, _ := rest.NewRequest(http.MethodPost, "http://ShardServer/query", []byte("q=1"))
+	results := invoker.CallAll(context.TODO(), req, endpoints, 1)
+	if assert.Len(t, results, 3) {
+		for n, want := range []string{"a q=1", "", "b q=1"} {
+			r := results[n]
+			assert.Equal(t, endpoints[n], r.Endpoint)
+			if want == "" {
+				assert.Error(t, r.Err, "unreachable endpoint fails")
+				continue
+			}
+			if assert.NoError(t, r.Err) {
+				body, _ := ioutil.ReadAll(r.Response.Body)
+				r.Response.Body.Close()
+				assert.Equal(t, want, string(body), "every endpoint receives the body")
+			}
+		}
+	}
+	assert.Equal(t, int32(1), atomic.LoadInt32(&maxInFlight), "concurrency is capped")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	req, _ = rest.NewRequest(http.MethodGet, "http://ShardServer/query", nil)
+	for _, r := range invoker.CallAll(ctx, req, endpoints, 1) {
+		assert.Error(t, r.Err, "call is not made after context is done")
+	}
+}
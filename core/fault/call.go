@@ -0,0 +1,64 @@
+package fault
+
+import (
+	"fmt"
+	"math/rand"
+	"net/http"
+	"time"
+
+	"github.com/go-chassis/go-chassis/v2/core/config/model"
+	"github.com/go-chassis/go-chassis/v2/core/invocation"
+	"github.com/go-chassis/openlog"
+)
+
+// InjectCall applies fault rule of a call before request is sent,
+// delay is ended early if context of invocation is done, abort returns a Fault error
+// and sets status of http reply. every injected fault is logged and recorded in decision trace
+func InjectCall(rule model.Fault, inv *invocation.Invocation) error {
+	if rule.Delay != (model.Delay{}) {
+		if err := ValidateFaultDelay(&rule); err != nil {
+			return err
+		}
+		if hit(rule.Delay.Percent) {
+			openlog.Warn(fmt.Sprintf("inject delay %s to call of [%s]", rule.Delay.FixedDelay, inv.MicroServiceName))
+			inv.Trace(invocation.EventFaultInjected, inv.Endpoint, "delay ", rule.Delay.FixedDelay)
+			if err := sleep(inv, rule.Delay.FixedDelay); err != nil {
+				return err
+			}
+		}
+	}
+	if rule.Abort != (model.Abort{}) {
+		if err := ValidateFaultAbort(&rule); err != nil {
+			return err
+		}
+		if hit(rule.Abort.Percent) {
+			openlog.Warn(fmt.Sprintf("inject abort %d to call of [%s]", rule.Abort.HTTPStatus, inv.MicroServiceName))
+			inv.Trace(invocation.EventFaultInjected, inv.Endpoint, "abort status ", rule.Abort.HTTPStatus)
+			if resp, ok := inv.Reply.(*http.Response); ok && resp != nil {
+				resp.StatusCode = rule.Abort.HTTPStatus
+				resp.Status = fmt.Sprintf("%d %s", rule.Abort.HTTPStatus, http.StatusText(rule.Abort.HTTPStatus))
+			}
+			return Fault{Message: fmt.Sprintf("injected fault, status %d", rule.Abort.HTTPStatus)}
+		}
+	}
+	return nil
+}
+
+func hit(percent int) bool {
+	return rand.Intn(MaxPercentage)+1 <= percent
+}
+
+func sleep(inv *invocation.Invocation, d time.Duration) error {
+	if inv.Ctx == nil {
+		time.Sleep(d)
+		return nil
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-inv.Ctx.Done():
+		return inv.Ctx.Err()
+	}
+}
@@ -0,0 +1,42 @@
+package fault_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/go-chassis/go-chassis/v2/core/config/model"
+	"github.com/go-chassis/go-chassis/v2/core/fault"
+	"github.com/go-chassis/go-chassis/v2/core/invocation"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInjectCall(t *testing.T) {
+	t.Run("abort sets status of reply", func(t *testing.T) {
+		resp := &http.Response{}
+		inv := &invocation.Invocation{MicroServiceName: "Server", Reply: resp}
+		err := fault.InjectCall(model.Fault{Abort: model.Abort{Percent: 100, HTTPStatus: http.StatusServiceUnavailable}}, inv)
+		assert.Equal(t, fault.Fault{Message: "injected fault, status 503"}, err)
+		assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+	})
+	t.Run("0 percent never injects", func(t *testing.T) {
+		inv := &invocation.Invocation{MicroServiceName: "Server"}
+		for n := 0; n < 100; n++ {
+			assert.NoError(t, fault.InjectCall(model.Fault{Abort: model.Abort{Percent: 0, HTTPStatus: http.StatusServiceUnavailable}}, inv))
+		}
+	})
+	t.Run("delay ends with context", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+		inv := &invocation.Invocation{MicroServiceName: "Server", Ctx: ctx}
+		start := time.Now()
+		err := fault.InjectCall(model.Fault{Delay: model.Delay{Percent: 100, FixedDelay: time.Minute}}, inv)
+		assert.Equal(t, context.DeadlineExceeded, err)
+		assert.True(t, time.Since(start) < time.Second)
+	})
+	t.Run("invalid rule", func(t *testing.T) {
+		err := fault.InjectCall(model.Fault{Abort: model.Abort{Percent: 100, HTTPStatus: 1}}, &invocation.Invocation{})
+		assert.Error(t, err)
+	})
+}
@@ -8,20 +8,68 @@ import (
 	"github.com/go-chassis/go-chassis/v2/resilience/retry"
 	"io/ioutil"
 	"net/http"
+	"strings"
 
 	"github.com/cenkalti/backoff"
 	"github.com/go-chassis/go-chassis/v2/control"
+	"github.com/go-chassis/go-chassis/v2/core/common"
 	"github.com/go-chassis/go-chassis/v2/core/invocation"
 	"github.com/go-chassis/go-chassis/v2/core/loadbalancer"
 	"github.com/go-chassis/go-chassis/v2/core/status"
 	"github.com/go-chassis/go-chassis/v2/pkg/util"
+	"github.com/go-chassis/go-chassis/v2/pkg/util/httputil"
 	"github.com/go-chassis/openlog"
 )
 
+// permanentError is implemented by failures which must not be retried, such as rest.PermanentError
+type permanentError interface {
+	Permanent() bool
+}
+
+// dialError is implemented by failures of establishing connection in time, such as rest.TimeoutError
+type dialError interface {
+	DialFailed() bool
+}
+
+// AttemptError is the failure of an attempt to an endpoint
+type AttemptError struct {
+	Endpoint string
+	Err      error
+}
+
+// AggregateError is returned by a call which failed after retries if invocation asks for it,
+// see common.AggregateErrorsKey. it carries failure of every attempt in order
+type AggregateError struct {
+	Attempts []AttemptError
+}
+
+// Error returns failures of all attempts, such as "a:8080: 503, b:8080: timeout"
+func (e *AggregateError) Error() string {
+	msgs := make([]string, 0, len(e.Attempts))
+	for _, a := range e.Attempts {
+		msgs = append(msgs, a.Endpoint+": "+a.Err.Error())
+	}
+	return strings.Join(msgs, ", ")
+}
+
+// Unwrap returns failure of the last attempt, it is the error returned without aggregation
+func (e *AggregateError) Unwrap() error {
+	if len(e.Attempts) == 0 {
+		return nil
+	}
+	return e.Attempts[len(e.Attempts)-1].Err
+}
+
 // LBHandler loadbalancer handler struct
 type LBHandler struct{}
 
 func (lb *LBHandler) getEndpoint(i *invocation.Invocation, lbConfig control.LoadBalancingConfig) (*registry.Endpoint, error) {
+	return lb.selectEndpoint(i, lbConfig, false)
+}
+
+// selectEndpoint runs strategy and filters to choose an endpoint,
+// if peek is true, strategy state will not be changed if strategy supports it
+func (lb *LBHandler) selectEndpoint(i *invocation.Invocation, lbConfig control.LoadBalancingConfig, peek bool) (*registry.Endpoint, error) {
 	var strategyFun func() loadbalancer.Strategy
 	var err error
 	if i.Strategy == "" {
@@ -41,13 +89,27 @@ func (lb *LBHandler) getEndpoint(i *invocation.Invocation, lbConfig control.Load
 	if len(i.Filters) == 0 {
 		i.Filters = lbConfig.Filters
 	}
+	if lbConfig.PickSampleSize > 0 {
+		i.SetMetadata(common.PickSampleSizeKey, lbConfig.PickSampleSize)
+	}
+	if lbConfig.LatencyDecay > 0 {
+		i.SetMetadata(common.LatencyDecayKey, lbConfig.LatencyDecay)
+	}
+	if lbConfig.LatencyMinSamples > 0 {
+		i.SetMetadata(common.LatencyMinSamplesKey, lbConfig.LatencyMinSamples)
+	}
 
 	s, err := loadbalancer.BuildStrategy(i, strategyFun())
 	if err != nil {
 		return nil, err
 	}
 
-	ins, err := s.Pick()
+	var ins *registry.MicroServiceInstance
+	if p, ok := s.(loadbalancer.Peeker); ok && peek {
+		ins, err = p.Peek()
+	} else {
+		ins, err = s.Pick()
+	}
 	if err != nil {
 		lbErr := loadbalancer.LBError{Message: err.Error()}
 		return nil, lbErr
@@ -69,9 +131,26 @@ func (lb *LBHandler) getEndpoint(i *invocation.Invocation, lbConfig control.Load
 		openlog.Error(lbErr.Error())
 		return nil, lbErr
 	}
+	if !peek {
+		i.Trace(invocation.EventPick, ep.Address, "strategy ", i.Strategy)
+	}
 	return ep, nil
 }
 
+// ResolveEndpoint runs route rules and load balancing for an invocation and returns the endpoint it would be sent to,
+// it does not call next handler, so that no request is sent.
+// strategies which implement loadbalancer.Peeker will not change their state, weighted routes still do
+func ResolveEndpoint(i *invocation.Invocation) (*registry.Endpoint, error) {
+	if i.RouteTags.KV == nil {
+		if err := route(i); err != nil {
+			return nil, err
+		}
+	}
+	lb := &LBHandler{}
+	lbConfig := control.DefaultPanel.GetLoadBalancing(*i)
+	return lb.selectEndpoint(i, lbConfig, true)
+}
+
 // Handle to handle the load balancing
 func (lb *LBHandler) Handle(chain *Chain, i *invocation.Invocation, cb invocation.ResponseCallBack) {
 	lbConfig := control.DefaultPanel.GetLoadBalancing(*i)
@@ -91,7 +170,18 @@ func (lb *LBHandler) handleWithNoRetry(chain *Chain, i *invocation.Invocation, l
 
 	i.Endpoint = ep.Address
 	i.SSLEnable = ep.IsSSLEnable()
-	chain.Next(i, cb)
+	if !i.Traced() {
+		chain.Next(i, cb)
+		return
+	}
+	chain.Next(i, func(r *invocation.Response) {
+		if r != nil && r.Err != nil {
+			i.Trace(invocation.EventAttemptFailed, ep.Address, r.Err)
+		} else {
+			i.Trace(invocation.EventSucceeded, ep.Address)
+		}
+		cb(r)
+	})
 }
 
 func (lb *LBHandler) handleWithRetry(chain *Chain, i *invocation.Invocation, lbConfig control.LoadBalancingConfig, cb invocation.ResponseCallBack) {
@@ -109,7 +199,15 @@ func (lb *LBHandler) handleWithRetry(chain *Chain, i *invocation.Invocation, lbC
 	}
 	// get retry func
 	lbBackoff := retry.GetBackOff(lbConfig.BackOffKind, lbConfig.BackOffMin, lbConfig.BackOffMax)
+	if i.Ctx != nil {
+		// stop waiting for next retry once context is done
+		lbBackoff = backoff.WithContext(lbBackoff, i.Ctx)
+	}
 	callTimes := 0
+	var failures *AggregateError
+	if aggregate, _ := i.Metadata[common.AggregateErrorsKey].(bool); aggregate {
+		failures = &AggregateError{}
+	}
 
 	ep, err := lb.getEndpoint(i, lbConfig)
 	if err != nil {
@@ -118,6 +216,12 @@ func (lb *LBHandler) handleWithRetry(chain *Chain, i *invocation.Invocation, lbC
 		return
 	}
 	operation := func() error {
+		if i.Ctx != nil && i.Ctx.Err() != nil {
+			if invResp == nil {
+				invResp = &invocation.Response{Err: i.Ctx.Err()}
+			}
+			return backoff.Permanent(invResp.Err)
+		}
 		i.Endpoint = ep.Address
 		i.SSLEnable = ep.IsSSLEnable()
 		callTimes++
@@ -136,18 +240,54 @@ func (lb *LBHandler) handleWithRetry(chain *Chain, i *invocation.Invocation, lbC
 			}
 		})
 
-		if callTimes >= retryOnSame+1 {
-			if retryOnNext <= 0 {
-				return backoff.Permanent(errors.New("retry times expires"))
+		budget := retry.GetBudget()
+		if respErr == nil {
+			if budget != nil {
+				budget.Deposit()
 			}
+			i.Trace(invocation.EventSucceeded, ep.Address)
+			return nil
+		}
+		i.Trace(invocation.EventAttemptFailed, ep.Address, respErr)
+		if failures != nil {
+			failures.Attempts = append(failures.Attempts, AttemptError{Endpoint: ep.Address, Err: respErr})
+		}
+		var pe permanentError
+		if errors.As(respErr, &pe) && pe.Permanent() {
+			i.Trace(invocation.EventRetryStopped, "", "failure is permanent")
+			return backoff.Permanent(respErr)
+		}
+		if req, ok := i.Args.(*http.Request); ok && lbConfig.RetryIdempotentOnly && !httputil.IsIdempotent(req) {
+			// request may have been processed, only retry it if it is marked idempotent
+			i.Trace(invocation.EventRetryStopped, "", "request is not idempotent")
+			return backoff.Permanent(respErr)
+		}
+		var de dialError
+		if failed, _ := loadbalancer.ResolveFailure(respErr); failed || (errors.As(respErr, &de) && de.DialFailed()) {
+			// host can not be resolved or connected now, try next endpoint instead of the same one
+			callTimes = retryOnSame + 1
+		}
+		if callTimes >= retryOnSame+1 && retryOnNext <= 0 {
+			i.Trace(invocation.EventRetryStopped, "", "retry times expires")
+			return backoff.Permanent(errors.New("retry times expires"))
+		}
+		// check budget before next endpoint is picked, so that a retry which is not sent does not move strategy
+		if budget != nil && !budget.Withdraw() {
+			openlog.Warn("retry budget is exhausted, stop retry")
+			i.Trace(invocation.EventRetryStopped, "", "retry budget is exhausted")
+			return backoff.Permanent(respErr)
+		}
+		if callTimes >= retryOnSame+1 {
 			ep, err = lb.getEndpoint(i, lbConfig)
 			if err != nil {
 				// if get endpoint failed, no need to retry
+				i.Trace(invocation.EventRetryStopped, "", err)
 				return backoff.Permanent(err)
 			}
 			callTimes = 0
 			retryOnNext--
 		}
+		i.Trace(invocation.EventRetry, ep.Address)
 		return respErr
 	}
 	if err := backoff.Retry(operation, lbBackoff); err != nil {
@@ -157,6 +297,9 @@ func (lb *LBHandler) handleWithRetry(chain *Chain, i *invocation.Invocation, lbC
 	if invResp == nil {
 		invResp = &invocation.Response{}
 	}
+	if failures != nil && invResp.Err != nil && len(failures.Attempts) > 0 {
+		invResp.Err = failures
+	}
 	cb(invResp)
 }
 
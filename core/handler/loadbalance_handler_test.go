@@ -1,17 +1,26 @@
 package handler_test
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
 	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/go-chassis/go-archaius"
 	"github.com/go-chassis/go-chassis/v2/client/rest"
 	"github.com/go-chassis/go-chassis/v2/control"
-	_ "github.com/go-chassis/go-chassis/v2/control/servicecomb"
+	"github.com/go-chassis/go-chassis/v2/control/servicecomb"
+	"github.com/go-chassis/go-chassis/v2/core/common"
 	"github.com/go-chassis/go-chassis/v2/core/config"
 	chassisModel "github.com/go-chassis/go-chassis/v2/core/config/model"
 	"github.com/go-chassis/go-chassis/v2/core/handler"
@@ -25,7 +34,9 @@ import (
 	_ "github.com/go-chassis/go-chassis/v2/initiator"
 	"github.com/go-chassis/go-chassis/v2/pkg/runtime"
 	"github.com/go-chassis/go-chassis/v2/pkg/util/fileutil"
+	"github.com/go-chassis/go-chassis/v2/pkg/util/httputil"
 	"github.com/go-chassis/go-chassis/v2/pkg/util/tags"
+	"github.com/go-chassis/go-chassis/v2/resilience/retry"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -501,6 +512,276 @@ cse:
 	t.Log(i.Protocol)
 	t.Log(i.Endpoint)
 }
+func TestResolveEndpoint(t *testing.T) {
+	err := control.Init(control.Options{})
+	assert.NoError(t, err)
+	loadbalancer.Enable(loadbalancer.StrategyRoundRobin)
+	testRegistryObj := new(mk.DiscoveryMock)
+	registry.DefaultServiceDiscoveryService = testRegistryObj
+	mss := []*registry.MicroServiceInstance{
+		{InstanceID: "ins1", EndpointsMap: map[string]*registry.Endpoint{"rest": {Address: "127.0.0.1:8001"}}},
+		{InstanceID: "ins2", EndpointsMap: map[string]*registry.Endpoint{"rest": {Address: "127.0.0.1:8002"}}},
+		{InstanceID: "ins3", EndpointsMap: map[string]*registry.Endpoint{"rest": {Address: "127.0.0.1:8003"}}},
+	}
+	testRegistryObj.On("FindMicroServiceInstances",
+		"selfServiceID", "appID", "resolveService", "1.0", "").Return(mss, nil)
+
+	c := handler.Chain{}
+	c.AddHandler(&handler.LBHandler{})
+	c.AddHandler(&handler1{})
+	newInv := func() *invocation.Invocation {
+		return &invocation.Invocation{
+			MicroServiceName: "resolveService",
+			SourceServiceID:  "selfServiceID",
+			Protocol:         "rest",
+			Strategy:         loadbalancer.StrategyRoundRobin,
+			RouteTags:        utiltags.NewDefaultTag("1.0", "appID"),
+		}
+	}
+	for n := 0; n < 3; n++ {
+		ep, err := handler.ResolveEndpoint(newInv())
+		assert.NoError(t, err)
+		again, err := handler.ResolveEndpoint(newInv())
+		assert.NoError(t, err)
+		assert.Equal(t, ep.Address, again.Address, "resolve should not move round robin index")
+
+		i := newInv()
+		c.Next(i, func(r *invocation.Response) {
+			assert.NoError(t, r.Err)
+		})
+		assert.Equal(t, ep.Address, i.Endpoint)
+	}
+}
+
+type slowFailHandler struct {
+	calls int
+}
+
+func (h *slowFailHandler) Name() string {
+	return "slowFail"
+}
+
+func (h *slowFailHandler) Handle(chain *handler.Chain, i *invocation.Invocation, cb invocation.ResponseCallBack) {
+	h.calls++
+	time.Sleep(50 * time.Millisecond)
+	cb(&invocation.Response{Err: fmt.Errorf("attempt %d failed", h.calls)})
+}
+
+func TestLBHandlerWithRetry_ContextDeadline(t *testing.T) {
+	archaius.Init(archaius.WithMemorySource())
+	err := control.Init(control.Options{})
+	assert.NoError(t, err)
+	loadbalancer.Enable(loadbalancer.StrategyRoundRobin)
+	testRegistryObj := new(mk.DiscoveryMock)
+	registry.DefaultServiceDiscoveryService = testRegistryObj
+	mss := []*registry.MicroServiceInstance{
+		{InstanceID: "ins1", EndpointsMap: map[string]*registry.Endpoint{"rest": {Address: "127.0.0.1:8001"}}},
+	}
+	testRegistryObj.On("FindMicroServiceInstances",
+		"selfServiceID", "appID", "deadlineService", "1.0", "").Return(mss, nil)
+	servicecomb.LBConfigCache.Set("deadlineService", control.LoadBalancingConfig{
+		Strategy:     loadbalancer.StrategyRoundRobin,
+		RetryEnabled: true,
+		RetryOnSame:  10,
+		BackOffKind:  "constant",
+		BackOffMin:   100,
+		BackOffMax:   100,
+	}, 0)
+	defer servicecomb.LBConfigCache.Delete("deadlineService")
+
+	h := &slowFailHandler{}
+	c := handler.Chain{}
+	c.AddHandler(&handler.LBHandler{})
+	c.AddHandler(h)
+	ctx, cancel := context.WithTimeout(context.Background(), 400*time.Millisecond)
+	defer cancel()
+	i := &invocation.Invocation{
+		Ctx:              ctx,
+		MicroServiceName: "deadlineService",
+		SourceServiceID:  "selfServiceID",
+		Protocol:         "rest",
+		Strategy:         loadbalancer.StrategyRoundRobin,
+		RouteTags:        utiltags.NewDefaultTag("1.0", "appID"),
+	}
+	start := time.Now()
+	var resp *invocation.Response
+	c.Next(i, func(r *invocation.Response) {
+		resp = r
+	})
+	elapsed := time.Since(start)
+	// 11 attempts with backoff would take more than 1.5s
+	assert.True(t, elapsed < 600*time.Millisecond, elapsed)
+	assert.True(t, h.calls < 11, h.calls)
+	assert.EqualError(t, resp.Err, fmt.Sprintf("attempt %d failed", h.calls), "last error should be returned")
+}
+
+func TestLBHandlerWithRetry_Idempotent(t *testing.T) {
+	archaius.Init(archaius.WithMemorySource())
+	err := control.Init(control.Options{})
+	assert.NoError(t, err)
+	loadbalancer.Enable(loadbalancer.StrategyRoundRobin)
+	testRegistryObj := new(mk.DiscoveryMock)
+	registry.DefaultServiceDiscoveryService = testRegistryObj
+	mss := []*registry.MicroServiceInstance{
+		{InstanceID: "ins1", EndpointsMap: map[string]*registry.Endpoint{"rest": {Address: "127.0.0.1:8001"}}},
+	}
+	testRegistryObj.On("FindMicroServiceInstances",
+		"selfServiceID", "appID", "idempotentService", "1.0", "").Return(mss, nil)
+	servicecomb.LBConfigCache.Set("idempotentService", control.LoadBalancingConfig{
+		Strategy:            loadbalancer.StrategyRoundRobin,
+		RetryEnabled:        true,
+		RetryOnSame:         2,
+		BackOffKind:         "zero",
+		RetryIdempotentOnly: true,
+	}, 0)
+	defer servicecomb.LBConfigCache.Delete("idempotentService")
+
+	calls := func(req *http.Request) int {
+		h := &slowFailHandler{}
+		c := handler.Chain{}
+		c.AddHandler(&handler.LBHandler{})
+		c.AddHandler(h)
+		i := &invocation.Invocation{
+			MicroServiceName: "idempotentService",
+			SourceServiceID:  "selfServiceID",
+			Protocol:         "rest",
+			Strategy:         loadbalancer.StrategyRoundRobin,
+			RouteTags:        utiltags.NewDefaultTag("1.0", "appID"),
+			Args:             req,
+		}
+		c.Next(i, func(r *invocation.Response) {
+			assert.Error(t, r.Err)
+		})
+		return h.calls
+	}
+	post, _ := rest.NewRequest(http.MethodPost, "http://idempotentService/orders", []byte("order"))
+	assert.Equal(t, 1, calls(post), "normal POST should not be retried")
+	post, _ = rest.NewRequest(http.MethodPost, "http://idempotentService/orders", []byte("order"))
+	httputil.SetIdempotent(post, true)
+	assert.Equal(t, 3, calls(post), "idempotent POST should be retried")
+	post, _ = rest.NewRequest(http.MethodPost, "http://idempotentService/orders", []byte("order"))
+	post.Header.Set(httputil.HeaderIdempotencyKey, "order-1")
+	assert.Equal(t, 3, calls(post), "POST with idempotency key should be retried")
+	get, _ := rest.NewRequest(http.MethodGet, "http://idempotentService/orders", nil)
+	assert.Equal(t, 3, calls(get))
+
+	servicecomb.LBConfigCache.Set("idempotentService", control.LoadBalancingConfig{
+		Strategy:     loadbalancer.StrategyRoundRobin,
+		RetryEnabled: true,
+		RetryOnSame:  2,
+		BackOffKind:  "zero",
+	}, 0)
+	post, _ = rest.NewRequest(http.MethodPost, "http://idempotentService/orders", []byte("order"))
+	assert.Equal(t, 3, calls(post), "POST is retried if retryIdempotentOnly is not set")
+}
+
+func TestLBHandlerWithRetry_Budget(t *testing.T) {
+	archaius.Init(archaius.WithMemorySource())
+	err := control.Init(control.Options{})
+	assert.NoError(t, err)
+	loadbalancer.Enable(loadbalancer.StrategyRoundRobin)
+	testRegistryObj := new(mk.DiscoveryMock)
+	registry.DefaultServiceDiscoveryService = testRegistryObj
+	mss := []*registry.MicroServiceInstance{
+		{InstanceID: "ins1", EndpointsMap: map[string]*registry.Endpoint{"rest": {Address: "127.0.0.1:8001"}}},
+	}
+	testRegistryObj.On("FindMicroServiceInstances",
+		"selfServiceID", "appID", "budgetService", "1.0", "").Return(mss, nil)
+	servicecomb.LBConfigCache.Set("budgetService", control.LoadBalancingConfig{
+		Strategy:     loadbalancer.StrategyRoundRobin,
+		RetryEnabled: true,
+		RetryOnSame:  2,
+		BackOffKind:  "zero",
+	}, 0)
+	defer servicecomb.LBConfigCache.Delete("budgetService")
+	retry.SetBudget(retry.NewBudget(0, 0))
+	defer retry.SetBudget(nil)
+
+	h := &slowFailHandler{}
+	c := handler.Chain{}
+	c.AddHandler(&handler.LBHandler{})
+	c.AddHandler(h)
+	for n := 0; n < 3; n++ {
+		req, _ := rest.NewRequest(http.MethodGet, "http://budgetService/orders", nil)
+		i := &invocation.Invocation{
+			MicroServiceName: "budgetService",
+			SourceServiceID:  "selfServiceID",
+			Protocol:         "rest",
+			Strategy:         loadbalancer.StrategyRoundRobin,
+			RouteTags:        utiltags.NewDefaultTag("1.0", "appID"),
+			Args:             req,
+		}
+		c.Next(i, func(r *invocation.Response) {
+			assert.Error(t, r.Err)
+		})
+	}
+	// budget has only one token, the other failures are not retried
+	assert.Equal(t, 4, h.calls)
+}
+
+// endpointRecordHandler fails every attempt and records its endpoint
+type endpointRecordHandler struct {
+	endpoints []string
+}
+
+func (h *endpointRecordHandler) Name() string {
+	return "endpointRecord"
+}
+
+func (h *endpointRecordHandler) Handle(chain *handler.Chain, i *invocation.Invocation, cb invocation.ResponseCallBack) {
+	h.endpoints = append(h.endpoints, i.Endpoint)
+	cb(&invocation.Response{Err: errors.New("fake")})
+}
+
+func TestLBHandlerWithRetry_BudgetExhausted(t *testing.T) {
+	archaius.Init(archaius.WithMemorySource())
+	err := control.Init(control.Options{})
+	assert.NoError(t, err)
+	loadbalancer.Enable(loadbalancer.StrategyRoundRobin)
+	testRegistryObj := new(mk.DiscoveryMock)
+	registry.DefaultServiceDiscoveryService = testRegistryObj
+	mss := []*registry.MicroServiceInstance{
+		{InstanceID: "ins1", EndpointsMap: map[string]*registry.Endpoint{"rest": {Address: "127.0.0.1:8001"}}},
+		{InstanceID: "ins2", EndpointsMap: map[string]*registry.Endpoint{"rest": {Address: "127.0.0.1:8002"}}},
+	}
+	testRegistryObj.On("FindMicroServiceInstances",
+		"selfServiceID", "appID", "exhaustedService", "1.0", "").Return(mss, nil)
+	servicecomb.LBConfigCache.Set("exhaustedService", control.LoadBalancingConfig{
+		Strategy:     loadbalancer.StrategyRoundRobin,
+		RetryEnabled: true,
+		RetryOnNext:  1,
+		BackOffKind:  "zero",
+	}, 0)
+	defer servicecomb.LBConfigCache.Delete("exhaustedService")
+	b := retry.NewBudget(0, 0)
+	assert.True(t, b.Withdraw())
+	retry.SetBudget(b)
+	defer retry.SetBudget(nil)
+
+	h := &endpointRecordHandler{}
+	c := handler.Chain{}
+	c.AddHandler(&handler.LBHandler{})
+	c.AddHandler(h)
+	for n := 0; n < 4; n++ {
+		req, _ := rest.NewRequest(http.MethodGet, "http://exhaustedService/orders", nil)
+		i := &invocation.Invocation{
+			MicroServiceName: "exhaustedService",
+			SourceServiceID:  "selfServiceID",
+			Protocol:         "rest",
+			Strategy:         loadbalancer.StrategyRoundRobin,
+			RouteTags:        utiltags.NewDefaultTag("1.0", "appID"),
+			Args:             req,
+		}
+		c.Next(i, func(r *invocation.Response) {
+			assert.Error(t, r.Err)
+		})
+	}
+	assert.Equal(t, 4, len(h.endpoints), "failures are not retried once budget is exhausted")
+	for n := 1; n < len(h.endpoints); n++ {
+		assert.NotEqual(t, h.endpoints[n-1], h.endpoints[n], "retry which is not sent does not move round robin")
+	}
+}
+
 func init() {
 	lager.Init(&lager.Options{
 		LoggerLevel: "INFO",
@@ -570,3 +851,526 @@ func BenchmarkLBHandler_Handle(b *testing.B) {
 	}
 
 }
+
+// failOnceHandler fails first attempt with 503
+type failOnceHandler struct {
+	failed bool
+}
+
+func (h *failOnceHandler) Name() string {
+	return "failOnce"
+}
+
+func (h *failOnceHandler) Handle(chain *handler.Chain, i *invocation.Invocation, cb invocation.ResponseCallBack) {
+	if !h.failed {
+		h.failed = true
+		cb(&invocation.Response{Err: fmt.Errorf("status 503 from %s", i.Endpoint)})
+		return
+	}
+	cb(&invocation.Response{})
+}
+
+func TestLBHandlerWithRetry_DecisionTrace(t *testing.T) {
+	archaius.Init(archaius.WithMemorySource())
+	err := control.Init(control.Options{})
+	assert.NoError(t, err)
+	loadbalancer.Enable(loadbalancer.StrategyRoundRobin)
+	testRegistryObj := new(mk.DiscoveryMock)
+	registry.DefaultServiceDiscoveryService = testRegistryObj
+	mss := []*registry.MicroServiceInstance{
+		{InstanceID: "ins1", EndpointsMap: map[string]*registry.Endpoint{"rest": {Address: "127.0.0.1:8001"}}},
+		{InstanceID: "ins2", EndpointsMap: map[string]*registry.Endpoint{"rest": {Address: "127.0.0.1:8002"}}},
+	}
+	testRegistryObj.On("FindMicroServiceInstances",
+		"selfServiceID", "appID", "traceService", "1.0", "").Return(mss, nil)
+	servicecomb.LBConfigCache.Set("traceService", control.LoadBalancingConfig{
+		Strategy:     loadbalancer.StrategyRoundRobin,
+		RetryEnabled: true,
+		RetryOnNext:  1,
+		BackOffKind:  "zero",
+	}, 0)
+	defer servicecomb.LBConfigCache.Delete("traceService")
+
+	c := handler.Chain{}
+	c.AddHandler(&handler.LBHandler{})
+	c.AddHandler(&failOnceHandler{})
+	trace := &invocation.DecisionTrace{}
+	i := &invocation.Invocation{
+		MicroServiceName: "traceService",
+		SourceServiceID:  "selfServiceID",
+		Protocol:         "rest",
+		Strategy:         loadbalancer.StrategyRoundRobin,
+		RouteTags:        utiltags.NewDefaultTag("1.0", "appID"),
+		Metadata:         map[string]interface{}{invocation.MDDecisionTrace: trace},
+	}
+	c.Next(i, func(r *invocation.Response) {
+		assert.NoError(t, r.Err)
+	})
+	if !assert.Len(t, trace.Events, 5, trace.String()) {
+		return
+	}
+	first, next := trace.Events[0].Endpoint, trace.Events[2].Endpoint
+	assert.NotEqual(t, first, next, "retry on next endpoint")
+	assert.Equal(t, []invocation.DecisionEvent{
+		{Kind: invocation.EventPick, Endpoint: first, Detail: "strategy RoundRobin"},
+		{Kind: invocation.EventAttemptFailed, Endpoint: first, Detail: "status 503 from " + first},
+		{Kind: invocation.EventPick, Endpoint: next, Detail: "strategy RoundRobin"},
+		{Kind: invocation.EventRetry, Endpoint: next},
+		{Kind: invocation.EventSucceeded, Endpoint: next},
+	}, trace.Events)
+}
+
+func TestLBHandlerWithRetry_FaultInjection(t *testing.T) {
+	archaius.Init(archaius.WithMemorySource())
+	err := control.Init(control.Options{})
+	assert.NoError(t, err)
+	loadbalancer.Enable(loadbalancer.StrategyRoundRobin)
+	testRegistryObj := new(mk.DiscoveryMock)
+	registry.DefaultServiceDiscoveryService = testRegistryObj
+	mss := []*registry.MicroServiceInstance{
+		{InstanceID: "ins1", EndpointsMap: map[string]*registry.Endpoint{"rest": {Address: "127.0.0.1:8001"}}},
+	}
+	testRegistryObj.On("FindMicroServiceInstances",
+		"selfServiceID", "appID", "faultService", "1.0", "").Return(mss, nil)
+	servicecomb.LBConfigCache.Set("faultService", control.LoadBalancingConfig{
+		Strategy:     loadbalancer.StrategyRoundRobin,
+		RetryEnabled: true,
+		RetryOnSame:  2,
+		BackOffKind:  "zero",
+	}, 0)
+	defer servicecomb.LBConfigCache.Delete("faultService")
+
+	c := handler.Chain{}
+	c.AddHandler(&handler.LBHandler{})
+	c.AddHandler(&handler.TransportHandler{})
+	trace := &invocation.DecisionTrace{}
+	i := &invocation.Invocation{
+		MicroServiceName: "faultService",
+		SourceServiceID:  "selfServiceID",
+		Protocol:         "rest",
+		Strategy:         loadbalancer.StrategyRoundRobin,
+		RouteTags:        utiltags.NewDefaultTag("1.0", "appID"),
+		Reply:            rest.NewResponse(),
+		Metadata: map[string]interface{}{
+			invocation.MDDecisionTrace: trace,
+			common.FaultInjectionKey:   chassisModel.Fault{Abort: chassisModel.Abort{Percent: 100, HTTPStatus: http.StatusServiceUnavailable}},
+		},
+	}
+	c.Next(i, func(r *invocation.Response) {
+		assert.Error(t, r.Err)
+		assert.Equal(t, http.StatusServiceUnavailable, r.Status)
+	})
+	var injected, retried int
+	for _, e := range trace.Events {
+		switch e.Kind {
+		case invocation.EventFaultInjected:
+			injected++
+			assert.Equal(t, "abort status 503", e.Detail)
+		case invocation.EventRetry:
+			retried++
+		}
+	}
+	assert.Equal(t, 3, injected, trace.String())
+	assert.Equal(t, 2, retried, trace.String())
+	assert.Equal(t, http.StatusServiceUnavailable, i.Reply.(*http.Response).StatusCode)
+}
+
+// dnsFailOnceHandler fails first attempt with a DNS error
+type dnsFailOnceHandler struct {
+	err       error
+	endpoints []string
+}
+
+func (h *dnsFailOnceHandler) Name() string {
+	return "dnsFailOnce"
+}
+
+func (h *dnsFailOnceHandler) Handle(chain *handler.Chain, i *invocation.Invocation, cb invocation.ResponseCallBack) {
+	h.endpoints = append(h.endpoints, i.Endpoint)
+	if len(h.endpoints) == 1 {
+		cb(&invocation.Response{Err: h.err})
+		return
+	}
+	cb(&invocation.Response{})
+}
+
+func TestLBHandlerWithRetry_DNSError(t *testing.T) {
+	archaius.Init(archaius.WithMemorySource())
+	err := control.Init(control.Options{})
+	assert.NoError(t, err)
+	loadbalancer.Enable(loadbalancer.StrategyRoundRobin)
+	testRegistryObj := new(mk.DiscoveryMock)
+	registry.DefaultServiceDiscoveryService = testRegistryObj
+	mss := []*registry.MicroServiceInstance{
+		{InstanceID: "ins1", EndpointsMap: map[string]*registry.Endpoint{"rest": {Address: "a.local:8001"}}},
+		{InstanceID: "ins2", EndpointsMap: map[string]*registry.Endpoint{"rest": {Address: "b.local:8002"}}},
+	}
+	testRegistryObj.On("FindMicroServiceInstances",
+		"selfServiceID", "appID", "dnsService", "1.0", "").Return(mss, nil)
+	servicecomb.LBConfigCache.Set("dnsService", control.LoadBalancingConfig{
+		Strategy:     loadbalancer.StrategyRoundRobin,
+		RetryEnabled: true,
+		RetryOnSame:  2,
+		RetryOnNext:  1,
+		BackOffKind:  "zero",
+	}, 0)
+	defer servicecomb.LBConfigCache.Delete("dnsService")
+
+	attempts := func(respErr error) []string {
+		h := &dnsFailOnceHandler{err: respErr}
+		c := handler.Chain{}
+		c.AddHandler(&handler.LBHandler{})
+		c.AddHandler(h)
+		i := &invocation.Invocation{
+			MicroServiceName: "dnsService",
+			SourceServiceID:  "selfServiceID",
+			Protocol:         "rest",
+			Strategy:         loadbalancer.StrategyRoundRobin,
+			RouteTags:        utiltags.NewDefaultTag("1.0", "appID"),
+		}
+		c.Next(i, func(r *invocation.Response) {
+			assert.NoError(t, r.Err)
+		})
+		return h.endpoints
+	}
+	for name, respErr := range map[string]error{
+		"not found": &rest.DNSError{Host: "a.local", Permanent: true, Err: errors.New("no such host")},
+		"temporary": &rest.DNSError{Host: "a.local", Err: errors.New("server misbehaving")},
+		"connect timeout": &rest.TransportError{Addr: "a.local:8001",
+			Err: &rest.TimeoutError{Phase: rest.PhaseDial, Addr: "a.local:8001", Err: errors.New("i/o timeout")}},
+	} {
+		eps := attempts(respErr)
+		if assert.Len(t, eps, 2, name) {
+			assert.NotEqual(t, eps[0], eps[1], "%s: retry goes to another endpoint", name)
+		}
+	}
+	for _, respErr := range []error{errors.New("refused"), &rest.TimeoutError{Phase: rest.PhaseRead, Err: errors.New("i/o timeout")}} {
+		eps := attempts(respErr)
+		if assert.Len(t, eps, 2) {
+			assert.Equal(t, eps[0], eps[1], "other failures are retried on the same endpoint first")
+		}
+	}
+}
+
+func TestLBHandlerWithRetry_BodyClassification(t *testing.T) {
+	var hits int32
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		w.Write([]byte(r.URL.Query().Get("body")))
+	}))
+	defer s.Close()
+	config.GlobalDefinition = &chassisModel.GlobalCfg{}
+	archaius.Init(archaius.WithMemorySource())
+	err := control.Init(control.Options{})
+	assert.NoError(t, err)
+	loadbalancer.Enable(loadbalancer.StrategyRoundRobin)
+	testRegistryObj := new(mk.DiscoveryMock)
+	registry.DefaultServiceDiscoveryService = testRegistryObj
+	mss := []*registry.MicroServiceInstance{
+		{InstanceID: "ins1", EndpointsMap: map[string]*registry.Endpoint{"rest": {Address: strings.TrimPrefix(s.URL, "http://")}}},
+	}
+	testRegistryObj.On("FindMicroServiceInstances",
+		"selfServiceID", "appID", "bodyRetryService", "1.0", "").Return(mss, nil)
+	servicecomb.LBConfigCache.Set("bodyRetryService", control.LoadBalancingConfig{
+		Strategy:     loadbalancer.StrategyRoundRobin,
+		RetryEnabled: true,
+		RetryOnSame:  2,
+		BackOffKind:  "zero",
+	}, 0)
+	defer servicecomb.LBConfigCache.Delete("bodyRetryService")
+
+	errBackOff := errors.New("upstream asks to retry")
+	peek := rest.ErrorPeek{Size: 64, Classify: func(resp *http.Response, head []byte) error {
+		switch {
+		case strings.Contains(string(head), `"retryable": true`):
+			return errBackOff
+		case strings.Contains(string(head), `"retryable": false`):
+			return rest.Permanent(errBackOff)
+		}
+		return nil
+	}}
+	call := func(body string) (int32, error) {
+		atomic.StoreInt32(&hits, 0)
+		c := handler.Chain{}
+		c.AddHandler(&handler.LBHandler{})
+		c.AddHandler(&handler.TransportHandler{})
+		req, _ := rest.NewRequest(http.MethodGet, "http://bodyRetryService/?body="+url.QueryEscape(body), nil)
+		i := &invocation.Invocation{
+			MicroServiceName: "bodyRetryService",
+			SourceServiceID:  "selfServiceID",
+			Protocol:         "rest",
+			Strategy:         loadbalancer.StrategyRoundRobin,
+			RouteTags:        utiltags.NewDefaultTag("1.0", "appID"),
+			Ctx:              context.TODO(),
+			Args:             req,
+			Reply:            rest.NewResponse(),
+			Metadata:         map[string]interface{}{common.ErrorPeekKey: peek},
+		}
+		var respErr error
+		c.Next(i, func(r *invocation.Response) {
+			respErr = r.Err
+		})
+		return atomic.LoadInt32(&hits), respErr
+	}
+
+	n, err := call(`{"retryable": true}`)
+	assert.Equal(t, int32(3), n, "200 with retryable body is retried")
+	assert.Equal(t, errBackOff, err)
+	n, err = call(`{"data": 1}`)
+	assert.Equal(t, int32(1), n, "200 without retryable body is a success")
+	assert.NoError(t, err)
+	n, err = call(`{"retryable": false}`)
+	assert.Equal(t, int32(1), n, "permanent failure is not retried")
+	assert.True(t, errors.Is(err, errBackOff))
+}
+
+func TestLBHandlerWithRetry_TooEarly(t *testing.T) {
+	var earlyData []string
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		earlyData = append(earlyData, r.Header.Get(rest.HeaderEarlyData))
+		if r.Header.Get(rest.HeaderEarlyData) == "1" {
+			w.WriteHeader(http.StatusTooEarly)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer s.Close()
+	config.GlobalDefinition = &chassisModel.GlobalCfg{}
+	config.GlobalDefinition.ServiceComb.Transport.Failure = map[string]string{"rest": "http_425,http_500"}
+	defer func() { config.GlobalDefinition = &chassisModel.GlobalCfg{} }()
+	archaius.Init(archaius.WithMemorySource())
+	err := control.Init(control.Options{})
+	assert.NoError(t, err)
+	loadbalancer.Enable(loadbalancer.StrategyRoundRobin)
+	testRegistryObj := new(mk.DiscoveryMock)
+	registry.DefaultServiceDiscoveryService = testRegistryObj
+	mss := []*registry.MicroServiceInstance{
+		{InstanceID: "ins1", EndpointsMap: map[string]*registry.Endpoint{"rest": {Address: strings.TrimPrefix(s.URL, "http://")}}},
+	}
+	testRegistryObj.On("FindMicroServiceInstances",
+		"selfServiceID", "appID", "earlyService", "1.0", "").Return(mss, nil)
+	servicecomb.LBConfigCache.Set("earlyService", control.LoadBalancingConfig{
+		Strategy:     loadbalancer.StrategyRoundRobin,
+		RetryEnabled: true,
+		RetryOnSame:  2,
+		BackOffKind:  "zero",
+	}, 0)
+	defer servicecomb.LBConfigCache.Delete("earlyService")
+
+	c := handler.Chain{}
+	c.AddHandler(&handler.LBHandler{})
+	c.AddHandler(&handler.TransportHandler{})
+	req, _ := rest.NewRequest(http.MethodGet, "http://earlyService/", nil)
+	req.Header.Set(rest.HeaderEarlyData, "1")
+	i := &invocation.Invocation{
+		MicroServiceName: "earlyService",
+		SourceServiceID:  "selfServiceID",
+		Protocol:         "rest",
+		Strategy:         loadbalancer.StrategyRoundRobin,
+		RouteTags:        utiltags.NewDefaultTag("1.0", "appID"),
+		Ctx:              context.TODO(),
+		Args:             req,
+		Reply:            rest.NewResponse(),
+	}
+	c.Next(i, func(r *invocation.Response) {
+		assert.NoError(t, r.Err)
+	})
+	assert.Equal(t, []string{"1", ""}, earlyData, "425 is retried without early data")
+	assert.Equal(t, http.StatusOK, i.Reply.(*http.Response).StatusCode)
+}
+
+func TestLBHandlerWithRetry_PermanentFailure(t *testing.T) {
+	hits := map[string]int{}
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits[r.URL.Path]++
+		code, _ := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/"))
+		w.WriteHeader(code)
+	}))
+	defer s.Close()
+	config.GlobalDefinition = &chassisModel.GlobalCfg{}
+	config.GlobalDefinition.ServiceComb.Transport.Failure = map[string]string{"rest": "http_500,http_501:permanent"}
+	defer func() { config.GlobalDefinition = &chassisModel.GlobalCfg{} }()
+	archaius.Init(archaius.WithMemorySource())
+	err := control.Init(control.Options{})
+	assert.NoError(t, err)
+	loadbalancer.Enable(loadbalancer.StrategyRoundRobin)
+	testRegistryObj := new(mk.DiscoveryMock)
+	registry.DefaultServiceDiscoveryService = testRegistryObj
+	mss := []*registry.MicroServiceInstance{
+		{InstanceID: "ins1", EndpointsMap: map[string]*registry.Endpoint{"rest": {Address: strings.TrimPrefix(s.URL, "http://")}}},
+	}
+	testRegistryObj.On("FindMicroServiceInstances",
+		"selfServiceID", "appID", "gatewayService", "1.0", "").Return(mss, nil)
+	servicecomb.LBConfigCache.Set("gatewayService", control.LoadBalancingConfig{
+		Strategy:     loadbalancer.StrategyRoundRobin,
+		RetryEnabled: true,
+		RetryOnSame:  2,
+		BackOffKind:  "zero",
+	}, 0)
+	defer servicecomb.LBConfigCache.Delete("gatewayService")
+
+	c := handler.Chain{}
+	c.AddHandler(&handler.LBHandler{})
+	c.AddHandler(&handler.TransportHandler{})
+	call := func(path string) error {
+		req, _ := rest.NewRequest(http.MethodGet, "http://gatewayService"+path, nil)
+		i := &invocation.Invocation{
+			MicroServiceName: "gatewayService",
+			SourceServiceID:  "selfServiceID",
+			Protocol:         "rest",
+			Strategy:         loadbalancer.StrategyRoundRobin,
+			RouteTags:        utiltags.NewDefaultTag("1.0", "appID"),
+			Ctx:              context.TODO(),
+			Args:             req,
+			Reply:            rest.NewResponse(),
+		}
+		var err error
+		c.Next(i, func(r *invocation.Response) {
+			err = r.Err
+		})
+		return err
+	}
+	var statusErr *rest.StatusError
+	assert.True(t, errors.As(call("/501"), &statusErr))
+	assert.Equal(t, 1, hits["/501"], "501 is not retried")
+	assert.Error(t, call("/500"))
+	assert.Equal(t, 3, hits["/500"], "500 is retried")
+}
+
+// endpointErrHandler fails every attempt with error of its endpoint
+type endpointErrHandler map[string]error
+
+func (h endpointErrHandler) Name() string {
+	return "endpointErr"
+}
+
+func (h endpointErrHandler) Handle(chain *handler.Chain, i *invocation.Invocation, cb invocation.ResponseCallBack) {
+	cb(&invocation.Response{Err: h[i.Endpoint]})
+}
+
+func TestLBHandlerWithRetry_AggregateErrors(t *testing.T) {
+	archaius.Init(archaius.WithMemorySource())
+	err := control.Init(control.Options{})
+	assert.NoError(t, err)
+	loadbalancer.Enable(loadbalancer.StrategyRoundRobin)
+	testRegistryObj := new(mk.DiscoveryMock)
+	registry.DefaultServiceDiscoveryService = testRegistryObj
+	mss := []*registry.MicroServiceInstance{
+		{InstanceID: "ins1", EndpointsMap: map[string]*registry.Endpoint{"rest": {Address: "10.0.0.1:8080"}}},
+		{InstanceID: "ins2", EndpointsMap: map[string]*registry.Endpoint{"rest": {Address: "10.0.0.2:8080"}}},
+		{InstanceID: "ins3", EndpointsMap: map[string]*registry.Endpoint{"rest": {Address: "10.0.0.3:8080"}}},
+	}
+	testRegistryObj.On("FindMicroServiceInstances",
+		"selfServiceID", "appID", "aggregateService", "1.0", "").Return(mss, nil)
+	servicecomb.LBConfigCache.Set("aggregateService", control.LoadBalancingConfig{
+		Strategy:     loadbalancer.StrategyRoundRobin,
+		RetryEnabled: true,
+		RetryOnNext:  2,
+		BackOffKind:  "zero",
+	}, 0)
+	defer servicecomb.LBConfigCache.Delete("aggregateService")
+
+	errs := endpointErrHandler{
+		"10.0.0.1:8080": &rest.StatusError{StatusCode: http.StatusServiceUnavailable, Addr: "10.0.0.1:8080"},
+		"10.0.0.2:8080": &rest.TimeoutError{Phase: rest.PhaseRead, Addr: "10.0.0.2:8080", Err: errors.New("i/o timeout")},
+		"10.0.0.3:8080": &rest.StatusError{StatusCode: http.StatusInternalServerError, Addr: "10.0.0.3:8080"},
+	}
+	call := func(aggregate bool) error {
+		c := handler.Chain{}
+		c.AddHandler(&handler.LBHandler{})
+		c.AddHandler(errs)
+		i := &invocation.Invocation{
+			MicroServiceName: "aggregateService",
+			SourceServiceID:  "selfServiceID",
+			Protocol:         "rest",
+			Strategy:         loadbalancer.StrategyRoundRobin,
+			RouteTags:        utiltags.NewDefaultTag("1.0", "appID"),
+		}
+		if aggregate {
+			i.SetMetadata(common.AggregateErrorsKey, true)
+		}
+		var respErr error
+		c.Next(i, func(r *invocation.Response) {
+			respErr = r.Err
+		})
+		return respErr
+	}
+
+	err = call(true)
+	var agg *handler.AggregateError
+	if assert.True(t, errors.As(err, &agg)) && assert.Len(t, agg.Attempts, 3) {
+		endpoints := map[string]error{}
+		for _, a := range agg.Attempts {
+			endpoints[a.Endpoint] = a.Err
+			assert.Contains(t, err.Error(), a.Endpoint+": "+a.Err.Error())
+		}
+		assert.Equal(t, map[string]error(errs), endpoints, "every endpoint is attempted once")
+		last := agg.Attempts[2]
+		assert.Equal(t, last.Err, errors.Unwrap(err), "last failure is unwrapped")
+	}
+
+	err = call(false)
+	assert.False(t, errors.As(err, &agg), "only the last failure is returned by default")
+	var statusErr *rest.StatusError
+	var timeoutErr *rest.TimeoutError
+	assert.True(t, errors.As(err, &statusErr) || errors.As(err, &timeoutErr))
+}
+
+func TestLBHandler_PickSampleSize(t *testing.T) {
+	archaius.Init(archaius.WithMemorySource())
+	err := control.Init(control.Options{})
+	assert.NoError(t, err)
+	var scored int
+	loadbalancer.InstallScoreStrategy("CountScored", func(ep *registry.Endpoint, s loadbalancer.Signals) float64 {
+		scored++
+		return 0
+	})
+	servicecomb.LBConfigCache.Set("sampleService", control.LoadBalancingConfig{
+		Strategy:       "CountScored",
+		BackOffKind:    "zero",
+		PickSampleSize: 2,
+	}, 0)
+	defer servicecomb.LBConfigCache.Delete("sampleService")
+
+	c := handler.Chain{}
+	c.AddHandler(&handler.LBHandler{})
+	c.AddHandler(&handler1{})
+	i := &invocation.Invocation{
+		MicroServiceName: "sampleService",
+		Protocol:         "rest",
+		RouteTags:        utiltags.NewDefaultTag("1.0", "appID"),
+	}
+	i.SetMetadata(common.ExplicitEndpointsKey, []string{"10.0.0.1:8080", "10.0.0.2:8080", "10.0.0.3:8080", "10.0.0.4:8080"})
+	c.Next(i, func(r *invocation.Response) {
+		assert.NoError(t, r.Err)
+	})
+	assert.Equal(t, 2, scored, "pick sample size of service config is applied")
+}
+
+func TestLBHandler_LatencyConfig(t *testing.T) {
+	archaius.Init(archaius.WithMemorySource())
+	err := control.Init(control.Options{})
+	assert.NoError(t, err)
+	servicecomb.LBConfigCache.Set("decayService", control.LoadBalancingConfig{
+		Strategy:          loadbalancer.StrategyRoundRobin,
+		BackOffKind:       "zero",
+		LatencyDecay:      0.3,
+		LatencyMinSamples: 5,
+	}, 0)
+	defer servicecomb.LBConfigCache.Delete("decayService")
+
+	c := handler.Chain{}
+	c.AddHandler(&handler.LBHandler{})
+	c.AddHandler(&handler1{})
+	i := &invocation.Invocation{
+		MicroServiceName: "decayService",
+		Protocol:         "rest",
+		RouteTags:        utiltags.NewDefaultTag("1.0", "appID"),
+	}
+	i.SetMetadata(common.ExplicitEndpointsKey, []string{"10.0.0.1:8080"})
+	c.Next(i, func(r *invocation.Response) {
+		assert.NoError(t, r.Err)
+	})
+	assert.Equal(t, 0.3, i.Metadata[common.LatencyDecayKey], "latency decay of service config is applied")
+	assert.Equal(t, 5, i.Metadata[common.LatencyMinSamplesKey], "latency min samples of service config is applied")
+}
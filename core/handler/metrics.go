@@ -0,0 +1,37 @@
+package handler
+
+import (
+	"strconv"
+	"sync"
+
+	"github.com/go-chassis/go-chassis/v2/core/invocation"
+	"github.com/go-chassis/go-chassis/v2/pkg/metrics"
+	"github.com/go-chassis/openlog"
+)
+
+// MetricConsumerRequests counts requests sent by transport handler, retries are counted too.
+// label operation is the operation name of invocation, see core.WithOperationName
+const MetricConsumerRequests = "consumer_request_total"
+
+var requestMetricOnce sync.Once
+
+// reportRequest counts a request, metric is only reported when metrics registry is initialized
+func reportRequest(i *invocation.Invocation, status int) {
+	if !metrics.Enabled() {
+		return
+	}
+	requestMetricOnce.Do(func() {
+		err := metrics.CreateCounter(metrics.CounterOpts{
+			Name:   MetricConsumerRequests,
+			Help:   "count of requests sent to services by operation and status code, 0 means no response",
+			Labels: []string{"service", "operation", "code"},
+		})
+		if err != nil {
+			openlog.Error(err.Error())
+		}
+	})
+	labels := map[string]string{"service": i.MicroServiceName, "operation": i.OperationName(), "code": strconv.Itoa(status)}
+	if err := metrics.CounterAdd(MetricConsumerRequests, 1, labels); err != nil {
+		openlog.Error("can not report request: " + err.Error())
+	}
+}
@@ -19,9 +19,22 @@ func (ph *RouterHandler) Handle(chain *Chain, i *invocation.Invocation, cb invoc
 		return
 	}
 
+	err := route(i)
+	if err != nil {
+		WriteBackErr(err, status.Status(i.Protocol, status.ServiceUnavailable), cb)
+	}
+
+	//call next chain
+	chain.Next(i, cb)
+}
+
+// route decides route tags of invocation by route rules
+func route(i *invocation.Invocation) error {
 	tags := map[string]string{}
 	for k, v := range i.Metadata {
-		tags[k] = v.(string)
+		if s, ok := v.(string); ok {
+			tags[k] = s
+		}
 	}
 	tags[common.BuildinTagApp] = runtime.App
 
@@ -33,13 +46,7 @@ func (ph *RouterHandler) Handle(chain *Chain, i *invocation.Invocation, cb invoc
 		}
 	}
 
-	err := router.Route(h, &registry.SourceInfo{Name: i.SourceMicroService, Tags: tags}, i)
-	if err != nil {
-		WriteBackErr(err, status.Status(i.Protocol, status.ServiceUnavailable), cb)
-	}
-
-	//call next chain
-	chain.Next(i, cb)
+	return router.Route(h, &registry.SourceInfo{Name: i.SourceMicroService, Tags: tags}, i)
 }
 
 func newRouterHandler() Handler {
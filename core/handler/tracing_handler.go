@@ -74,10 +74,10 @@ func (t *TracingConsumerHandler) Handle(chain *Chain, i *invocation.Invocation,
 	var span opentracing.Span
 	wireContext, _ := opentracing.GlobalTracer().Extract(opentracing.TextMap, opentracing.TextMapCarrier(i.Headers()))
 	if wireContext == nil {
-		span = opentracing.StartSpan(i.OperationID)
+		span = opentracing.StartSpan(i.OperationName())
 	} else {
 		// store span in context
-		span = opentracing.StartSpan(i.OperationID, opentracing.ChildOf(wireContext))
+		span = opentracing.StartSpan(i.OperationName(), opentracing.ChildOf(wireContext))
 	}
 	// set span kind to be client
 	ext.SpanKindRPCClient.Set(span)
@@ -2,6 +2,7 @@ package handler
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/http"
 	"time"
@@ -9,6 +10,8 @@ import (
 	"github.com/go-chassis/go-chassis/v2/core/client"
 	"github.com/go-chassis/go-chassis/v2/core/common"
 	"github.com/go-chassis/go-chassis/v2/core/config"
+	"github.com/go-chassis/go-chassis/v2/core/config/model"
+	"github.com/go-chassis/go-chassis/v2/core/fault"
 	"github.com/go-chassis/go-chassis/v2/core/invocation"
 	"github.com/go-chassis/go-chassis/v2/core/loadbalancer"
 	"github.com/go-chassis/go-chassis/v2/session"
@@ -32,6 +35,16 @@ func errNotNil(err error, cb invocation.ResponseCallBack) {
 
 // Handle is to handle transport related things
 func (th *TransportHandler) Handle(chain *Chain, i *invocation.Invocation, cb invocation.ResponseCallBack) {
+	if rule, ok := i.Metadata[common.FaultInjectionKey].(model.Fault); ok {
+		if err := fault.InjectCall(rule, i); err != nil {
+			r := &invocation.Response{Err: err}
+			if resp, ok := i.Reply.(*http.Response); ok && resp != nil {
+				r.Status = resp.StatusCode
+			}
+			cb(r)
+			return
+		}
+	}
 
 	c, err := client.GetClient(i)
 	if err != nil {
@@ -43,13 +56,14 @@ func (th *TransportHandler) Handle(chain *Chain, i *invocation.Invocation, cb in
 
 	//taking the time elapsed to check for latency aware strategy
 	timeBefore := time.Now()
-	err = c.Call(i.Ctx, i.Endpoint, i, i.Reply)
+	err = call(c, i)
 	if resp, ok := i.Reply.(*http.Response); ok {
 		r.Status = resp.StatusCode
 	}
+	reportRequest(i, r.Status)
 	if err != nil {
 		r.Err = err
-		if err != client.ErrCanceled {
+		if !errors.Is(err, client.ErrCanceled) {
 			openlog.Error(fmt.Sprintf("call err [%s]", err.Error()))
 		}
 		if i.Strategy == loadbalancer.StrategySessionStickiness {
@@ -60,9 +74,10 @@ func (th *TransportHandler) Handle(chain *Chain, i *invocation.Invocation, cb in
 		return
 	}
 
-	if i.Strategy == loadbalancer.StrategyLatency {
+	if skip, _ := i.Metadata[common.SkipLatencyRecordingKey].(bool); !skip && loadbalancer.NeedLatency(i.Strategy) {
 		timeAfter := time.Since(timeBefore)
-		loadbalancer.SetLatency(timeAfter, i.Endpoint, i.MicroServiceName, i.RouteTags, i.Protocol)
+		decay, _ := i.Metadata[common.LatencyDecayKey].(float64)
+		loadbalancer.SetLatencyWithDecay(timeAfter, decay, i.Endpoint, i.MicroServiceName, i.RouteTags, i.Protocol)
 	}
 
 	if i.Strategy == loadbalancer.StrategySessionStickiness {
@@ -73,6 +88,20 @@ func (th *TransportHandler) Handle(chain *Chain, i *invocation.Invocation, cb in
 	cb(r)
 }
 
+// errCallAborted is recorded as the result of a call which panics
+var errCallAborted = errors.New("call aborted")
+
+// call sends request by client and records it for endpoint scoring, even if client panics
+func call(c client.ProtocolClient, i *invocation.Invocation) (err error) {
+	loadbalancer.CallStarted(i.Endpoint)
+	err = errCallAborted
+	defer func() {
+		loadbalancer.CallFinished(i.Endpoint, err)
+	}()
+	err = c.Call(i.Ctx, i.Endpoint, i, i.Reply)
+	return err
+}
+
 //ProcessSpecialProtocol handles special logic for protocol
 func ProcessSpecialProtocol(inv *invocation.Invocation) {
 	switch inv.Protocol {
@@ -2,20 +2,30 @@ package handler_test
 
 import (
 	"context"
+	"github.com/go-chassis/go-archaius"
 	"github.com/go-chassis/go-chassis/v2/client/rest"
 	"github.com/go-chassis/go-chassis/v2/core/common"
 	"github.com/go-chassis/go-chassis/v2/core/lager"
 	"github.com/go-chassis/go-chassis/v2/pkg/util/fileutil"
 	"io"
 	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/go-chassis/go-chassis/v2/core/config"
+	"github.com/go-chassis/go-chassis/v2/core/config/model"
 	"github.com/go-chassis/go-chassis/v2/core/handler"
 	"github.com/go-chassis/go-chassis/v2/core/invocation"
+	"github.com/go-chassis/go-chassis/v2/core/loadbalancer"
+	"github.com/go-chassis/go-chassis/v2/core/tracing"
 	"github.com/go-chassis/go-chassis/v2/examples/schemas/helloworld"
+	"github.com/go-chassis/go-chassis/v2/pkg/metrics"
+	"github.com/go-chassis/go-chassis/v2/pkg/util/tags"
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/mocktracer"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -90,3 +100,125 @@ servicecomb:
 	})
 
 }
+
+func TestTransportHandler_SkipLatencyRecording(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer s.Close()
+	if config.GlobalDefinition == nil {
+		config.GlobalDefinition = &model.GlobalCfg{}
+		defer func() { config.GlobalDefinition = nil }()
+	}
+	archaius.Init(archaius.WithMemorySource())
+	tags := utiltags.NewDefaultTag("1.0", "appID")
+	key := loadbalancer.BuildKey("latencyService", tags.String(), "rest")
+	records := func() int {
+		loadbalancer.LatencyMapRWMutex.RLock()
+		defer loadbalancer.LatencyMapRWMutex.RUnlock()
+		n := 0
+		for _, ps := range loadbalancer.ProtocolStatsMap[key] {
+			n += len(ps.Latency)
+		}
+		return n
+	}
+	send := func(skip bool) {
+		req, _ := rest.NewRequest(http.MethodGet, "http://latencyService/", nil)
+		i := &invocation.Invocation{
+			MicroServiceName: "latencyService",
+			Endpoint:         strings.TrimPrefix(s.URL, "http://"),
+			Protocol:         "rest",
+			Strategy:         loadbalancer.StrategyLatency,
+			RouteTags:        tags,
+			Ctx:              context.TODO(),
+			Args:             req,
+			Reply:            rest.NewResponse(),
+		}
+		if skip {
+			i.SetMetadata(common.SkipLatencyRecordingKey, true)
+		}
+		c := &handler.Chain{}
+		c.AddHandler(&handler.TransportHandler{})
+		c.Next(i, func(r *invocation.Response) {
+			assert.NoError(t, r.Err)
+		})
+	}
+	send(true)
+	assert.Equal(t, 0, records(), "skipped call is not recorded")
+	send(false)
+	assert.Equal(t, 1, records(), "normal call is recorded")
+	send(true)
+	assert.Equal(t, 1, records())
+}
+
+func TestTransportHandler_OperationName(t *testing.T) {
+	if !metrics.Enabled() {
+		assert.NoError(t, metrics.Init())
+	}
+	tracer := mocktracer.New()
+	old := opentracing.GlobalTracer()
+	opentracing.SetGlobalTracer(tracer)
+	defer opentracing.SetGlobalTracer(old)
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer s.Close()
+	if config.GlobalDefinition == nil {
+		config.GlobalDefinition = &model.GlobalCfg{}
+		defer func() { config.GlobalDefinition = nil }()
+	}
+	archaius.Init(archaius.WithMemorySource())
+
+	send := func(name string) {
+		req, _ := rest.NewRequest(http.MethodGet, "http://profileService/users/42/profile", nil)
+		i := invocation.New(context.TODO())
+		i.MicroServiceName = "profileService"
+		i.OperationID = "/users/42/profile"
+		i.Endpoint = strings.TrimPrefix(s.URL, "http://")
+		i.Protocol = "rest"
+		i.Args = req
+		i.Reply = rest.NewResponse()
+		if name != "" {
+			i.SetMetadata(invocation.MDOperationName, name)
+		}
+		c := &handler.Chain{}
+		c.AddHandler(&handler.TracingConsumerHandler{})
+		c.AddHandler(&handler.TransportHandler{})
+		c.Next(i, func(r *invocation.Response) {
+			assert.NoError(t, r.Err)
+		})
+	}
+	count := func(operation string) float64 {
+		mfs, err := metrics.GetSystemPrometheusRegistry().Gather()
+		assert.NoError(t, err)
+		for _, mf := range mfs {
+			if mf.GetName() != handler.MetricConsumerRequests {
+				continue
+			}
+			for _, m := range mf.GetMetric() {
+				labels := map[string]string{}
+				for _, l := range m.GetLabel() {
+					labels[l.GetName()] = l.GetValue()
+				}
+				if labels["service"] == "profileService" && labels["operation"] == operation && labels["code"] == "200" {
+					return m.GetCounter().GetValue()
+				}
+			}
+		}
+		return 0
+	}
+
+	send("GetUserProfile")
+	send("GetUserProfile")
+	assert.Equal(t, float64(2), count("GetUserProfile"))
+	assert.Equal(t, float64(0), count("/users/42/profile"))
+	send("")
+	assert.Equal(t, float64(1), count("/users/42/profile"), "default is operation id")
+
+	spans := tracer.FinishedSpans()
+	if assert.Len(t, spans, 3) {
+		assert.Equal(t, "GetUserProfile", spans[0].OperationName)
+		assert.Equal(t, "/users/42/profile", spans[0].Tag(tracing.HTTPPath), "path is still a tag of span")
+		assert.Equal(t, "/users/42/profile", spans[2].OperationName)
+	}
+}
@@ -15,6 +15,8 @@ const (
 )
 const (
 	MDMark = "mark"
+	// MDOperationName saves logical name of operation, see OperationName
+	MDOperationName = "_Operation_Name"
 )
 
 // Response is invocation response struct
@@ -74,6 +76,15 @@ func (inv *Invocation) GetMark() string {
 	return "none"
 }
 
+// OperationName returns logical name of operation for metrics labels and span names,
+// such as GetUserProfile of a templated path. default is OperationID
+func (inv *Invocation) OperationName() string {
+	if name, ok := inv.Metadata[MDOperationName].(string); ok && name != "" {
+		return name
+	}
+	return inv.OperationID
+}
+
 // Mark marks a invocation, it means the invocation matches a match rule
 // so that governance rule can be applied to invocation with specific mark
 func (inv *Invocation) Mark(matchRuleName string) {
@@ -0,0 +1,75 @@
+package invocation
+
+import (
+	"fmt"
+	"strings"
+)
+
+// MDDecisionTrace is the metadata key of decision trace, it saves *DecisionTrace
+const MDDecisionTrace = "_Decision_Trace"
+
+// kinds of decision event
+const (
+	// EventPick means strategy picked an endpoint
+	EventPick = "pick"
+	// EventStaleInstances means discovery failed and last known instances are used
+	EventStaleInstances = "stale_instances"
+	// EventAttemptFailed means an attempt to endpoint failed
+	EventAttemptFailed = "attempt_failed"
+	// EventRetry means a failed attempt is retried on endpoint
+	EventRetry = "retry"
+	// EventRetryStopped means no more attempt is made, detail tells why
+	EventRetryStopped = "retry_stopped"
+	// EventCircuitOpen means call is rejected by circuit breaker
+	EventCircuitOpen = "circuit_open"
+	// EventSucceeded means an attempt to endpoint succeeded
+	EventSucceeded = "succeeded"
+	// EventFaultInjected means a delay or an abort is injected by fault injection, it is not a real failure
+	EventFaultInjected = "fault_injected"
+)
+
+// DecisionEvent is a decision made or an outcome seen while a call is handled
+type DecisionEvent struct {
+	Kind     string
+	Endpoint string
+	Detail   string
+}
+
+func (e DecisionEvent) String() string {
+	s := e.Kind
+	if e.Endpoint != "" {
+		s += " " + e.Endpoint
+	}
+	if e.Detail != "" {
+		s += ": " + e.Detail
+	}
+	return s
+}
+
+// DecisionTrace is the events of a call in order, it explains why call behaved as it did
+type DecisionTrace struct {
+	Events []DecisionEvent
+}
+
+func (t *DecisionTrace) String() string {
+	events := make([]string, 0, len(t.Events))
+	for _, e := range t.Events {
+		events = append(events, e.String())
+	}
+	return strings.Join(events, ", ")
+}
+
+// Traced tells if decision trace is enabled for invocation
+func (inv *Invocation) Traced() bool {
+	_, ok := inv.Metadata[MDDecisionTrace].(*DecisionTrace)
+	return ok
+}
+
+// Trace records an event if decision trace is enabled for invocation, detail is formatted by fmt.Sprint
+func (inv *Invocation) Trace(kind, endpoint string, detail ...interface{}) {
+	t, ok := inv.Metadata[MDDecisionTrace].(*DecisionTrace)
+	if !ok {
+		return
+	}
+	t.Events = append(t.Events, DecisionEvent{Kind: kind, Endpoint: endpoint, Detail: fmt.Sprint(detail...)})
+}
@@ -0,0 +1,115 @@
+package loadbalancer
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/go-chassis/go-chassis/v2/core/common"
+	"github.com/go-chassis/go-chassis/v2/core/invocation"
+	"github.com/go-chassis/go-chassis/v2/core/registry"
+	"github.com/go-chassis/openlog"
+)
+
+// ErrDiscoveryTimeout means registry did not return instances before timeout or context deadline
+var ErrDiscoveryTimeout = LBError{Message: "discovery timeout"}
+
+// knownInstances is the last instance list which discovery returned
+type knownInstances struct {
+	instances []*registry.MicroServiceInstance
+	updated   time.Time
+}
+
+var (
+	lastKnown   = make(map[string]knownInstances)
+	lastKnownMu sync.RWMutex
+)
+
+type discoveryResult struct {
+	instances []*registry.MicroServiceInstance
+	err       error
+}
+
+// findInstances query instances of a service, it honors discovery timeout and context deadline of invocation.
+// serviceKey is used to save last known instances
+func findInstances(i *invocation.Invocation, serviceKey string) ([]*registry.MicroServiceInstance, error) {
+	ctx := i.Ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	timeout, _ := i.Metadata[common.DiscoveryTimeoutKey].(time.Duration)
+	_, hasDeadline := ctx.Deadline()
+	if timeout <= 0 && !hasDeadline {
+		instances, err := registry.DefaultServiceDiscoveryService.FindMicroServiceInstances(i.SourceServiceID, i.MicroServiceName, i.RouteTags)
+		if err != nil {
+			return fallback(i, serviceKey, err)
+		}
+		saveKnownInstances(serviceKey, instances)
+		recordDiscovery(i.MicroServiceName, serviceKey, instances, nil)
+		observeEndpoints(i.MicroServiceName, serviceKey, instances)
+		return instances, nil
+	}
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	result := make(chan discoveryResult, 1)
+	go func() {
+		instances, err := registry.DefaultServiceDiscoveryService.FindMicroServiceInstances(i.SourceServiceID, i.MicroServiceName, i.RouteTags)
+		result <- discoveryResult{instances: instances, err: err}
+	}()
+	select {
+	case r := <-result:
+		if r.err != nil {
+			return fallback(i, serviceKey, r.err)
+		}
+		saveKnownInstances(serviceKey, r.instances)
+		recordDiscovery(i.MicroServiceName, serviceKey, r.instances, nil)
+		observeEndpoints(i.MicroServiceName, serviceKey, r.instances)
+		return r.instances, nil
+	case <-ctx.Done():
+		return fallback(i, serviceKey, ErrDiscoveryTimeout)
+	}
+}
+
+// fallback returns last known instances if invocation allows stale instances and they are not too old,
+// otherwise it returns the discovery error
+func fallback(i *invocation.Invocation, serviceKey string, err error) ([]*registry.MicroServiceInstance, error) {
+	recordDiscovery(i.MicroServiceName, serviceKey, nil, err)
+	maxAge, _ := i.Metadata[common.StaleInstancesMaxAgeKey].(time.Duration)
+	if maxAge <= 0 {
+		return nil, err
+	}
+	k, ok := getKnownInstances(serviceKey)
+	if !ok {
+		return nil, err
+	}
+	age := time.Since(k.updated)
+	if age > maxAge {
+		openlog.Warn(fmt.Sprintf("last known instances of [%s] expired, age: %s", serviceKey, age))
+		return nil, err
+	}
+	reportStaleInstances(serviceKey, age, err)
+	i.Trace(invocation.EventStaleInstances, "", "age ", age, ", discovery error: ", err)
+	return k.instances, nil
+}
+
+func saveKnownInstances(serviceKey string, instances []*registry.MicroServiceInstance) {
+	if len(instances) == 0 {
+		return
+	}
+	lastKnownMu.Lock()
+	old := lastKnown[serviceKey].instances
+	lastKnown[serviceKey] = knownInstances{instances: instances, updated: time.Now()}
+	lastKnownMu.Unlock()
+	pruneEndpointCounters(old, instances)
+}
+
+func getKnownInstances(serviceKey string) (knownInstances, bool) {
+	lastKnownMu.RLock()
+	k, ok := lastKnown[serviceKey]
+	lastKnownMu.RUnlock()
+	return k, ok
+}
@@ -0,0 +1,125 @@
+package loadbalancer_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/go-chassis/go-chassis/v2/core/common"
+	"github.com/go-chassis/go-chassis/v2/core/invocation"
+	"github.com/go-chassis/go-chassis/v2/core/loadbalancer"
+	"github.com/go-chassis/go-chassis/v2/core/registry"
+	mk "github.com/go-chassis/go-chassis/v2/core/registry/mock"
+	"github.com/go-chassis/go-chassis/v2/pkg/metrics"
+	"github.com/go-chassis/go-chassis/v2/pkg/util/tags"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuildStrategy_DiscoveryTimeout(t *testing.T) {
+	old := registry.DefaultServiceDiscoveryService
+	defer func() { registry.DefaultServiceDiscoveryService = old }()
+	mss := []*registry.MicroServiceInstance{
+		{InstanceID: "ins1", EndpointsMap: map[string]*registry.Endpoint{"rest": {Address: "127.0.0.1:8001"}}},
+	}
+	newInv := func(service string) *invocation.Invocation {
+		inv := invocation.New(context.Background())
+		inv.SourceServiceID = "selfServiceID"
+		inv.MicroServiceName = service
+		inv.RouteTags = utiltags.NewDefaultTag("1.0", "appID")
+		inv.SetMetadata(common.DiscoveryTimeoutKey, 50*time.Millisecond)
+		return inv
+	}
+
+	t.Run("slow discovery hits timeout", func(t *testing.T) {
+		d := &mk.DiscoveryMock{}
+		registry.DefaultServiceDiscoveryService = d
+		d.On("FindMicroServiceInstances", "selfServiceID", "appID", "slowService", "1.0", "").
+			Return(mss, nil).After(time.Second)
+		start := time.Now()
+		_, err := loadbalancer.BuildStrategy(newInv("slowService"), nil)
+		assert.Equal(t, loadbalancer.ErrDiscoveryTimeout, err)
+		assert.True(t, time.Since(start) < 500*time.Millisecond)
+	})
+	t.Run("context deadline is honored", func(t *testing.T) {
+		d := &mk.DiscoveryMock{}
+		registry.DefaultServiceDiscoveryService = d
+		d.On("FindMicroServiceInstances", "selfServiceID", "appID", "slowService", "1.0", "").
+			Return(mss, nil).After(time.Second)
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		inv := newInv("slowService")
+		inv.Ctx = ctx
+		delete(inv.Metadata, common.DiscoveryTimeoutKey)
+		_, err := loadbalancer.BuildStrategy(inv, nil)
+		assert.Equal(t, loadbalancer.ErrDiscoveryTimeout, err)
+	})
+	t.Run("timeout falls back to last known instances", func(t *testing.T) {
+		d := &mk.DiscoveryMock{}
+		registry.DefaultServiceDiscoveryService = d
+		d.On("FindMicroServiceInstances", "selfServiceID", "appID", "cachedService", "1.0", "").
+			Return(mss, nil).Once()
+		d.On("FindMicroServiceInstances", "selfServiceID", "appID", "cachedService", "1.0", "").
+			Return(mss, nil).After(time.Second)
+		_, err := loadbalancer.BuildStrategy(newInv("cachedService"), nil)
+		assert.NoError(t, err)
+
+		inv := newInv("cachedService")
+		_, err = loadbalancer.BuildStrategy(inv, nil)
+		assert.Equal(t, loadbalancer.ErrDiscoveryTimeout, err)
+
+		inv = newInv("cachedService")
+		inv.SetMetadata(common.StaleInstancesMaxAgeKey, time.Minute)
+		s, err := loadbalancer.BuildStrategy(inv, nil)
+		assert.NoError(t, err)
+		ins, err := s.Pick()
+		assert.NoError(t, err)
+		assert.Equal(t, "ins1", ins.InstanceID)
+	})
+}
+
+func TestBuildStrategy_StaleInstancesFallback(t *testing.T) {
+	old := registry.DefaultServiceDiscoveryService
+	defer func() { registry.DefaultServiceDiscoveryService = old }()
+	assert.NoError(t, metrics.Init())
+	mss := []*registry.MicroServiceInstance{
+		{InstanceID: "ins1", EndpointsMap: map[string]*registry.Endpoint{"rest": {Address: "127.0.0.1:8001"}}},
+	}
+	d := &mk.DiscoveryMock{}
+	registry.DefaultServiceDiscoveryService = d
+	d.On("FindMicroServiceInstances", "selfServiceID", "appID", "staleService", "1.0", "").
+		Return(mss, nil).Once()
+	d.On("FindMicroServiceInstances", "selfServiceID", "appID", "staleService", "1.0", "").
+		Return([]*registry.MicroServiceInstance{}, errors.New("registry unavailable"))
+	newInv := func() *invocation.Invocation {
+		inv := invocation.New(context.Background())
+		inv.SourceServiceID = "selfServiceID"
+		inv.MicroServiceName = "staleService"
+		inv.RouteTags = utiltags.NewDefaultTag("1.0", "appID")
+		inv.SetMetadata(common.StaleInstancesMaxAgeKey, 200*time.Millisecond)
+		return inv
+	}
+	_, err := loadbalancer.BuildStrategy(newInv(), nil)
+	assert.NoError(t, err)
+
+	for n := 0; n < 3; n++ {
+		s, err := loadbalancer.BuildStrategy(newInv(), nil)
+		assert.NoError(t, err)
+		ins, err := s.Pick()
+		assert.NoError(t, err)
+		assert.Equal(t, "ins1", ins.InstanceID)
+	}
+	mfs, err := metrics.GetSystemPrometheusRegistry().Gather()
+	assert.NoError(t, err)
+	var used float64
+	for _, mf := range mfs {
+		if mf.GetName() == loadbalancer.MetricStaleInstances {
+			used = mf.GetMetric()[0].GetCounter().GetValue()
+		}
+	}
+	assert.Equal(t, float64(3), used)
+
+	time.Sleep(250 * time.Millisecond)
+	_, err = loadbalancer.BuildStrategy(newInv(), nil)
+	assert.Error(t, err)
+}
@@ -0,0 +1,177 @@
+package loadbalancer_test
+
+import (
+	"context"
+	"testing"
+
+	scregistry "github.com/go-chassis/cari/discovery"
+	"github.com/go-chassis/go-chassis/v2/core/common"
+	"github.com/go-chassis/go-chassis/v2/core/invocation"
+	"github.com/go-chassis/go-chassis/v2/core/loadbalancer"
+	"github.com/go-chassis/go-chassis/v2/core/registry"
+	mk "github.com/go-chassis/go-chassis/v2/core/registry/mock"
+	"github.com/go-chassis/go-chassis/v2/core/registry/servicecenter"
+	"github.com/go-chassis/go-chassis/v2/pkg/util/tags"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFilter_EndpointMetadata(t *testing.T) {
+	old := registry.DefaultServiceDiscoveryService
+	defer func() { registry.DefaultServiceDiscoveryService = old }()
+	mss := []*registry.MicroServiceInstance{
+		servicecenter.ToMicroServiceInstance(&scregistry.MicroServiceInstance{
+			InstanceId: "ins1",
+			Endpoints:  []string{"rest://127.0.0.1:8001"},
+			Properties: map[string]string{"rack": "r1", "capabilities": "gpu"},
+		}),
+		servicecenter.ToMicroServiceInstance(&scregistry.MicroServiceInstance{
+			InstanceId: "ins2",
+			Endpoints:  []string{"rest://127.0.0.1:8002"},
+			Properties: map[string]string{"rack": "r2"},
+		}),
+	}
+	d := &mk.DiscoveryMock{}
+	registry.DefaultServiceDiscoveryService = d
+	d.On("FindMicroServiceInstances", "selfServiceID", "appID", "metaService", "1.0", "").Return(mss, nil)
+
+	loadbalancer.InstallFilter("gpu", func(instances []*registry.MicroServiceInstance,
+		criteria []*loadbalancer.Criteria) []*registry.MicroServiceInstance {
+		var result []*registry.MicroServiceInstance
+		for _, ins := range instances {
+			if v, ok := ins.EndpointsMap["rest"].Meta("capabilities"); ok && v == "gpu" {
+				result = append(result, ins)
+			}
+		}
+		return result
+	})
+	inv := invocation.New(context.Background())
+	inv.SourceServiceID = "selfServiceID"
+	inv.MicroServiceName = "metaService"
+	inv.RouteTags = utiltags.NewDefaultTag("1.0", "appID")
+	inv.Filters = []string{"gpu"}
+	s, err := loadbalancer.BuildStrategy(inv, nil)
+	assert.NoError(t, err)
+	ins, err := s.Pick()
+	assert.NoError(t, err)
+	assert.Equal(t, "ins1", ins.InstanceID)
+	rack, ok := ins.EndpointsMap["rest"].Meta("rack")
+	assert.True(t, ok)
+	assert.Equal(t, "r1", rack)
+	_, ok = ins.EndpointsMap["rest"].Meta("zone")
+	assert.False(t, ok)
+}
+
+func TestFilter_InstancesNotModified(t *testing.T) {
+	old := registry.DefaultServiceDiscoveryService
+	defer func() { registry.DefaultServiceDiscoveryService = old }()
+	mss := []*registry.MicroServiceInstance{
+		{
+			InstanceID:   "ins1",
+			Metadata:     map[string]string{"rack": "r1"},
+			EndpointsMap: map[string]*registry.Endpoint{"rest": {Address: "127.0.0.1:8001"}},
+		},
+	}
+	d := &mk.DiscoveryMock{}
+	registry.DefaultServiceDiscoveryService = d
+	d.On("FindMicroServiceInstances", "selfServiceID", "appID", "plainService", "1.0", "").Return(mss, nil)
+
+	inv := invocation.New(context.Background())
+	inv.SourceServiceID = "selfServiceID"
+	inv.MicroServiceName = "plainService"
+	inv.RouteTags = utiltags.NewDefaultTag("1.0", "appID")
+	s, err := loadbalancer.BuildStrategy(inv, nil)
+	assert.NoError(t, err)
+	ins, err := s.Pick()
+	assert.NoError(t, err)
+	assert.Nil(t, ins.EndpointsMap["rest"].Metadata, "discovered instances are shared by calls, they are not written")
+
+	_, ok := ins.EndpointsMap["highway"].Meta("rack")
+	assert.False(t, ok)
+	var nilIns *registry.MicroServiceInstance
+	_, ok = nilIns.Meta("rack")
+	assert.False(t, ok)
+}
+
+func TestBuildStrategy_Selector(t *testing.T) {
+	old := registry.DefaultServiceDiscoveryService
+	defer func() { registry.DefaultServiceDiscoveryService = old }()
+	mss := []*registry.MicroServiceInstance{
+		{InstanceID: "ins1", EndpointsMap: map[string]*registry.Endpoint{"rest": {Address: "127.0.0.1:8001"}}},
+		{InstanceID: "canary", EndpointsMap: map[string]*registry.Endpoint{"rest": {Address: "127.0.0.1:8002"}}},
+		{InstanceID: "ins3", EndpointsMap: map[string]*registry.Endpoint{"rest": {Address: "127.0.0.1:8003"}}},
+	}
+	d := &mk.DiscoveryMock{}
+	registry.DefaultServiceDiscoveryService = d
+	d.On("FindMicroServiceInstances", "selfServiceID", "appID", "selectorService", "1.0", "").Return(mss, nil)
+	inv := func(selector loadbalancer.Selector) *invocation.Invocation {
+		inv := invocation.New(context.Background())
+		inv.SourceServiceID = "selfServiceID"
+		inv.MicroServiceName = "selectorService"
+		inv.RouteTags = utiltags.NewDefaultTag("1.0", "appID")
+		inv.SetMetadata(common.EndpointSelectorKey, selector)
+		return inv
+	}
+
+	var candidates int
+	canary := func(instances []*registry.MicroServiceInstance) []*registry.MicroServiceInstance {
+		candidates = len(instances)
+		for _, ins := range instances {
+			if ins.InstanceID == "canary" {
+				return []*registry.MicroServiceInstance{ins}
+			}
+		}
+		return nil
+	}
+	s, err := loadbalancer.BuildStrategy(inv(canary), &loadbalancer.RoundRobinStrategy{})
+	assert.NoError(t, err)
+	assert.Equal(t, 3, candidates)
+	for n := 0; n < 5; n++ {
+		ins, err := s.Pick()
+		assert.NoError(t, err)
+		assert.Equal(t, "canary", ins.InstanceID)
+	}
+
+	none := func([]*registry.MicroServiceInstance) []*registry.MicroServiceInstance { return nil }
+	_, err = loadbalancer.BuildStrategy(inv(none), nil)
+	assert.Equal(t, loadbalancer.ErrNoneAvailableInstance, err)
+
+	plain := inv(nil)
+	delete(plain.Metadata, common.EndpointSelectorKey)
+	s, err = loadbalancer.BuildStrategy(plain, &loadbalancer.RoundRobinStrategy{})
+	assert.NoError(t, err)
+	picked := map[string]bool{}
+	for n := 0; n < 3; n++ {
+		ins, _ := s.Pick()
+		picked[ins.InstanceID] = true
+	}
+	assert.Len(t, picked, 3, "selector only applies to its own call")
+}
+
+func TestBuildStrategy_ExplicitEndpoints(t *testing.T) {
+	old := registry.DefaultServiceDiscoveryService
+	defer func() { registry.DefaultServiceDiscoveryService = old }()
+	d := &mk.DiscoveryMock{}
+	registry.DefaultServiceDiscoveryService = d
+	inv := invocation.New(context.Background())
+	inv.SourceServiceID = "selfServiceID"
+	inv.MicroServiceName = "externalService"
+	inv.Protocol = "rest"
+	inv.RouteTags = utiltags.NewDefaultTag("1.0", "appID")
+	inv.SetMetadata(common.ExplicitEndpointsKey, []string{"10.0.0.1:8080", "10.0.0.2:8080"})
+
+	s, err := loadbalancer.BuildStrategy(inv, &loadbalancer.RoundRobinStrategy{})
+	assert.NoError(t, err)
+	picked := map[string]bool{}
+	for n := 0; n < 4; n++ {
+		ins, err := s.Pick()
+		assert.NoError(t, err)
+		picked[ins.EndpointsMap["rest"].Address] = true
+		assert.Equal(t, ins.EndpointsMap["rest"].Address, ins.InstanceID)
+	}
+	assert.Equal(t, map[string]bool{"10.0.0.1:8080": true, "10.0.0.2:8080": true}, picked, "strategy picks among explicit endpoints")
+	assert.Empty(t, d.Calls, "registry is not queried")
+
+	inv.SetMetadata(common.ExplicitEndpointsKey, []string{})
+	_, err = loadbalancer.BuildStrategy(inv, nil)
+	assert.Error(t, err, "empty list has no instance")
+}
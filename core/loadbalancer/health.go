@@ -0,0 +1,94 @@
+package loadbalancer
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+
+	"github.com/go-chassis/go-chassis/v2/core/registry"
+)
+
+// EjectionFailures is the number of successive failures after which an endpoint is ejected,
+// it is not counted as usable by ServiceHealthy until it succeeds again
+var EjectionFailures int64 = 5
+
+// discoveryState is the result of last discovery of a service key
+type discoveryState struct {
+	addrs []string
+	// stale is true if last discovery failed, endpoints are the ones known before
+	stale bool
+}
+
+var (
+	// discoveryStates key is service name, value key is service key
+	discoveryStates   = make(map[string]map[string]*discoveryState)
+	discoveryStatesMu sync.RWMutex
+)
+
+func recordDiscovery(service, serviceKey string, instances []*registry.MicroServiceInstance, err error) {
+	discoveryStatesMu.Lock()
+	defer discoveryStatesMu.Unlock()
+	states, ok := discoveryStates[service]
+	if !ok {
+		states = make(map[string]*discoveryState)
+		discoveryStates[service] = states
+	}
+	if err != nil {
+		if s, ok := states[serviceKey]; ok {
+			s.stale = true
+		} else {
+			states[serviceKey] = &discoveryState{stale: true}
+		}
+		return
+	}
+	s := &discoveryState{}
+	for _, ins := range instances {
+		for _, ep := range ins.EndpointsMap {
+			if ep != nil {
+				s.addrs = append(s.addrs, ep.Address)
+			}
+		}
+	}
+	states[serviceKey] = s
+}
+
+// ServiceHealthy returns true if last discovery of service succeeded and
+// at least one of its endpoints is not ejected, see EjectionFailures.
+// it never queries registry, a service which is not called yet is not healthy
+func ServiceHealthy(service string) bool {
+	discoveryStatesMu.RLock()
+	defer discoveryStatesMu.RUnlock()
+	for _, s := range discoveryStates[service] {
+		if s.stale {
+			continue
+		}
+		for _, addr := range s.addrs {
+			if !ejected(addr) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// resolveError is implemented by errors of resolving host of endpoint, such as rest.DNSError
+type resolveError interface {
+	error
+	NotFound() bool
+}
+
+// ResolveFailure tells if err is caused by resolving host of endpoint,
+// notFound is true if host does not exist, the endpoint is ejected at once by CallFinished.
+// either way retrying the same endpoint is useless, retry goes to another endpoint
+func ResolveFailure(err error) (failed, notFound bool) {
+	var re resolveError
+	if !errors.As(err, &re) {
+		return false, false
+	}
+	return true, re.NotFound()
+}
+
+func ejected(addr string) bool {
+	c := getEndpointCounter(addr)
+	return c != nil && atomic.LoadInt64(&c.failures) >= EjectionFailures
+}
@@ -0,0 +1,104 @@
+package loadbalancer_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/go-chassis/go-chassis/v2/core/invocation"
+	"github.com/go-chassis/go-chassis/v2/core/loadbalancer"
+	"github.com/go-chassis/go-chassis/v2/core/registry"
+	mk "github.com/go-chassis/go-chassis/v2/core/registry/mock"
+	"github.com/go-chassis/go-chassis/v2/pkg/util/tags"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestServiceHealthy(t *testing.T) {
+	old := registry.DefaultServiceDiscoveryService
+	defer func() { registry.DefaultServiceDiscoveryService = old }()
+	mss := []*registry.MicroServiceInstance{
+		{InstanceID: "ins1", EndpointsMap: map[string]*registry.Endpoint{"rest": {Address: "127.0.0.1:9101"}}},
+		{InstanceID: "ins2", EndpointsMap: map[string]*registry.Endpoint{"rest": {Address: "127.0.0.1:9102"}}},
+	}
+	d := &mk.DiscoveryMock{}
+	registry.DefaultServiceDiscoveryService = d
+	d.On("FindMicroServiceInstances", "selfServiceID", "appID", "healthService", "1.0", "").Return(mss, nil)
+	build := func() error {
+		inv := invocation.New(context.Background())
+		inv.SourceServiceID = "selfServiceID"
+		inv.MicroServiceName = "healthService"
+		inv.RouteTags = utiltags.NewDefaultTag("1.0", "appID")
+		_, err := loadbalancer.BuildStrategy(inv, nil)
+		return err
+	}
+	fail := func(addr string) {
+		for n := int64(0); n < loadbalancer.EjectionFailures; n++ {
+			loadbalancer.CallStarted(addr)
+			loadbalancer.CallFinished(addr, errors.New("refused"))
+		}
+	}
+
+	assert.False(t, loadbalancer.ServiceHealthy("healthService"), "service is not discovered yet")
+	assert.NoError(t, build())
+	assert.True(t, loadbalancer.ServiceHealthy("healthService"))
+
+	fail("127.0.0.1:9101")
+	assert.True(t, loadbalancer.ServiceHealthy("healthService"), "one endpoint is still usable")
+	fail("127.0.0.1:9102")
+	assert.False(t, loadbalancer.ServiceHealthy("healthService"), "all endpoints are ejected")
+
+	loadbalancer.CallStarted("127.0.0.1:9102")
+	loadbalancer.CallFinished("127.0.0.1:9102", nil)
+	assert.True(t, loadbalancer.ServiceHealthy("healthService"), "endpoint recovers after success")
+
+	d = &mk.DiscoveryMock{}
+	registry.DefaultServiceDiscoveryService = d
+	d.On("FindMicroServiceInstances", "selfServiceID", "appID", "healthService", "1.0", "").
+		Return([]*registry.MicroServiceInstance(nil), errors.New("registry is down"))
+	assert.Error(t, build())
+	assert.False(t, loadbalancer.ServiceHealthy("healthService"), "discovery is stale")
+}
+
+// resolveErr is an error of resolving host, as rest.DNSError
+type resolveErr struct {
+	notFound bool
+}
+
+func (e resolveErr) Error() string  { return "lookup host" }
+func (e resolveErr) NotFound() bool { return e.notFound }
+
+func TestCallFinished_ResolveFailure(t *testing.T) {
+	old := registry.DefaultServiceDiscoveryService
+	defer func() { registry.DefaultServiceDiscoveryService = old }()
+	mss := []*registry.MicroServiceInstance{
+		{InstanceID: "ins1", EndpointsMap: map[string]*registry.Endpoint{"rest": {Address: "gone.local:9201"}}},
+		{InstanceID: "ins2", EndpointsMap: map[string]*registry.Endpoint{"rest": {Address: "flaky.local:9202"}}},
+	}
+	d := &mk.DiscoveryMock{}
+	registry.DefaultServiceDiscoveryService = d
+	d.On("FindMicroServiceInstances", "selfServiceID", "appID", "dnsService", "1.0", "").Return(mss, nil)
+	inv := invocation.New(context.Background())
+	inv.SourceServiceID = "selfServiceID"
+	inv.MicroServiceName = "dnsService"
+	inv.RouteTags = utiltags.NewDefaultTag("1.0", "appID")
+	_, err := loadbalancer.BuildStrategy(inv, nil)
+	assert.NoError(t, err)
+
+	failed, notFound := loadbalancer.ResolveFailure(fmt.Errorf("dial: %w", resolveErr{notFound: true}))
+	assert.True(t, failed)
+	assert.True(t, notFound)
+	failed, _ = loadbalancer.ResolveFailure(errors.New("refused"))
+	assert.False(t, failed)
+
+	loadbalancer.CallStarted("flaky.local:9202")
+	loadbalancer.CallFinished("flaky.local:9202", resolveErr{notFound: false})
+	loadbalancer.CallStarted("gone.local:9201")
+	loadbalancer.CallFinished("gone.local:9201", resolveErr{notFound: true})
+	assert.True(t, loadbalancer.ServiceHealthy("dnsService"), "temporary failure does not eject endpoint")
+	for n := int64(1); n < loadbalancer.EjectionFailures; n++ {
+		loadbalancer.CallStarted("flaky.local:9202")
+		loadbalancer.CallFinished("flaky.local:9202", resolveErr{notFound: false})
+	}
+	assert.False(t, loadbalancer.ServiceHealthy("dnsService"), "host which does not exist is ejected at once")
+}
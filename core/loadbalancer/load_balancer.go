@@ -7,8 +7,10 @@ import (
 	"sync"
 	"time"
 
+	"github.com/go-chassis/go-chassis/v2/core/common"
 	"github.com/go-chassis/go-chassis/v2/core/invocation"
 	"github.com/go-chassis/go-chassis/v2/core/registry"
+	"github.com/go-chassis/go-chassis/v2/pkg/util"
 	"github.com/go-chassis/go-chassis/v2/pkg/util/tags"
 	"github.com/go-chassis/openlog"
 )
@@ -24,6 +26,7 @@ const StrategyLatency = "WeightedResponse"
 // constant strings for load balance variables
 const (
 	StrategyRoundRobin        = "RoundRobin"
+	StrategySortedRoundRobin  = "SortedRoundRobin"
 	StrategyRandom            = "Random"
 	StrategySessionStickiness = "SessionStickiness"
 
@@ -64,7 +67,18 @@ func BuildStrategy(i *invocation.Invocation,
 
 	}
 
-	instances, err := registry.DefaultServiceDiscoveryService.FindMicroServiceInstances(i.SourceServiceID, i.MicroServiceName, i.RouteTags)
+	serviceKey := strings.Join([]string{i.MicroServiceName, i.RouteTags.String()}, "|")
+	var instances []*registry.MicroServiceInstance
+	var err error
+	if addrs, ok := i.Metadata[common.ExplicitEndpointsKey].([]string); ok {
+		instances = explicitInstances(i, addrs)
+	} else {
+		instances, err = findInstances(i, serviceKey)
+	}
+	if err == ErrDiscoveryTimeout {
+		openlog.Error(fmt.Sprintf("Lb err: %s, key: %s(%v)", err, i.MicroServiceName, i.RouteTags))
+		return nil, err
+	}
 	if err != nil {
 		lbErr := LBError{err.Error()}
 		openlog.Error(fmt.Sprintf("Lb err: %s", err))
@@ -86,23 +100,69 @@ func BuildStrategy(i *invocation.Invocation,
 		}
 	}
 
+	if selector, ok := i.Metadata[common.EndpointSelectorKey].(Selector); ok {
+		if instances = selector(instances); len(instances) == 0 {
+			openlog.Error(fmt.Sprintf("selector of call returns no instance, key: %s(%v)", i.MicroServiceName, i.RouteTags))
+			return nil, ErrNoneAvailableInstance
+		}
+	}
+
 	if len(instances) == 0 {
 		lbErr := LBError{fmt.Sprintf("No available instance, key: %s(%v)", i.MicroServiceName, i.RouteTags)}
 		openlog.Error(lbErr.Error())
 		return nil, lbErr
 	}
 
-	serviceKey := strings.Join([]string{i.MicroServiceName, i.RouteTags.String()}, "|")
+	if sampler, ok := s.(Sampler); ok && sampler.ScansAllInstances() {
+		k, _ := i.Metadata[common.PickSampleSizeKey].(int)
+		instances = Sample(instances, k)
+	}
 	s.ReceiveData(i, instances, serviceKey)
 	return s, nil
 }
 
+// explicitInstances makes an instance for each address, so that strategy picks from them without discovery,
+// instance id is the address
+func explicitInstances(i *invocation.Invocation, addrs []string) []*registry.MicroServiceInstance {
+	protocol := i.Protocol
+	if protocol == "" {
+		protocol = common.ProtocolRest
+	}
+	key := util.GenProtoEndPoint(protocol, i.PortName)
+	instances := make([]*registry.MicroServiceInstance, 0, len(addrs))
+	for _, addr := range addrs {
+		instances = append(instances, &registry.MicroServiceInstance{
+			InstanceID:   addr,
+			EndpointsMap: map[string]*registry.Endpoint{key: {Address: addr}},
+		})
+	}
+	return instances
+}
+
+// Selector receives instances which pass filters, and returns instances for strategy to pick from,
+// it can reorder, filter or add instances for one call, see common.EndpointSelectorKey
+type Selector func(candidates []*registry.MicroServiceInstance) []*registry.MicroServiceInstance
+
 // Strategy is load balancer algorithm , call Pick to return one instance
 type Strategy interface {
 	ReceiveData(inv *invocation.Invocation, instances []*registry.MicroServiceInstance, serviceKey string)
 	Pick() (*registry.MicroServiceInstance, error)
 }
 
+// Sampler is implemented by strategies which scan every instance for each pick, such as latency and score strategies.
+// if pickSampleSize is set in load balancing config of service, such a strategy receives a random sample of instances
+// for each pick, see Sample. strategies which keep state over a stable list of instances, such as round robin
+// and session stickiness, do not implement it
+type Sampler interface {
+	ScansAllInstances() bool
+}
+
+// Peeker is implemented by strategies which are able to tell the instance Pick will return,
+// without changing strategy state, it is used to resolve an endpoint without sending request
+type Peeker interface {
+	Peek() (*registry.MicroServiceInstance, error)
+}
+
 //Criteria is rule for filter
 type Criteria struct {
 	Key      string
@@ -118,6 +178,7 @@ func Enable(strategyName string) error {
 	openlog.Info("Enable LoadBalancing")
 	InstallStrategy(StrategyRandom, newRandomStrategy)
 	InstallStrategy(StrategyRoundRobin, newRoundRobinStrategy)
+	InstallStrategy(StrategySortedRoundRobin, newSortedRoundRobinStrategy)
 	InstallStrategy(StrategySessionStickiness, newSessionStickinessStrategy)
 
 	if strategyName == "" {
@@ -153,6 +214,12 @@ func BuildKey(microServiceName, tags, protocol string) string {
 
 // SetLatency for a instance, it only save latest 10 stats for instance's protocol
 func SetLatency(latency time.Duration, addr, microServiceName string, tags utiltags.Tags, protocol string) {
+	SetLatencyWithDecay(latency, 0, addr, microServiceName, tags, protocol)
+}
+
+// SetLatencyWithDecay saves latency of a instance like SetLatency, and averages latencies of it
+// by exponentially weighted moving average with the decay, see ProtocolStats.Decay
+func SetLatencyWithDecay(latency time.Duration, decay float64, addr, microServiceName string, tags utiltags.Tags, protocol string) {
 	key := BuildKey(microServiceName, tags.String(), protocol)
 
 	LatencyMapRWMutex.RLock()
@@ -164,13 +231,15 @@ func SetLatency(latency time.Duration, addr, microServiceName string, tags utilt
 	exist := false
 	for _, v := range stats {
 		if v.Addr == addr {
+			v.Decay = decay
 			v.SaveLatency(latency)
 			exist = true
 		}
 	}
 	if !exist {
 		ps := &ProtocolStats{
-			Addr: addr,
+			Addr:  addr,
+			Decay: decay,
 		}
 
 		ps.SaveLatency(latency)
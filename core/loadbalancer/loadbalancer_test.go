@@ -71,12 +71,12 @@ func TestBuildStrategy(t *testing.T) {
 			Status:     "UP",
 			EndpointsMap: map[string]*registry.Endpoint{
 				"rest": {
-					false,
-					"127.0.0.1",
+					SSLEnabled: false,
+					Address:    "127.0.0.1",
 				},
 				"highway": {
-					false,
-					"10.0.0.3:8080",
+					SSLEnabled: false,
+					Address:    "10.0.0.3:8080",
 				},
 			},
 		},
@@ -86,8 +86,8 @@ func TestBuildStrategy(t *testing.T) {
 			Status:     "UP",
 			EndpointsMap: map[string]*registry.Endpoint{
 				"highway": {
-					false,
-					"10.0.0.3:8080",
+					SSLEnabled: false,
+					Address:    "10.0.0.3:8080",
 				},
 			},
 		},
@@ -161,8 +161,8 @@ func BenchmarkDefaultSelector_Select(b *testing.B) {
 			Status:   "UP",
 			EndpointsMap: map[string]*registry.Endpoint{
 				"highway": {
-					false,
-					"10.0.0.4:1234",
+					SSLEnabled: false,
+					Address:    "10.0.0.4:1234",
 				},
 			},
 		},
@@ -171,8 +171,8 @@ func BenchmarkDefaultSelector_Select(b *testing.B) {
 			Status:   "UP",
 			EndpointsMap: map[string]*registry.Endpoint{
 				"highway": {
-					false,
-					"10.0.0.3:1234",
+					SSLEnabled: false,
+					Address:    "10.0.0.3:1234",
 				},
 			},
 		},
@@ -0,0 +1,37 @@
+package loadbalancer
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/go-chassis/go-chassis/v2/pkg/metrics"
+	"github.com/go-chassis/openlog"
+)
+
+// MetricStaleInstances counts how many times last known instances are used because discovery failed
+const MetricStaleInstances = "lb_stale_instances_used_total"
+
+var staleMetricOnce sync.Once
+
+// reportStaleInstances logs and counts usage of last known instances,
+// metric is only reported when metrics registry is initialized
+func reportStaleInstances(serviceKey string, age time.Duration, cause error) {
+	openlog.Warn(fmt.Sprintf("use last known instances of [%s], age: %s, because of [%s]", serviceKey, age, cause))
+	if !metrics.Enabled() {
+		return
+	}
+	staleMetricOnce.Do(func() {
+		err := metrics.CreateCounter(metrics.CounterOpts{
+			Name:   MetricStaleInstances,
+			Help:   "count of using last known instances because discovery failed",
+			Labels: []string{"service"},
+		})
+		if err != nil {
+			openlog.Error(err.Error())
+		}
+	})
+	if err := metrics.CounterAdd(MetricStaleInstances, 1, map[string]string{"service": serviceKey}); err != nil {
+		openlog.Error("can not report stale instances: " + err.Error())
+	}
+}
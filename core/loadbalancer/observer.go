@@ -0,0 +1,106 @@
+package loadbalancer
+
+import (
+	"sync"
+	"time"
+
+	"github.com/go-chassis/go-chassis/v2/core/registry"
+)
+
+// EndpointsObserver is notified when discovery returns a different endpoint set for a service,
+// added and removed are diff to the set of last notification
+type EndpointsObserver func(service string, added, removed []*registry.Endpoint)
+
+// EndpointsChangedDebounce is the interval changes are collected before observers are notified,
+// so that rapid flapping does not cause callback storm
+var EndpointsChangedDebounce = time.Second
+
+var (
+	observers   []EndpointsObserver
+	observersMu sync.RWMutex
+
+	endpointSets   = make(map[string]*endpointSet)
+	endpointSetsMu sync.Mutex
+)
+
+// endpointSet key is endpoint string, it includes address and ssl flag
+type endpointSet struct {
+	notified map[string]*registry.Endpoint
+	current  map[string]*registry.Endpoint
+	pending  bool
+}
+
+// OnEndpointsChanged registers an observer of endpoint set changes
+func OnEndpointsChanged(o EndpointsObserver) {
+	observersMu.Lock()
+	observers = append(observers, o)
+	observersMu.Unlock()
+}
+
+func getObservers() []EndpointsObserver {
+	observersMu.RLock()
+	defer observersMu.RUnlock()
+	return observers
+}
+
+// observeEndpoints records endpoints discovery returned, serviceKey is service name with tags
+func observeEndpoints(service, serviceKey string, instances []*registry.MicroServiceInstance) {
+	if len(getObservers()) == 0 {
+		return
+	}
+	current := make(map[string]*registry.Endpoint)
+	for _, ins := range instances {
+		for _, ep := range ins.EndpointsMap {
+			if ep != nil {
+				current[ep.GenEndpoint()] = ep
+			}
+		}
+	}
+	endpointSetsMu.Lock()
+	defer endpointSetsMu.Unlock()
+	s, ok := endpointSets[serviceKey]
+	if !ok {
+		s = &endpointSet{notified: map[string]*registry.Endpoint{}}
+		endpointSets[serviceKey] = s
+	}
+	s.current = current
+	if s.pending {
+		return
+	}
+	if added, removed := diffEndpoints(s.notified, current); len(added) == 0 && len(removed) == 0 {
+		return
+	}
+	s.pending = true
+	time.AfterFunc(EndpointsChangedDebounce, func() {
+		notifyEndpoints(service, serviceKey)
+	})
+}
+
+func notifyEndpoints(service, serviceKey string) {
+	endpointSetsMu.Lock()
+	s := endpointSets[serviceKey]
+	added, removed := diffEndpoints(s.notified, s.current)
+	s.notified = s.current
+	s.pending = false
+	endpointSetsMu.Unlock()
+	if len(added) == 0 && len(removed) == 0 {
+		return
+	}
+	for _, o := range getObservers() {
+		o(service, added, removed)
+	}
+}
+
+func diffEndpoints(old, cur map[string]*registry.Endpoint) (added, removed []*registry.Endpoint) {
+	for k, ep := range cur {
+		if _, ok := old[k]; !ok {
+			added = append(added, ep)
+		}
+	}
+	for k, ep := range old {
+		if _, ok := cur[k]; !ok {
+			removed = append(removed, ep)
+		}
+	}
+	return added, removed
+}
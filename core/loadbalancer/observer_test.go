@@ -0,0 +1,86 @@
+package loadbalancer_test
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-chassis/go-chassis/v2/core/invocation"
+	"github.com/go-chassis/go-chassis/v2/core/loadbalancer"
+	"github.com/go-chassis/go-chassis/v2/core/registry"
+	mk "github.com/go-chassis/go-chassis/v2/core/registry/mock"
+	"github.com/go-chassis/go-chassis/v2/pkg/util/tags"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOnEndpointsChanged(t *testing.T) {
+	old := registry.DefaultServiceDiscoveryService
+	defer func() { registry.DefaultServiceDiscoveryService = old }()
+	oldDebounce := loadbalancer.EndpointsChangedDebounce
+	loadbalancer.EndpointsChangedDebounce = 50 * time.Millisecond
+	defer func() { loadbalancer.EndpointsChangedDebounce = oldDebounce }()
+
+	newIns := func(id, addr string) *registry.MicroServiceInstance {
+		return &registry.MicroServiceInstance{InstanceID: id,
+			EndpointsMap: map[string]*registry.Endpoint{"rest": {Address: addr}}}
+	}
+	a, b := newIns("a", "127.0.0.1:8001"), newIns("b", "127.0.0.1:8002")
+	d := &mk.DiscoveryMock{}
+	registry.DefaultServiceDiscoveryService = d
+	for _, mss := range [][]*registry.MicroServiceInstance{
+		{a}, {a, b}, {a}, // flapping inside one debounce interval
+		{a, b}, {b},
+	} {
+		d.On("FindMicroServiceInstances", "selfServiceID", "appID", "observedService", "1.0", "").
+			Return(mss, nil).Once()
+	}
+
+	type change struct {
+		added, removed []string
+	}
+	var mu sync.Mutex
+	var changes []change
+	addrs := func(eps []*registry.Endpoint) []string {
+		var r []string
+		for _, ep := range eps {
+			r = append(r, ep.Address)
+		}
+		sort.Strings(r)
+		return r
+	}
+	loadbalancer.OnEndpointsChanged(func(service string, added, removed []*registry.Endpoint) {
+		if service != "observedService" {
+			return
+		}
+		mu.Lock()
+		changes = append(changes, change{addrs(added), addrs(removed)})
+		mu.Unlock()
+	})
+	discover := func() {
+		inv := invocation.New(context.Background())
+		inv.SourceServiceID = "selfServiceID"
+		inv.MicroServiceName = "observedService"
+		inv.RouteTags = utiltags.NewDefaultTag("1.0", "appID")
+		_, err := loadbalancer.BuildStrategy(inv, nil)
+		assert.NoError(t, err)
+	}
+	getChanges := func() []change {
+		time.Sleep(150 * time.Millisecond)
+		mu.Lock()
+		defer mu.Unlock()
+		r := changes
+		changes = nil
+		return r
+	}
+
+	discover()
+	discover()
+	discover()
+	assert.Equal(t, []change{{added: []string{"127.0.0.1:8001"}}}, getChanges(), "flapping is debounced")
+	discover()
+	assert.Equal(t, []change{{added: []string{"127.0.0.1:8002"}}}, getChanges())
+	discover()
+	assert.Equal(t, []change{{removed: []string{"127.0.0.1:8001"}}}, getChanges())
+}
@@ -2,10 +2,12 @@ package loadbalancer
 
 import (
 	"math/rand"
+	"sort"
 	"sync"
 
 	"github.com/go-chassis/go-chassis/v2/core/invocation"
 	"github.com/go-chassis/go-chassis/v2/core/registry"
+	"github.com/go-chassis/go-chassis/v2/pkg/util"
 )
 
 // RoundRobinStrategy is strategy
@@ -30,14 +32,80 @@ func (r *RoundRobinStrategy) Pick() (*registry.MicroServiceInstance, error) {
 		return nil, ErrNoneAvailableInstance
 	}
 
-	i := pick(r.key)
+	i := pick(r.key, rand.Int)
 	return r.instances[i%len(r.instances)], nil
 }
 
+//Peek return the instance which next Pick will return, without moving the round robin index
+func (r *RoundRobinStrategy) Peek() (*registry.MicroServiceInstance, error) {
+	if len(r.instances) == 0 {
+		return nil, ErrNoneAvailableInstance
+	}
+
+	i := peek(r.key, rand.Int)
+	return r.instances[i%len(r.instances)], nil
+}
+
+// SortedRoundRobinStrategy is round robin over instances sorted by endpoint address,
+// it starts from the first one, so the sequence is same for the same instances whatever order registry returns
+type SortedRoundRobinStrategy struct {
+	RoundRobinStrategy
+}
+
+func newSortedRoundRobinStrategy() Strategy {
+	return &SortedRoundRobinStrategy{}
+}
+
+//ReceiveData receive data, instances are sorted by address of endpoint of invocation protocol
+func (r *SortedRoundRobinStrategy) ReceiveData(inv *invocation.Invocation, instances []*registry.MicroServiceInstance, serviceKey string) {
+	protocolServer := ""
+	if inv != nil {
+		protocolServer = util.GenProtoEndPoint(inv.Protocol, inv.PortName)
+	}
+	addr := func(ins *registry.MicroServiceInstance) string {
+		if ep, ok := ins.EndpointsMap[protocolServer]; ok && ep != nil {
+			return ep.Address
+		}
+		return ins.InstanceID
+	}
+	sorted := make([]*registry.MicroServiceInstance, len(instances))
+	copy(sorted, instances)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return addr(sorted[i]) < addr(sorted[j])
+	})
+	r.instances = sorted
+	r.key = StrategySortedRoundRobin + "|" + serviceKey
+}
+
+//Pick return instance
+func (r *SortedRoundRobinStrategy) Pick() (*registry.MicroServiceInstance, error) {
+	if len(r.instances) == 0 {
+		return nil, ErrNoneAvailableInstance
+	}
+
+	i := pick(r.key, firstIdx)
+	return r.instances[i%len(r.instances)], nil
+}
+
+//Peek return the instance which next Pick will return, without moving the round robin index
+func (r *SortedRoundRobinStrategy) Peek() (*registry.MicroServiceInstance, error) {
+	if len(r.instances) == 0 {
+		return nil, ErrNoneAvailableInstance
+	}
+
+	i := peek(r.key, firstIdx)
+	return r.instances[i%len(r.instances)], nil
+}
+
+func firstIdx() int {
+	return 0
+}
+
 var rrIdxMap = make(map[string]int)
 var mu sync.RWMutex
 
-func pick(key string) int {
+// pick returns round robin index of key and moves it, start gives the index of a new key
+func pick(key string, start func() int) int {
 	mu.RLock()
 	i, ok := rrIdxMap[key]
 	if !ok {
@@ -45,7 +113,7 @@ func pick(key string) int {
 		mu.Lock()
 		i, ok = rrIdxMap[key]
 		if !ok {
-			i = rand.Int()
+			i = start()
 			rrIdxMap[key] = i
 		}
 		rrIdxMap[key]++
@@ -59,3 +127,20 @@ func pick(key string) int {
 	mu.Unlock()
 	return i
 }
+
+func peek(key string, start func() int) int {
+	mu.RLock()
+	i, ok := rrIdxMap[key]
+	mu.RUnlock()
+	if ok {
+		return i
+	}
+	mu.Lock()
+	i, ok = rrIdxMap[key]
+	if !ok {
+		i = start()
+		rrIdxMap[key] = i
+	}
+	mu.Unlock()
+	return i
+}
@@ -0,0 +1,28 @@
+package loadbalancer
+
+import (
+	"math/rand"
+
+	"github.com/go-chassis/go-chassis/v2/core/registry"
+)
+
+// Sample returns k distinct instances chosen at random, instances are returned as they are if there are not more than k.
+// its cost depends on k only, not on number of instances
+func Sample(instances []*registry.MicroServiceInstance, k int) []*registry.MicroServiceInstance {
+	if k <= 0 || len(instances) <= k {
+		return instances
+	}
+	// Floyd's algorithm, each subset of size k is equally likely
+	n := len(instances)
+	chosen := make(map[int]bool, k)
+	sample := make([]*registry.MicroServiceInstance, 0, k)
+	for j := n - k; j < n; j++ {
+		i := rand.Intn(j + 1)
+		if chosen[i] {
+			i = j
+		}
+		chosen[i] = true
+		sample = append(sample, instances[i])
+	}
+	return sample
+}
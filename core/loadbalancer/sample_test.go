@@ -0,0 +1,135 @@
+package loadbalancer_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/go-chassis/go-chassis/v2/core/common"
+	"github.com/go-chassis/go-chassis/v2/core/invocation"
+	"github.com/go-chassis/go-chassis/v2/core/loadbalancer"
+	"github.com/go-chassis/go-chassis/v2/core/registry"
+	"github.com/go-chassis/go-chassis/v2/pkg/util/tags"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSample(t *testing.T) {
+	mss := instancesOf(10)
+	assert.Equal(t, mss, loadbalancer.Sample(mss, 0), "0 means all instances")
+	assert.Equal(t, mss, loadbalancer.Sample(mss, 10))
+	for n := 0; n < 100; n++ {
+		s := loadbalancer.Sample(mss, 3)
+		assert.Len(t, s, 3)
+		assert.Len(t, distinct(s), 3, "instances in sample are distinct")
+	}
+}
+
+func TestBuildStrategy_PickSampleSize(t *testing.T) {
+	var scored []string
+	// endpoint with higher port has higher score
+	loadbalancer.InstallScoreStrategy("HighPort", func(ep *registry.Endpoint, s loadbalancer.Signals) float64 {
+		scored = append(scored, ep.Address)
+		var port float64
+		fmt.Sscanf(ep.Address, "10.0.0.1:%v", &port)
+		return port
+	})
+	f, err := loadbalancer.GetStrategyPlugin("HighPort")
+	assert.NoError(t, err)
+
+	var addrs []string
+	for port := 8001; port <= 8005; port++ {
+		addrs = append(addrs, fmt.Sprintf("10.0.0.1:%d", port))
+	}
+	inv := invocation.New(context.Background())
+	inv.MicroServiceName = "sampleService"
+	inv.Protocol = "rest"
+	inv.RouteTags = utiltags.NewDefaultTag("1.0", "appID")
+	inv.SetMetadata(common.ExplicitEndpointsKey, addrs)
+	inv.SetMetadata(common.PickSampleSizeKey, 2)
+
+	picked := map[string]bool{}
+	for n := 0; n < 200; n++ {
+		scored = nil
+		s, err := loadbalancer.BuildStrategy(inv, f())
+		assert.NoError(t, err)
+		ins, err := s.Pick()
+		assert.NoError(t, err)
+		addr := ins.EndpointsMap["rest"].Address
+		if assert.Len(t, scored, 2, "only sampled endpoints are scored") {
+			assert.NotEqual(t, scored[0], scored[1])
+			best := scored[0]
+			if scored[1] > best {
+				best = scored[1]
+			}
+			assert.Equal(t, best, addr, "endpoint with highest score in sample is picked")
+		}
+		picked[addr] = true
+	}
+	assert.NotContains(t, picked, "10.0.0.1:8001", "lowest endpoint never wins a sample")
+	assert.Len(t, picked, 4, "any other endpoint may win a sample")
+}
+
+func TestBuildStrategy_PickSampleSize_RoundRobin(t *testing.T) {
+	var addrs []string
+	for port := 8001; port <= 8005; port++ {
+		addrs = append(addrs, fmt.Sprintf("10.0.0.2:%d", port))
+	}
+	inv := invocation.New(context.Background())
+	inv.MicroServiceName = "sampleRoundRobinService"
+	inv.Protocol = "rest"
+	inv.RouteTags = utiltags.NewDefaultTag("1.0", "appID")
+	inv.SetMetadata(common.ExplicitEndpointsKey, addrs)
+	inv.SetMetadata(common.PickSampleSizeKey, 2)
+	s := &loadbalancer.RoundRobinStrategy{}
+	picked := map[string]bool{}
+	for n := 0; n < len(addrs); n++ {
+		_, err := loadbalancer.BuildStrategy(inv, s)
+		assert.NoError(t, err)
+		ins, err := s.Pick()
+		assert.NoError(t, err)
+		picked[ins.EndpointsMap["rest"].Address] = true
+	}
+	assert.Len(t, picked, len(addrs), "round robin rotates over all instances, they are not sampled")
+}
+
+// BenchmarkScoreStrategy_Pick shows that with sampling cost of a pick does not grow with number of instances
+func BenchmarkScoreStrategy_Pick(b *testing.B) {
+	loadbalancer.InstallScoreStrategy("LeastInFlight", func(ep *registry.Endpoint, s loadbalancer.Signals) float64 {
+		return -float64(s.InFlight)
+	})
+	f, _ := loadbalancer.GetStrategyPlugin("LeastInFlight")
+	inv := &invocation.Invocation{MicroServiceName: "benchService", Protocol: "rest",
+		RouteTags: utiltags.NewDefaultTag("1.0", "appID")}
+	for _, n := range []int{100, 10000, 100000} {
+		mss := instancesOf(n)
+		for _, k := range []int{0, 2, 10} {
+			b.Run(fmt.Sprintf("instances=%d/sample=%d", n, k), func(b *testing.B) {
+				s := f()
+				for i := 0; i < b.N; i++ {
+					s.ReceiveData(inv, loadbalancer.Sample(mss, k), "benchService")
+					if _, err := s.Pick(); err != nil {
+						b.Fatal(err)
+					}
+				}
+			})
+		}
+	}
+}
+
+func instancesOf(n int) []*registry.MicroServiceInstance {
+	mss := make([]*registry.MicroServiceInstance, 0, n)
+	for i := 0; i < n; i++ {
+		addr := fmt.Sprintf("10.%d.%d.%d:8080", i>>16&0xff, i>>8&0xff, i&0xff)
+		mss = append(mss, &registry.MicroServiceInstance{InstanceID: addr,
+			EndpointsMap: map[string]*registry.Endpoint{"rest": {Address: addr}}})
+	}
+	return mss
+}
+
+func distinct(mss []*registry.MicroServiceInstance) map[string]bool {
+	m := make(map[string]bool)
+	for _, ins := range mss {
+		m[ins.InstanceID] = true
+	}
+	return m
+}
@@ -0,0 +1,216 @@
+package loadbalancer
+
+import (
+	"math/rand"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-chassis/go-chassis/v2/core/invocation"
+	"github.com/go-chassis/go-chassis/v2/core/registry"
+	"github.com/go-chassis/go-chassis/v2/pkg/util"
+)
+
+// Signals are the stats of an endpoint which a Scorer can use to score it
+type Signals struct {
+	InstanceID string
+	// InFlight is the number of requests which are being sent to the endpoint
+	InFlight int64
+	// AvgLatency is the average of recent latencies, it is 0 if there is no record
+	AvgLatency time.Duration
+	// Latencies are recent latencies, at most 10 records
+	Latencies []time.Duration
+	// SuccessiveFailures is the number of failed calls since last success
+	SuccessiveFailures int64
+	// Metadata is the metadata of instance
+	Metadata map[string]string
+}
+
+// Scorer scores an endpoint, the endpoint with highest score is picked
+type Scorer func(ep *registry.Endpoint, s Signals) float64
+
+var scoreStrategies sync.Map
+
+// InstallScoreStrategy installs a strategy which picks the endpoint with highest score of scorer,
+// ties are broken randomly
+func InstallScoreStrategy(name string, scorer Scorer) {
+	scoreStrategies.Store(name, true)
+	InstallStrategy(name, func() Strategy {
+		return &ScoreStrategy{scorer: scorer}
+	})
+}
+
+// NeedLatency tells if latency of endpoints should be recorded for strategy
+func NeedLatency(strategy string) bool {
+	if strategy == StrategyLatency {
+		return true
+	}
+	_, ok := scoreStrategies.Load(strategy)
+	return ok
+}
+
+// ScoreStrategy picks the instance which endpoint has the highest score
+type ScoreStrategy struct {
+	scorer    Scorer
+	instances []*registry.MicroServiceInstance
+	protocol  string
+	statsKey  string
+}
+
+// ReceiveData receive data
+func (r *ScoreStrategy) ReceiveData(inv *invocation.Invocation, instances []*registry.MicroServiceInstance, serviceKey string) {
+	r.instances = instances
+	if inv.Protocol != "" {
+		r.protocol = util.GenProtoEndPoint(inv.Protocol, inv.PortName)
+	}
+	r.statsKey = BuildKey(inv.MicroServiceName, inv.RouteTags.String(), inv.Protocol)
+}
+
+// ScansAllInstances tells instances are sampled for the strategy, see Sampler
+func (r *ScoreStrategy) ScansAllInstances() bool {
+	return true
+}
+
+// Pick return instance
+func (r *ScoreStrategy) Pick() (*registry.MicroServiceInstance, error) {
+	if len(r.instances) == 0 {
+		return nil, ErrNoneAvailableInstance
+	}
+	latencies := r.latencies()
+	var best []*registry.MicroServiceInstance
+	var bestScore float64
+	for _, ins := range r.instances {
+		ep := r.endpoint(ins)
+		if ep == nil {
+			continue
+		}
+		s := Signals{
+			InstanceID: ins.InstanceID,
+			Metadata:   ins.Metadata,
+		}
+		if ps, ok := latencies[ep.Address]; ok {
+			s.AvgLatency = ps.AvgLatency
+			s.Latencies = ps.Latency
+		}
+		if c := getEndpointCounter(ep.Address); c != nil {
+			s.InFlight = atomic.LoadInt64(&c.inFlight)
+			s.SuccessiveFailures = atomic.LoadInt64(&c.failures)
+		}
+		score := r.scorer(ep, s)
+		switch {
+		case len(best) == 0 || score > bestScore:
+			best = []*registry.MicroServiceInstance{ins}
+			bestScore = score
+		case score == bestScore:
+			best = append(best, ins)
+		}
+	}
+	if len(best) == 0 {
+		return nil, ErrNoneAvailableInstance
+	}
+	return best[rand.Intn(len(best))], nil
+}
+
+func (r *ScoreStrategy) endpoint(ins *registry.MicroServiceInstance) *registry.Endpoint {
+	if r.protocol != "" {
+		return ins.EndpointsMap[r.protocol]
+	}
+	for _, ep := range ins.EndpointsMap {
+		return ep
+	}
+	return nil
+}
+
+// latencies copies recent latencies of service, key is address
+func (r *ScoreStrategy) latencies() map[string]ProtocolStats {
+	LatencyMapRWMutex.RLock()
+	defer LatencyMapRWMutex.RUnlock()
+	stats := ProtocolStatsMap[r.statsKey]
+	m := make(map[string]ProtocolStats, len(stats))
+	for _, ps := range stats {
+		c := ProtocolStats{Addr: ps.Addr, Latency: append([]time.Duration(nil), ps.Latency...)}
+		c.CalculateAverageLatency()
+		m[ps.Addr] = c
+	}
+	return m
+}
+
+type endpointCounter struct {
+	inFlight int64
+	failures int64
+}
+
+// endpointCounters key is address, value is *endpointCounter
+var endpointCounters sync.Map
+
+func getEndpointCounter(addr string) *endpointCounter {
+	c, ok := endpointCounters.Load(addr)
+	if !ok {
+		return nil
+	}
+	return c.(*endpointCounter)
+}
+
+// CallStarted records a request is being sent to addr
+func CallStarted(addr string) {
+	c, _ := endpointCounters.LoadOrStore(addr, &endpointCounter{})
+	atomic.AddInt64(&c.(*endpointCounter).inFlight, 1)
+}
+
+// CallFinished records a request to addr is finished, failures are counted until a success
+func CallFinished(addr string, err error) {
+	counter := getEndpointCounter(addr)
+	if counter == nil {
+		// endpoint is removed while request is in flight
+		return
+	}
+	atomic.AddInt64(&counter.inFlight, -1)
+	if err != nil {
+		failures := atomic.AddInt64(&counter.failures, 1)
+		if _, notFound := ResolveFailure(err); notFound && failures < EjectionFailures {
+			// host does not exist, it will not recover by retrying
+			atomic.StoreInt64(&counter.failures, EjectionFailures)
+		}
+		return
+	}
+	atomic.StoreInt64(&counter.failures, 0)
+}
+
+// pruneEndpointCounters removes counters of endpoints which are in old instances of a service but not in current ones,
+// counters which have requests in flight are kept
+func pruneEndpointCounters(old, current []*registry.MicroServiceInstance) {
+	if sameInstances(old, current) {
+		return
+	}
+	kept := make(map[string]bool)
+	for _, ins := range current {
+		for _, ep := range ins.EndpointsMap {
+			if ep != nil {
+				kept[ep.Address] = true
+			}
+		}
+	}
+	for _, ins := range old {
+		for _, ep := range ins.EndpointsMap {
+			if ep == nil || kept[ep.Address] {
+				continue
+			}
+			if c := getEndpointCounter(ep.Address); c != nil && atomic.LoadInt64(&c.inFlight) == 0 {
+				endpointCounters.Delete(ep.Address)
+			}
+		}
+	}
+}
+
+// sameInstances tells if discovery returns the same instances as last time, registry cache keeps them unchanged
+func sameInstances(old, current []*registry.MicroServiceInstance) bool {
+	if len(old) != len(current) {
+		return false
+	}
+	for n := range old {
+		if old[n] != current[n] {
+			return false
+		}
+	}
+	return true
+}
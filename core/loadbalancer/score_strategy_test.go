@@ -0,0 +1,125 @@
+package loadbalancer_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/go-chassis/go-chassis/v2/core/invocation"
+	"github.com/go-chassis/go-chassis/v2/core/loadbalancer"
+	"github.com/go-chassis/go-chassis/v2/core/registry"
+	mk "github.com/go-chassis/go-chassis/v2/core/registry/mock"
+	"github.com/go-chassis/go-chassis/v2/pkg/util/tags"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScoreStrategy(t *testing.T) {
+	lowLatency := func(ep *registry.Endpoint, s loadbalancer.Signals) float64 {
+		if s.AvgLatency == 0 {
+			return 0
+		}
+		return 1 / s.AvgLatency.Seconds()
+	}
+	loadbalancer.InstallScoreStrategy("LowLatency", lowLatency)
+	assert.True(t, loadbalancer.NeedLatency("LowLatency"))
+	assert.False(t, loadbalancer.NeedLatency(loadbalancer.StrategyRoundRobin))
+
+	mss := []*registry.MicroServiceInstance{
+		{InstanceID: "ins1", EndpointsMap: map[string]*registry.Endpoint{"rest": {Address: "127.0.0.1:9001"}}},
+		{InstanceID: "ins2", EndpointsMap: map[string]*registry.Endpoint{"rest": {Address: "127.0.0.1:9002"}}},
+		{InstanceID: "ins3", EndpointsMap: map[string]*registry.Endpoint{"rest": {Address: "127.0.0.1:9003"}}},
+	}
+	tags := utiltags.NewDefaultTag("1.0", "appID")
+	loadbalancer.SetLatency(30*time.Millisecond, "127.0.0.1:9001", "scoreService", tags, "rest")
+	loadbalancer.SetLatency(5*time.Millisecond, "127.0.0.1:9002", "scoreService", tags, "rest")
+	loadbalancer.SetLatency(80*time.Millisecond, "127.0.0.1:9003", "scoreService", tags, "rest")
+
+	newStrategy := func() loadbalancer.Strategy {
+		f, err := loadbalancer.GetStrategyPlugin("LowLatency")
+		assert.NoError(t, err)
+		s := f()
+		s.ReceiveData(&invocation.Invocation{MicroServiceName: "scoreService", Protocol: "rest", RouteTags: tags},
+			mss, "scoreService")
+		return s
+	}
+	t.Run("lowest latency endpoint is picked", func(t *testing.T) {
+		for n := 0; n < 10; n++ {
+			ins, err := newStrategy().Pick()
+			assert.NoError(t, err)
+			assert.Equal(t, "ins2", ins.InstanceID)
+		}
+	})
+	t.Run("ties are broken randomly", func(t *testing.T) {
+		for _, addr := range []string{"127.0.0.1:9001", "127.0.0.1:9002"} {
+			loadbalancer.SetLatency(10*time.Millisecond, addr, "tieService", tags, "rest")
+		}
+		f, _ := loadbalancer.GetStrategyPlugin("LowLatency")
+		picked := make(map[string]bool)
+		for n := 0; n < 100; n++ {
+			s := f()
+			s.ReceiveData(&invocation.Invocation{MicroServiceName: "tieService", Protocol: "rest", RouteTags: tags},
+				mss, "tieService")
+			ins, err := s.Pick()
+			assert.NoError(t, err)
+			picked[ins.InstanceID] = true
+		}
+		assert.Equal(t, map[string]bool{"ins1": true, "ins2": true}, picked)
+	})
+	t.Run("in flight and failures are exposed", func(t *testing.T) {
+		var got loadbalancer.Signals
+		loadbalancer.InstallScoreStrategy("Capture", func(ep *registry.Endpoint, s loadbalancer.Signals) float64 {
+			if ep.Address == "127.0.0.1:9003" {
+				got = s
+			}
+			return 0
+		})
+		loadbalancer.CallStarted("127.0.0.1:9003")
+		loadbalancer.CallStarted("127.0.0.1:9003")
+		loadbalancer.CallFinished("127.0.0.1:9003", errors.New("fake"))
+		f, _ := loadbalancer.GetStrategyPlugin("Capture")
+		s := f()
+		s.ReceiveData(&invocation.Invocation{MicroServiceName: "scoreService", Protocol: "rest", RouteTags: tags},
+			mss, "scoreService")
+		_, err := s.Pick()
+		assert.NoError(t, err)
+		assert.Equal(t, int64(1), got.InFlight)
+		assert.Equal(t, int64(1), got.SuccessiveFailures)
+		assert.Equal(t, 80*time.Millisecond, got.AvgLatency)
+	})
+	t.Run("counters of removed endpoints are pruned", func(t *testing.T) {
+		old := registry.DefaultServiceDiscoveryService
+		defer func() { registry.DefaultServiceDiscoveryService = old }()
+		removed := []*registry.MicroServiceInstance{
+			{InstanceID: "ins4", EndpointsMap: map[string]*registry.Endpoint{"rest": {Address: "127.0.0.1:9004"}}},
+		}
+		kept := []*registry.MicroServiceInstance{
+			{InstanceID: "ins5", EndpointsMap: map[string]*registry.Endpoint{"rest": {Address: "127.0.0.1:9005"}}},
+		}
+		d := &mk.DiscoveryMock{}
+		registry.DefaultServiceDiscoveryService = d
+		d.On("FindMicroServiceInstances", "selfServiceID", "appID", "prunedService", "1.0", "").Return(removed, nil).Once()
+		d.On("FindMicroServiceInstances", "selfServiceID", "appID", "prunedService", "1.0", "").Return(kept, nil)
+		inv := func() *invocation.Invocation {
+			return &invocation.Invocation{SourceServiceID: "selfServiceID", MicroServiceName: "prunedService",
+				Protocol: "rest", RouteTags: tags}
+		}
+		loadbalancer.CallStarted("127.0.0.1:9004")
+		loadbalancer.CallFinished("127.0.0.1:9004", errors.New("fake"))
+		for n := 0; n < 2; n++ {
+			_, err := loadbalancer.BuildStrategy(inv(), &loadbalancer.RoundRobinStrategy{})
+			assert.NoError(t, err)
+		}
+
+		got := loadbalancer.Signals{SuccessiveFailures: -1}
+		loadbalancer.InstallScoreStrategy("CapturePruned", func(ep *registry.Endpoint, s loadbalancer.Signals) float64 {
+			got = s
+			return 0
+		})
+		f, _ := loadbalancer.GetStrategyPlugin("CapturePruned")
+		s := f()
+		s.ReceiveData(inv(), removed, "prunedService")
+		_, err := s.Pick()
+		assert.NoError(t, err)
+		assert.Equal(t, int64(0), got.SuccessiveFailures)
+	})
+}
@@ -33,20 +33,20 @@ func TestSessionStickinessStrategy_Pick(t *testing.T) {
 		{
 			EndpointsMap: map[string]*registry.Endpoint{
 				"rest": {
-					false,
-					"10.0.0.3:8080",
+					SSLEnabled: false,
+					Address:    "10.0.0.3:8080",
 				},
 			},
 		},
 		{
 			EndpointsMap: map[string]*registry.Endpoint{
 				"rest": {
-					false,
-					"2",
+					SSLEnabled: false,
+					Address:    "2",
 				},
 				"highway": {
-					false,
-					"10.0.0.3:8080",
+					SSLEnabled: false,
+					Address:    "10.0.0.3:8080",
 				},
 			},
 		},
@@ -0,0 +1,111 @@
+package loadbalancer
+
+import (
+	"sort"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-chassis/go-chassis/v2/core/common"
+	"github.com/go-chassis/go-chassis/v2/third_party/forked/afex/hystrix-go/hystrix"
+)
+
+// EndpointSnapshot is the state of an endpoint seen by load balancer
+type EndpointSnapshot struct {
+	Address string
+	// Stale is true if endpoint is from last successful discovery, the latest discovery failed
+	Stale bool
+	// InFlight is the number of requests which are being sent to the endpoint
+	InFlight int64
+	// SuccessiveFailures is the number of failed calls since last success
+	SuccessiveFailures int64
+	// Ejected is true if SuccessiveFailures reaches EjectionFailures
+	Ejected bool
+	// Latencies are recent latencies, key is stats key of route tags and protocol, see BuildKey
+	Latencies map[string][]time.Duration
+	// Circuit is the circuit breaker of instance scope which protects the endpoint, it is nil if there is none
+	Circuit *hystrix.CircuitStats
+}
+
+// ServiceSnapshot is the state of a service seen by load balancer, it is a copy which does not change
+type ServiceSnapshot struct {
+	Service string
+	Time    time.Time
+	// Healthy is the result of ServiceHealthy
+	Healthy bool
+	// Endpoints are sorted by address
+	Endpoints []EndpointSnapshot
+	// Circuits are circuit breakers of calls to service, sorted by name
+	Circuits []hystrix.CircuitStats
+}
+
+// LoadBalancerSnapshot copies the state of service for debugging, such as to be dumped by an admin handler.
+// endpoints are the ones of last discovery of all service keys, a service which is not called yet has none
+func LoadBalancerSnapshot(service string) ServiceSnapshot {
+	s := ServiceSnapshot{Service: service, Time: time.Now()}
+	endpoints := make(map[string]*EndpointSnapshot)
+	discoveryStatesMu.RLock()
+	for _, state := range discoveryStates[service] {
+		for _, addr := range state.addrs {
+			ep, ok := endpoints[addr]
+			if !ok {
+				ep = &EndpointSnapshot{Address: addr, Stale: state.stale}
+				endpoints[addr] = ep
+			}
+			// an endpoint is fresh if any service key has it from latest discovery
+			ep.Stale = ep.Stale && state.stale
+		}
+	}
+	discoveryStatesMu.RUnlock()
+
+	LatencyMapRWMutex.RLock()
+	for key, stats := range ProtocolStatsMap {
+		if !strings.HasPrefix(key, service+"/") {
+			continue
+		}
+		for _, ps := range stats {
+			if ep, ok := endpoints[ps.Addr]; ok {
+				if ep.Latencies == nil {
+					ep.Latencies = make(map[string][]time.Duration)
+				}
+				ep.Latencies[key] = append([]time.Duration(nil), ps.Latency...)
+			}
+		}
+	}
+	LatencyMapRWMutex.RUnlock()
+
+	circuits := hystrix.AllStats()
+	for _, ep := range endpoints {
+		if c := getEndpointCounter(ep.Address); c != nil {
+			ep.InFlight = atomic.LoadInt64(&c.inFlight)
+			ep.SuccessiveFailures = atomic.LoadInt64(&c.failures)
+			ep.Ejected = ep.SuccessiveFailures >= EjectionFailures
+		}
+		if cs, ok := circuits[common.Consumer+"."+service+"."+ep.Address]; ok {
+			ep.Circuit = &cs
+		}
+		s.Endpoints = append(s.Endpoints, *ep)
+		if !ep.Stale && !ep.Ejected {
+			s.Healthy = true
+		}
+	}
+	sort.Slice(s.Endpoints, func(i, j int) bool { return s.Endpoints[i].Address < s.Endpoints[j].Address })
+	for name, cs := range circuits {
+		if circuitOf(name, service) {
+			s.Circuits = append(s.Circuits, cs)
+		}
+	}
+	sort.Slice(s.Circuits, func(i, j int) bool { return s.Circuits[i].Name < s.Circuits[j].Name })
+	return s
+}
+
+// circuitOf tells if circuit name is built for calls to service, it is Consumer.{service}
+// followed by scope of circuit, it may start with name of caller
+func circuitOf(name, service string) bool {
+	cmd := common.Consumer + "." + service
+	if i := strings.Index(name, cmd); i == 0 || i > 0 && name[i-1] == '.' {
+		rest := name[i+len(cmd):]
+		return rest == "" || rest[0] == '.'
+	}
+	return false
+}
@@ -0,0 +1,81 @@
+package loadbalancer_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/go-chassis/go-chassis/v2/core/invocation"
+	"github.com/go-chassis/go-chassis/v2/core/loadbalancer"
+	"github.com/go-chassis/go-chassis/v2/core/registry"
+	mk "github.com/go-chassis/go-chassis/v2/core/registry/mock"
+	"github.com/go-chassis/go-chassis/v2/pkg/util/tags"
+	"github.com/go-chassis/go-chassis/v2/third_party/forked/afex/hystrix-go/hystrix"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoadBalancerSnapshot(t *testing.T) {
+	old := registry.DefaultServiceDiscoveryService
+	defer func() { registry.DefaultServiceDiscoveryService = old }()
+	mss := []*registry.MicroServiceInstance{
+		{InstanceID: "ins1", EndpointsMap: map[string]*registry.Endpoint{"rest": {Address: "127.0.0.1:9201"}}},
+		{InstanceID: "ins2", EndpointsMap: map[string]*registry.Endpoint{"rest": {Address: "127.0.0.1:9202"}}},
+	}
+	d := &mk.DiscoveryMock{}
+	registry.DefaultServiceDiscoveryService = d
+	d.On("FindMicroServiceInstances", "selfServiceID", "appID", "snapService", "1.0", "").Return(mss, nil)
+	inv := invocation.New(context.Background())
+	inv.SourceServiceID = "selfServiceID"
+	inv.MicroServiceName = "snapService"
+	inv.RouteTags = utiltags.NewDefaultTag("1.0", "appID")
+	assert.Empty(t, loadbalancer.LoadBalancerSnapshot("snapService").Endpoints, "service is not discovered yet")
+	_, err := loadbalancer.BuildStrategy(inv, nil)
+	assert.NoError(t, err)
+
+	for n := int64(0); n < loadbalancer.EjectionFailures; n++ {
+		loadbalancer.CallStarted("127.0.0.1:9201")
+		loadbalancer.CallFinished("127.0.0.1:9201", errors.New("refused"))
+	}
+	loadbalancer.CallStarted("127.0.0.1:9202")
+	loadbalancer.SetLatency(20*time.Millisecond, "127.0.0.1:9202", "snapService", inv.RouteTags, "rest")
+	circuit := "Consumer.snapService.127.0.0.1:9202"
+	defer hystrix.FlushByName(circuit)
+	_, _, err = hystrix.GetCircuit(circuit)
+	assert.NoError(t, err)
+
+	s := loadbalancer.LoadBalancerSnapshot("snapService")
+	assert.Equal(t, "snapService", s.Service)
+	assert.True(t, s.Healthy)
+	if assert.Len(t, s.Endpoints, 2) {
+		ejected, busy := s.Endpoints[0], s.Endpoints[1]
+		assert.Equal(t, "127.0.0.1:9201", ejected.Address)
+		assert.True(t, ejected.Ejected)
+		assert.Equal(t, loadbalancer.EjectionFailures, ejected.SuccessiveFailures)
+		assert.Equal(t, int64(0), ejected.InFlight)
+		assert.Nil(t, ejected.Circuit)
+
+		assert.Equal(t, "127.0.0.1:9202", busy.Address)
+		assert.False(t, busy.Ejected)
+		assert.False(t, busy.Stale)
+		assert.Equal(t, int64(1), busy.InFlight)
+		key := loadbalancer.BuildKey("snapService", inv.RouteTags.String(), "rest")
+		assert.Equal(t, []time.Duration{20 * time.Millisecond}, busy.Latencies[key])
+		if assert.NotNil(t, busy.Circuit) {
+			assert.Equal(t, hystrix.StateClosed, busy.Circuit.State)
+		}
+	}
+	if assert.Len(t, s.Circuits, 1) {
+		assert.Equal(t, circuit, s.Circuits[0].Name)
+	}
+
+	loadbalancer.CallFinished("127.0.0.1:9202", errors.New("reset"))
+	loadbalancer.CallStarted("127.0.0.1:9201")
+	loadbalancer.CallFinished("127.0.0.1:9201", nil)
+	assert.True(t, s.Endpoints[0].Ejected, "snapshot is a copy")
+	assert.Equal(t, int64(1), s.Endpoints[1].InFlight)
+	s = loadbalancer.LoadBalancerSnapshot("snapService")
+	assert.False(t, s.Endpoints[0].Ejected, "endpoint recovers after success")
+	assert.Equal(t, int64(0), s.Endpoints[1].InFlight)
+	assert.Equal(t, int64(1), s.Endpoints[1].SuccessiveFailures)
+}
@@ -6,6 +6,7 @@ import (
 	"testing"
 
 	"github.com/go-chassis/go-chassis/v2/core/config"
+	"github.com/go-chassis/go-chassis/v2/core/invocation"
 	"github.com/go-chassis/go-chassis/v2/core/loadbalancer"
 	"github.com/go-chassis/go-chassis/v2/core/registry"
 	"github.com/stretchr/testify/assert"
@@ -17,24 +18,24 @@ func TestRandomStrategy_Pick(t *testing.T) {
 		{
 			EndpointsMap: map[string]*registry.Endpoint{
 				"rest": {
-					false,
-					"1",
+					SSLEnabled: false,
+					Address:    "1",
 				},
 				"highway": {
-					false,
-					"10.0.0.3:8080",
+					SSLEnabled: false,
+					Address:    "10.0.0.3:8080",
 				},
 			},
 		},
 		{
 			EndpointsMap: map[string]*registry.Endpoint{
 				"rest": {
-					false,
-					"2",
+					SSLEnabled: false,
+					Address:    "2",
 				},
 				"highway": {
-					false,
-					"10.0.0.3:8080",
+					SSLEnabled: false,
+					Address:    "10.0.0.3:8080",
 				},
 			},
 		},
@@ -64,24 +65,24 @@ func TestRoundRobinStrategy_Pick(t *testing.T) {
 		{
 			EndpointsMap: map[string]*registry.Endpoint{
 				"rest": {
-					false,
-					"1",
+					SSLEnabled: false,
+					Address:    "1",
 				},
 				"highway": {
-					false,
-					"10.0.0.3:8080",
+					SSLEnabled: false,
+					Address:    "10.0.0.3:8080",
 				},
 			},
 		},
 		{
 			EndpointsMap: map[string]*registry.Endpoint{
 				"rest": {
-					false,
-					"2",
+					SSLEnabled: false,
+					Address:    "2",
 				},
 				"highway": {
-					false,
-					"10.0.0.3:8080",
+					SSLEnabled: false,
+					Address:    "10.0.0.3:8080",
 				},
 			},
 		},
@@ -107,3 +108,30 @@ func TestGetStrategyPlugin(t *testing.T) {
 	_, err = loadbalancer.GetStrategyPlugin(loadbalancer.StrategyRoundRobin)
 	assert.NoError(t, err)
 }
+
+func TestSortedRoundRobinStrategy_Pick(t *testing.T) {
+	var instances []*registry.MicroServiceInstance
+	for _, addr := range []string{"10.0.0.1:80", "10.0.0.2:80", "10.0.0.3:80", "10.0.0.4:80"} {
+		instances = append(instances, &registry.MicroServiceInstance{
+			EndpointsMap: map[string]*registry.Endpoint{"rest": {Address: addr}},
+		})
+	}
+	sequence := func(instances []*registry.MicroServiceInstance, serviceKey string) []string {
+		s := &loadbalancer.SortedRoundRobinStrategy{}
+		s.ReceiveData(&invocation.Invocation{Protocol: "rest"}, instances, serviceKey)
+		var seq []string
+		for n := 0; n < 8; n++ {
+			ins, err := s.Pick()
+			assert.NoError(t, err)
+			seq = append(seq, ins.EndpointsMap["rest"].Address)
+		}
+		return seq
+	}
+	shuffled := []*registry.MicroServiceInstance{instances[2], instances[0], instances[3], instances[1]}
+	a := sequence(instances, "sortedA")
+	b := sequence(shuffled, "sortedB")
+	assert.Equal(t, a, b)
+	assert.Equal(t, []string{"10.0.0.1:80", "10.0.0.2:80", "10.0.0.3:80", "10.0.0.4:80",
+		"10.0.0.1:80", "10.0.0.2:80", "10.0.0.3:80", "10.0.0.4:80"}, a)
+	assert.Equal(t, "10.0.0.3:80", shuffled[0].EndpointsMap["rest"].Address, "instances of caller are not sorted")
+}
@@ -4,27 +4,51 @@ import (
 	"time"
 )
 
+func ewmaEnabled(decay float64) bool {
+	return decay > 0 && decay <= 1
+}
+
 // ProtocolStats store protocol stats
 type ProtocolStats struct {
 	Latency    []time.Duration
 	Addr       string
 	AvgLatency time.Duration
+	// Samples is the number of latencies saved
+	Samples int
+	// Decay is the weight of a new latency sample in exponentially weighted moving average, from 0 to 1,
+	// the weight of older samples decays by 1-Decay per sample, so that recent latencies dominate.
+	// 0 means average latency is the plain average of latest 10 samples
+	Decay float64
+	// ewma is exponentially weighted moving average of samples saved since Decay is set
+	ewma time.Duration
 }
 
 // CalculateAverageLatency make avg latency
 func (ps *ProtocolStats) CalculateAverageLatency() {
+	if len(ps.Latency) == 0 {
+		return
+	}
+	if ewmaEnabled(ps.Decay) {
+		ps.AvgLatency = ps.ewma
+		return
+	}
 	var sum time.Duration
 	for i := 0; i < len(ps.Latency); i++ {
 		sum = sum + ps.Latency[i]
 	}
-	if len(ps.Latency) == 0 {
-		return
-	}
 	ps.AvgLatency = time.Duration(sum.Nanoseconds() / int64(len(ps.Latency)))
 }
 
 // SaveLatency save latest 10 record
 func (ps *ProtocolStats) SaveLatency(l time.Duration) {
+	if !ewmaEnabled(ps.Decay) {
+		ps.ewma = 0
+	} else if ps.ewma == 0 {
+		ps.ewma = l
+	} else {
+		ps.ewma = time.Duration(ps.Decay*float64(l) + (1-ps.Decay)*float64(ps.ewma))
+	}
+	ps.Samples++
 	if len(ps.Latency) >= 10 {
 		//save latest 10 latencies
 		ps.Latency = ps.Latency[1:]
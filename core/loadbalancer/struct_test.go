@@ -34,3 +34,30 @@ func TestProtocolStats_SaveLatency(t *testing.T) {
 	assert.Equal(t, 2200*time.Millisecond, s.AvgLatency)
 
 }
+
+func TestProtocolStats_EWMA(t *testing.T) {
+	stats := func(decay float64) *loadbalancer.ProtocolStats {
+		s := &loadbalancer.ProtocolStats{Addr: "127.0.0.1:8080", Decay: decay}
+		for n := 0; n < 9; n++ {
+			s.SaveLatency(100 * time.Millisecond)
+		}
+		s.SaveLatency(time.Second)
+		s.CalculateAverageLatency()
+		return s
+	}
+	window := stats(0)
+	assert.Equal(t, 190*time.Millisecond, window.AvgLatency)
+
+	ewma := stats(0.5)
+	assert.Equal(t, 550*time.Millisecond, ewma.AvgLatency, "latest sample weighs half")
+	assert.True(t, ewma.AvgLatency > window.AvgLatency, "ewma reacts faster to spike")
+
+	ewma.SaveLatency(100 * time.Millisecond)
+	ewma.CalculateAverageLatency()
+	assert.Equal(t, 325*time.Millisecond, ewma.AvgLatency, "spike decays with later samples")
+
+	ewma.Decay = 0
+	ewma.SaveLatency(100 * time.Millisecond)
+	ewma.CalculateAverageLatency()
+	assert.Equal(t, 190*time.Millisecond, ewma.AvgLatency, "plain average once decay is unset")
+}
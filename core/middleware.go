@@ -0,0 +1,29 @@
+package core
+
+import (
+	"context"
+	"net/http"
+)
+
+// CallFunc sends a rest request and returns its response, it is the call wrapped by Middleware
+type CallFunc func(ctx context.Context, req *http.Request) (*http.Response, error)
+
+// Middleware wraps a call, it can change request and response, measure or recover the call,
+// or return without calling next at all
+type Middleware func(next CallFunc) CallFunc
+
+// WithMiddleware is a request option, mw wrap the whole call, including admission, retries and token refresh.
+// the first middleware is the outermost one, middleware of several WithMiddleware options are chained in order
+func WithMiddleware(mw ...Middleware) InvocationOption {
+	return func(o *InvokeOptions) {
+		o.Middleware = append(o.Middleware, mw...)
+	}
+}
+
+// chainMiddleware wraps call by mw, so that mw[0] is called first
+func chainMiddleware(call CallFunc, mw []Middleware) CallFunc {
+	for n := len(mw) - 1; n >= 0; n-- {
+		call = mw[n](call)
+	}
+	return call
+}
@@ -1,9 +1,14 @@
 package core
 
 import (
+	"net/http"
 	"time"
 
+	"github.com/go-chassis/go-chassis/v2/client/rest"
+	"github.com/go-chassis/go-chassis/v2/core/common"
+	"github.com/go-chassis/go-chassis/v2/core/config/model"
 	"github.com/go-chassis/go-chassis/v2/core/invocation"
+	"github.com/go-chassis/go-chassis/v2/core/loadbalancer"
 	"github.com/go-chassis/go-chassis/v2/pkg/util/tags"
 )
 
@@ -37,6 +42,65 @@ type InvokeOptions struct {
 	Metadata map[string]interface{}
 	// tags for router
 	RouteTags utiltags.Tags
+	// limits how long instance discovery can take, 0 means only context deadline applies
+	DiscoveryTimeout time.Duration
+	// use last known instances not older than it if discovery fails or times out
+	StaleEndpointMaxAge time.Duration
+	// close connection after request, do not return it to pool
+	ConnectionClose bool
+	// refresh bearer token when server returns 401 with a Bearer challenge
+	TokenProvider TokenProvider
+	// receives dump of request and response of the final failed attempt
+	DumpOnError func([]byte)
+	// bounds the whole call, including all retries and backoffs
+	MaxTotalDuration time.Duration
+	// coalesce concurrent identical GET and HEAD requests into one call
+	Singleflight bool
+	// keep result of coalesced call for a while, so that requests arriving just after it finishes share it
+	CoalesceWindow time.Duration
+	// weight of call in admission control of service, 0 means call is not under admission control
+	AdmissionWeight int64
+	// guess content type of response by body if server omits Content-Type
+	ContentSniffing bool
+	// order of call in admission control
+	Priority Priority
+	// read response body into error if response status is failure
+	ErrorBody bool
+	// receive raw request and response of the final attempt
+	CaptureRequest  *[]byte
+	CaptureResponse *[]byte
+	// connections are not shared with calls of other isolation key
+	IsolationKey string
+	// receives decisions made while call is handled
+	DecisionTrace *invocation.DecisionTrace
+	// inject delay or abort to call, nil means no fault is injected
+	FaultInjection *model.Fault
+	// wrap the whole call, the first one is the outermost
+	Middleware []Middleware
+	// content encodings asked in Accept-Encoding, response body is decoded by client
+	AcceptEncodings []string
+	// name of profile registered on invoker, see RestInvoker.RegisterProfile
+	Profile string
+	// changes instances of call before strategy picks
+	EndpointSelector loadbalancer.Selector
+	// classifies response by first bytes of body
+	ErrorPeek *rest.ErrorPeek
+	// return failures of all attempts instead of the last one
+	AggregateErrors bool
+	// a new connection must be established in it, 0 means only dial timeout of client applies
+	ConnectTimeout time.Duration
+	// headers removed or kept when following redirects
+	RedirectHeaderPolicy *rest.RedirectHeaderPolicy
+	// limits size of decompressed response body, 0 means no limit
+	MaxDecompressedSize int64
+	// addresses which strategy picks from, discovery is skipped
+	ExplicitEndpoints []string
+	// latency of call is not recorded for load balancing
+	SkipLatencyRecording bool
+	// logical name of operation for metrics and tracing
+	OperationName string
+	// a request lost on a connection idle beyond it is sent again on a new connection, 0 means no check
+	ValidateIdleConns time.Duration
 }
 
 //TODO a lot of options
@@ -46,10 +110,10 @@ var DefaultOptions = Options{
 	ChainName: "default",
 }
 
-//ChainName is able to custom handler chain for a invoker.
-//you can specify a handler chain name under "servicecomb.handler.chain.Consumer" in chassis.yaml file.
-//so that you can define different invoker with different handler chain.
-//a handler chain is bind to a invoker instance.
+// ChainName is able to custom handler chain for a invoker.
+// you can specify a handler chain name under "servicecomb.handler.chain.Consumer" in chassis.yaml file.
+// so that you can define different invoker with different handler chain.
+// a handler chain is bind to a invoker instance.
 func ChainName(name string) Option {
 	return func(o *Options) {
 		o.ChainName = name
@@ -126,6 +190,258 @@ func WithRouteTags(t map[string]string) InvocationOption {
 	}
 }
 
+// WithDiscoveryTimeout is a request option, it limits how long instance discovery can take,
+// if context has an earlier deadline, the deadline wins
+func WithDiscoveryTimeout(d time.Duration) InvocationOption {
+	return func(o *InvokeOptions) {
+		o.DiscoveryTimeout = d
+	}
+}
+
+// WithStaleEndpointFallback is a request option, if discovery fails or times out,
+// last known instances of the service will be used as long as they are not older than maxAge
+func WithStaleEndpointFallback(maxAge time.Duration) InvocationOption {
+	return func(o *InvokeOptions) {
+		o.StaleEndpointMaxAge = maxAge
+	}
+}
+
+// WithConnectionClose is a request option, it sends "Connection: close"
+// and the connection will not be reused after the request
+func WithConnectionClose() InvocationOption {
+	return func(o *InvokeOptions) {
+		o.ConnectionClose = true
+	}
+}
+
+// WithTokenProvider is a request option, if server returns 401 with a Bearer challenge,
+// provider is called once to get a new token and request is sent again with it.
+// concurrent calls rejected with the same token share a single refresh
+func WithTokenProvider(p TokenProvider) InvocationOption {
+	return func(o *InvokeOptions) {
+		o.TokenProvider = p
+	}
+}
+
+// WithDumpOnError is a request option, if call fails, f receives the dump of request and response of the final attempt,
+// sensitive headers are redacted. nothing is dumped if call succeeds
+func WithDumpOnError(f func([]byte)) InvocationOption {
+	return func(o *InvokeOptions) {
+		o.DumpOnError = f
+	}
+}
+
+// WithMaxTotalDuration is a request option, it bounds the whole call including all retries and backoffs,
+// no more attempt is made once d is exceeded, and the last error is returned.
+// if ctx has an earlier deadline, the earlier one wins
+func WithMaxTotalDuration(d time.Duration) InvocationOption {
+	return func(o *InvokeOptions) {
+		o.MaxTotalDuration = d
+	}
+}
+
+// WithSingleflight is a request option, concurrent identical GET or HEAD requests without body share one call,
+// requests are identical if method, url, endpoint and headers are same.
+// response body is buffered, and every caller receives its own copy of response.
+// shared call is not canceled by context of any caller, each caller stops waiting when its own context is done.
+// shared call has the deadline of the caller which starts it, such as of WithMaxTotalDuration,
+// a caller which has time after that calls server on its own.
+// it has no effect if WithCapture, WithDumpOnError or WithDecisionTrace is used
+func WithSingleflight(enable bool) InvocationOption {
+	return func(o *InvokeOptions) {
+		o.Singleflight = enable
+	}
+}
+
+// WithCoalesceWindow is a request option, it enables singleflight and keeps a successful result for d after
+// the shared call finishes, identical requests arriving within d get a copy of it without calling server.
+// it is not kept if Cache-Control of request or response has no-store or no-cache.
+// window bridges singleflight and a cache, keep it short, such as several milliseconds
+func WithCoalesceWindow(d time.Duration) InvocationOption {
+	return func(o *InvokeOptions) {
+		o.CoalesceWindow = d
+	}
+}
+
+// WithAdmission is a request option, call waits until service has capacity for weight,
+// capacity is set by SetAdmissionLimit, if service has no limit, call is sent immediately.
+// call fails with ctx error if ctx is done before it is admitted
+func WithAdmission(weight int64) InvocationOption {
+	return func(o *InvokeOptions) {
+		o.AdmissionWeight = weight
+	}
+}
+
+// WithPriority is a request option, high priority calls are admitted ahead of waiting normal calls
+// in admission control, see WithAdmission
+func WithPriority(p Priority) InvocationOption {
+	return func(o *InvokeOptions) {
+		o.Priority = p
+	}
+}
+
+// WithErrorBody is a request option, if response status is failure,
+// error of call carries response body. by default body is not read, to protect service sensitive data
+func WithErrorBody(include bool) InvocationOption {
+	return func(o *InvokeOptions) {
+		o.ErrorBody = include
+	}
+}
+
+// WithCapture is a request option, reqOut and respOut are filled with raw request and response of the final attempt,
+// headers are not redacted, either of them can be nil
+func WithCapture(reqOut, respOut *[]byte) InvocationOption {
+	return func(o *InvokeOptions) {
+		o.CaptureRequest = reqOut
+		o.CaptureResponse = respOut
+	}
+}
+
+// WithContentSniffing is a request option, if response has no Content-Type header,
+// it is guessed by first bytes of body, JSON for '{' or '[', XML for '<',
+// otherwise Content-Type of request is used. it is off by default
+func WithContentSniffing(enable bool) InvocationOption {
+	return func(o *InvokeOptions) {
+		o.ContentSniffing = enable
+	}
+}
+
+// WithIsolatedConnection is a request option, calls with the same tenantKey share a connection pool of their own,
+// connections are never reused by calls of other keys or calls without key.
+// a pool is kept for each key, so keys should be bounded, such as tenant ids
+func WithIsolatedConnection(tenantKey string) InvocationOption {
+	return func(o *InvokeOptions) {
+		o.IsolationKey = tenantKey
+	}
+}
+
+// WithDecisionTrace is a request option, trace receives events of call in order,
+// such as endpoint picked by strategy, failed attempts, retries and why retry stops.
+// it is for debugging, events are only recorded if trace is not nil
+func WithDecisionTrace(trace *invocation.DecisionTrace) InvocationOption {
+	return func(o *InvokeOptions) {
+		o.DecisionTrace = trace
+	}
+}
+
+// WithFaultInjection is a request option for chaos testing, for Percent of calls,
+// spec.Delay delays the call and spec.Abort fails the call with HTTPStatus before request is sent.
+// injected faults are handled as real failures by retry and circuit breaker,
+// they are logged and recorded as invocation.EventFaultInjected in decision trace.
+// no fault is injected unless this option is used
+func WithFaultInjection(spec model.Fault) InvocationOption {
+	return func(o *InvokeOptions) {
+		o.FaultInjection = &spec
+	}
+}
+
+// WithAcceptEncodings is a request option, Accept-Encoding of request is set to encodings in order,
+// and response body is decoded by its Content-Encoding. gzip and deflate are built in,
+// others such as br or zstd must be installed by rest.InstallContentDecoder.
+// call fails if an encoding has no decoder, or server responds with an encoding which has no decoder
+func WithAcceptEncodings(encodings []string) InvocationOption {
+	return func(o *InvokeOptions) {
+		o.AcceptEncodings = encodings
+	}
+}
+
+// WithExplicitEndpoints is a request option, strategy of call picks from endpoints instead of discovered instances,
+// registry is not queried, so that a gateway can route to services which are not registered.
+// filters and WithEndpointSelector still apply, an instance is made for each endpoint, its id is the endpoint.
+// unlike WithEndpoint, load balancing and retries to next endpoint work as usual
+func WithExplicitEndpoints(endpoints []string) InvocationOption {
+	return func(o *InvokeOptions) {
+		o.ExplicitEndpoints = endpoints
+	}
+}
+
+// WithOperationName is a request option, it names operation of call in metrics labels and span name,
+// so that calls to a templated path, such as /users/{id}/profile, have one label instead of one for each path.
+// default is operation id, which is method of rpc call and path of rest call
+func WithOperationName(name string) InvocationOption {
+	return func(o *InvokeOptions) {
+		o.OperationName = name
+	}
+}
+
+// WithValidateIdleConns is a request option, if server closes a pooled connection which was idle beyond idleThreshold
+// just as request is sent on it, the request is sent once again on a new connection instead of failing with reset or EOF.
+// transport only does it for idempotent requests, the option also does it for others, so use it only if server
+// is known to close idle connections silently, such as after its keep-alive timeout
+func WithValidateIdleConns(idleThreshold time.Duration) InvocationOption {
+	return func(o *InvokeOptions) {
+		o.ValidateIdleConns = idleThreshold
+	}
+}
+
+// WithSkipLatencyRecording is a request option, call is sent as usual but its latency is not recorded
+// for latency aware strategies, so that probe, health check and warmup calls do not skew routing
+func WithSkipLatencyRecording(skip bool) InvocationOption {
+	return func(o *InvokeOptions) {
+		o.SkipLatencyRecording = skip
+	}
+}
+
+// WithMaxDecompressedSize is a request option, reading decompressed response body fails with
+// rest.ErrDecompressedBodyTooLarge once more than n bytes are read, it protects from decompression bombs.
+// it applies to any encoding decoded by client, and to gzip decoded by transport if WithAcceptEncodings is not used
+func WithMaxDecompressedSize(n int64) InvocationOption {
+	return func(o *InvokeOptions) {
+		o.MaxDecompressedSize = n
+	}
+}
+
+// WithEndpointSelector is a request option, selector receives instances of service which pass route rules and filters,
+// and returns instances which strategy picks from, such as a canary instance for a debug header.
+// it only applies to this call, retries of the call use it too. call fails with loadbalancer.ErrNoneAvailableInstance
+// if selector returns no instance
+func WithEndpointSelector(selector loadbalancer.Selector) InvocationOption {
+	return func(o *InvokeOptions) {
+		o.EndpointSelector = selector
+	}
+}
+
+// WithErrorPeek is a request option, classify receives response and at most n bytes from start of its body,
+// if it returns error, call fails with it as if response status is failure, so that it can be retried,
+// unless error is marked by rest.Permanent.
+// body is not buffered, peeked bytes are returned first when body is read, then the rest is streamed
+func WithErrorPeek(n int, classify func(resp *http.Response, head []byte) error) InvocationOption {
+	return func(o *InvokeOptions) {
+		o.ErrorPeek = &rest.ErrorPeek{Size: n, Classify: classify}
+	}
+}
+
+// WithAggregateErrors is a request option, if it is true, a call failed after retries returns *handler.AggregateError,
+// it carries failure of every attempt with its endpoint. by default only failure of the last attempt is returned
+func WithAggregateErrors(aggregate bool) InvocationOption {
+	return func(o *InvokeOptions) {
+		o.AggregateErrors = aggregate
+	}
+}
+
+// WithConnectTimeout is a request option, a new connection of each attempt must be established in timeout,
+// otherwise the attempt fails with rest.TimeoutError in dial phase, and retry goes to another endpoint at once.
+// the wait for a connection of pool is not counted. use it with WithTotalTimeout to fail fast on unreachable hosts,
+// while the call itself can be slow
+func WithConnectTimeout(timeout time.Duration) InvocationOption {
+	return func(o *InvokeOptions) {
+		o.ConnectTimeout = timeout
+	}
+}
+
+// WithRedirectHeaderPolicy is a request option, when following redirects, headers in dropCrossHost are removed
+// if redirect goes to another host, headers in keep are always sent, even Authorization which is removed by default
+func WithRedirectHeaderPolicy(dropCrossHost, keep []string) InvocationOption {
+	return func(o *InvokeOptions) {
+		o.RedirectHeaderPolicy = &rest.RedirectHeaderPolicy{DropCrossHost: dropCrossHost, Keep: keep}
+	}
+}
+
+// WithTotalTimeout is a request option, it is the same as WithMaxTotalDuration
+func WithTotalTimeout(timeout time.Duration) InvocationOption {
+	return WithMaxTotalDuration(timeout)
+}
+
 // getOpts is to get the options
 func getOpts(microservice string, options ...InvocationOption) InvokeOptions {
 	opts := InvokeOptions{}
@@ -143,8 +459,69 @@ func wrapInvocationWithOpts(i *invocation.Invocation, opts InvokeOptions) {
 	i.Filters = opts.Filters
 	i.PortName = opts.Port
 	if opts.Metadata != nil {
-		i.Metadata = opts.Metadata
+		// copy it, internal keys must not be written to map of caller, it may be shared by calls
+		i.Metadata = make(map[string]interface{}, len(opts.Metadata))
+		for k, v := range opts.Metadata {
+			i.Metadata[k] = v
+		}
 	}
 
 	i.RouteTags = opts.RouteTags
+	if opts.DiscoveryTimeout > 0 {
+		i.SetMetadata(common.DiscoveryTimeoutKey, opts.DiscoveryTimeout)
+	}
+	if opts.DumpOnError != nil {
+		i.SetMetadata(common.DumpOnErrorKey, true)
+	}
+	if opts.ErrorBody {
+		i.SetMetadata(common.ErrorBodyKey, true)
+	}
+	if opts.CaptureRequest != nil || opts.CaptureResponse != nil {
+		i.SetMetadata(common.CaptureKey, true)
+	}
+	if opts.StaleEndpointMaxAge > 0 {
+		i.SetMetadata(common.StaleInstancesMaxAgeKey, opts.StaleEndpointMaxAge)
+	}
+	if opts.IsolationKey != "" {
+		i.SetMetadata(common.IsolationKey, opts.IsolationKey)
+	}
+	if opts.DecisionTrace != nil {
+		i.SetMetadata(invocation.MDDecisionTrace, opts.DecisionTrace)
+	}
+	if len(opts.AcceptEncodings) > 0 {
+		i.SetMetadata(common.AcceptEncodingsKey, opts.AcceptEncodings)
+	}
+	if opts.ExplicitEndpoints != nil {
+		i.SetMetadata(common.ExplicitEndpointsKey, opts.ExplicitEndpoints)
+	}
+	if opts.OperationName != "" {
+		i.SetMetadata(invocation.MDOperationName, opts.OperationName)
+	}
+	if opts.SkipLatencyRecording {
+		i.SetMetadata(common.SkipLatencyRecordingKey, true)
+	}
+	if opts.ValidateIdleConns > 0 {
+		i.SetMetadata(common.ValidateIdleConnsKey, opts.ValidateIdleConns)
+	}
+	if opts.MaxDecompressedSize > 0 {
+		i.SetMetadata(common.MaxDecompressedSizeKey, opts.MaxDecompressedSize)
+	}
+	if opts.EndpointSelector != nil {
+		i.SetMetadata(common.EndpointSelectorKey, opts.EndpointSelector)
+	}
+	if opts.ErrorPeek != nil {
+		i.SetMetadata(common.ErrorPeekKey, *opts.ErrorPeek)
+	}
+	if opts.FaultInjection != nil {
+		i.SetMetadata(common.FaultInjectionKey, *opts.FaultInjection)
+	}
+	if opts.AggregateErrors {
+		i.SetMetadata(common.AggregateErrorsKey, true)
+	}
+	if opts.ConnectTimeout > 0 {
+		i.SetMetadata(common.ConnectTimeoutKey, opts.ConnectTimeout)
+	}
+	if opts.RedirectHeaderPolicy != nil {
+		i.SetMetadata(common.RedirectHeaderPolicyKey, *opts.RedirectHeaderPolicy)
+	}
 }
@@ -0,0 +1,59 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// ErrUnknownProfile means no profile is registered with the name given by WithProfile
+var ErrUnknownProfile = errors.New("unknown invocation profile")
+
+// profiles are named bundles of invocation options of an invoker
+type profiles struct {
+	m  map[string][]InvocationOption
+	mu sync.RWMutex
+}
+
+func (p *profiles) get(name string) ([]InvocationOption, bool) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	opts, ok := p.m[name]
+	return opts, ok
+}
+
+// RegisterProfile registers options as a named profile of invoker, such as timeout and strategy for bulk calls,
+// a call uses them by WithProfile(name). registering a name again replaces the profile
+func (ri *RestInvoker) RegisterProfile(name string, opts ...InvocationOption) {
+	ri.profiles.mu.Lock()
+	defer ri.profiles.mu.Unlock()
+	if ri.profiles.m == nil {
+		ri.profiles.m = make(map[string][]InvocationOption)
+	}
+	ri.profiles.m[name] = append([]InvocationOption(nil), opts...)
+}
+
+// WithProfile is a request option, options of the profile registered by RestInvoker.RegisterProfile are applied,
+// other options of the call override them. call fails with ErrUnknownProfile if profile is not registered
+func WithProfile(name string) InvocationOption {
+	return func(o *InvokeOptions) {
+		o.Profile = name
+	}
+}
+
+// invokeOptions returns options of call, options of profile go first, so that options of call win,
+// strategy of service registered on invoker applies if neither of them chooses one
+func (ri *RestInvoker) invokeOptions(host string, options []InvocationOption) (InvokeOptions, error) {
+	opts := getOpts(host, options...)
+	if opts.Profile != "" {
+		profile, ok := ri.profiles.get(opts.Profile)
+		if !ok {
+			return opts, fmt.Errorf("%w [%s]", ErrUnknownProfile, opts.Profile)
+		}
+		all := make([]InvocationOption, 0, len(profile)+len(options))
+		all = append(all, profile...)
+		opts = getOpts(host, append(all, options...)...)
+	}
+	ri.applyServiceStrategy(host, &opts)
+	return opts, nil
+}
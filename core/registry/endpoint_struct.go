@@ -13,6 +13,8 @@ const (
 type Endpoint struct {
 	SSLEnabled bool   `json:"sslEnabled"`
 	Address    string `json:"address"`
+	// Metadata is the labels of instance which endpoint belongs to, for example rack, version
+	Metadata map[string]string `json:"metadata,omitempty"`
 }
 
 // NewEndPoint return a Endpoint object what parse from url
@@ -38,6 +40,15 @@ func (e *Endpoint) SetSSLEnable(enabled bool) {
 	e.SSLEnabled = enabled
 }
 
+//Meta return the metadata value of key and whether it exists
+func (e *Endpoint) Meta(key string) (string, bool) {
+	if e == nil {
+		return "", false
+	}
+	v, ok := e.Metadata[key]
+	return v, ok
+}
+
 func (e *Endpoint) String() string {
 	return e.GenEndpoint()
 }
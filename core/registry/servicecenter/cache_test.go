@@ -47,8 +47,8 @@ func TestCacheManager_AutoSync(t *testing.T) {
 	microServiceInstance := &registry.MicroServiceInstance{
 		EndpointsMap: map[string]*registry.Endpoint{
 			"rest": {
-				false,
-				"10.146.207.197:5080",
+				SSLEnabled: false,
+				Address:    "10.146.207.197:5080",
 			},
 		},
 		InstanceID: "event1",
@@ -82,6 +82,7 @@ func ToMicroServiceInstance(ins *scregistry.MicroServiceInstance) *registry.Micr
 		msi.Metadata = make(map[string]string)
 	}
 	msi.Metadata["version"] = ins.Version
+	msi.ShareMetadata()
 	return msi
 }
 
@@ -46,8 +46,8 @@ func testRegisterServiceAndInstance(t *testing.T, scc registry.Registrator, sd r
 	microServiceInstance := &registry.MicroServiceInstance{
 		EndpointsMap: map[string]*registry.Endpoint{
 			"rest": {
-				false,
-				"10.146.207.197:8080",
+				SSLEnabled: false,
+				Address:    "10.146.207.197:8080",
 			},
 		},
 		HostName: "default",
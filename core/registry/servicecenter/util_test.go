@@ -1,6 +1,7 @@
 package servicecenter_test
 
 import (
+	scregistry "github.com/go-chassis/cari/discovery"
 	"github.com/go-chassis/go-chassis/v2/core/registry"
 	"github.com/go-chassis/go-chassis/v2/core/registry/servicecenter"
 	"github.com/patrickmn/go-cache"
@@ -20,3 +21,14 @@ func TestGetCriteria(t *testing.T) {
 	c = servicecenter.GetCriteriaByService("service2")
 	assert.Equal(t, 1, len(c))
 }
+
+func TestToMicroServiceInstance_EndpointMetadata(t *testing.T) {
+	msi := servicecenter.ToMicroServiceInstance(&scregistry.MicroServiceInstance{
+		InstanceId: "ins1",
+		Version:    "1.0",
+		Endpoints:  []string{"rest://127.0.0.1:8001"},
+	})
+	v, ok := msi.EndpointsMap["rest"].Meta("version")
+	assert.True(t, ok, "metadata is shared even if instance has no properties")
+	assert.Equal(t, "1.0", v)
+}
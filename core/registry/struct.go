@@ -52,6 +52,26 @@ type MicroServiceInstance struct {
 func (m *MicroServiceInstance) appID() string   { return m.Metadata[common.BuildinTagApp] }
 func (m *MicroServiceInstance) version() string { return m.Metadata[common.BuildinTagVersion] }
 
+// Meta return the metadata value of key and whether it exists
+func (m *MicroServiceInstance) Meta(key string) (string, bool) {
+	if m == nil {
+		return "", false
+	}
+	v, ok := m.Metadata[key]
+	return v, ok
+}
+
+// ShareMetadata makes metadata of instance visible on all of its endpoints,
+// so that strategies and filters which only see endpoint can use it.
+// registry plugin calls it once when it creates the instance, before the instance is cached and shared
+func (m *MicroServiceInstance) ShareMetadata() {
+	for _, ep := range m.EndpointsMap {
+		if ep != nil && ep.Metadata == nil {
+			ep.Metadata = m.Metadata
+		}
+	}
+}
+
 // Has return whether microservice has tags
 func (m *MicroServiceInstance) Has(tags map[string]string) bool {
 	for k, v := range tags {
@@ -8,9 +8,11 @@ import (
 
 	"github.com/go-chassis/go-chassis/v2/client/rest"
 	"github.com/go-chassis/go-chassis/v2/core/common"
+	"github.com/go-chassis/go-chassis/v2/core/handler"
 	"github.com/go-chassis/go-chassis/v2/core/invocation"
 	"github.com/go-chassis/go-chassis/v2/pkg/runtime"
 	"github.com/go-chassis/go-chassis/v2/pkg/util"
+	"github.com/go-chassis/go-chassis/v2/pkg/util/httputil"
 )
 
 const (
@@ -23,6 +25,10 @@ const (
 // thread safe
 type RestInvoker struct {
 	*abstractInvoker
+	refresher  *tokenRefresher
+	flights    *flightGroup
+	profiles   profiles
+	strategies serviceStrategies
 }
 
 // NewRestInvoker is gives the object of rest invoker
@@ -33,6 +39,8 @@ func NewRestInvoker(opt ...Option) *RestInvoker {
 		abstractInvoker: &abstractInvoker{
 			opts: opts,
 		},
+		refresher: newTokenRefresher(),
+		flights:   newFlightGroup(),
 	}
 	return ri
 }
@@ -43,26 +51,133 @@ func (ri *RestInvoker) ContextDo(ctx context.Context, req *http.Request, options
 	if req.URL.Scheme != HTTP {
 		return nil, fmt.Errorf("scheme invalid: %s, only support {http}://", req.URL.Scheme)
 	}
-	common.SetXCSEContext(map[string]string{common.HeaderSourceName: runtime.ServiceName}, req)
-	// set headers to Ctx
-	if len(req.Header) > 0 {
-		m, ok := ctx.Value(common.ContextHeaderKey{}).(map[string]string)
-		if !ok {
-			m = make(map[string]string)
+	opts, err := ri.invokeOptions(req.Host, options)
+	if err != nil {
+		return nil, err
+	}
+	if len(opts.Middleware) > 0 {
+		return chainMiddleware(func(ctx context.Context, req *http.Request) (*http.Response, error) {
+			return ri.send(ctx, req, opts)
+		}, opts.Middleware)(ctx, req)
+	}
+	return ri.send(ctx, req, opts)
+}
+
+// send applies admission and singleflight of options to call
+func (ri *RestInvoker) send(ctx context.Context, req *http.Request, opts InvokeOptions) (*http.Response, error) {
+	if opts.MaxTotalDuration > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, opts.MaxTotalDuration)
+		defer cancel()
+	}
+	if opts.AdmissionWeight > 0 {
+		service, _, _ := util.ParseServiceAndPort(req.Host)
+		if s := getAdmission(service); s != nil {
+			if err := s.acquire(ctx, opts.AdmissionWeight, opts.Priority); err != nil {
+				return nil, err
+			}
+			defer s.release(opts.AdmissionWeight)
+		}
+	}
+	if (opts.Singleflight || opts.CoalesceWindow > 0) && canCoalesce(req, opts) {
+		window := opts.CoalesceWindow
+		if !storable(req.Header) {
+			window = 0
+		}
+		return ri.flights.do(ctx, flightKey(req, opts), window, func(ctx context.Context) (*http.Response, error) {
+			return ri.call(ctx, req, opts)
+		})
+	}
+	return ri.call(ctx, req, opts)
+}
+
+func (ri *RestInvoker) call(ctx context.Context, req *http.Request, opts InvokeOptions) (*http.Response, error) {
+	resp, err := ri.do(ctx, req, opts)
+	if opts.TokenProvider != nil && needTokenRefresh(resp) {
+		resp, err = ri.retryWithNewToken(ctx, req, opts, resp, err)
+	}
+	if opts.ContentSniffing && resp != nil {
+		httputil.SniffContentType(resp, req.Header.Get("Content-Type"))
+	}
+	return resp, err
+}
+
+// headerToContext sets headers of request to context, so that handlers can read them
+func headerToContext(ctx context.Context, req *http.Request) context.Context {
+	if len(req.Header) == 0 {
+		return ctx
+	}
+	m, ok := ctx.Value(common.ContextHeaderKey{}).(map[string]string)
+	if !ok {
+		m = make(map[string]string)
+	}
+	for k, v := range req.Header {
+		if len(v) == 0 {
+			// nil value header is a marker which is not sent, such as X-Idempotency-Key
+			continue
 		}
-		ctx = context.WithValue(ctx, common.ContextHeaderKey{}, m)
-		for k := range req.Header {
-			m[k] = req.Header.Get(k)
+		m[k] = req.Header.Get(k)
+	}
+	return context.WithValue(ctx, common.ContextHeaderKey{}, m)
+}
+
+func (ri *RestInvoker) do(ctx context.Context, req *http.Request, opts InvokeOptions) (*http.Response, error) {
+	common.SetXCSEContext(map[string]string{common.HeaderSourceName: runtime.ServiceName}, req)
+	ctx = headerToContext(ctx, req)
+
+	if opts.ConnectionClose {
+		httputil.SetConnectionClose(req, true)
+	}
+	resp := rest.NewResponse()
+	inv := ri.newInvocation(ctx, req, resp, opts)
+
+	err := ri.invoke(inv)
+	if opts.CaptureRequest != nil {
+		*opts.CaptureRequest, _ = inv.Metadata[common.CapturedRequestKey].([]byte)
+	}
+	if opts.CaptureResponse != nil {
+		*opts.CaptureResponse, _ = inv.Metadata[common.CapturedResponseKey].([]byte)
+	}
+
+	if err == nil {
+		setCookieToCache(*inv, getNamespaceFromMetadata(opts.Metadata))
+	} else if opts.DumpOnError != nil {
+		if dump, ok := inv.Metadata[common.ErrorDumpKey].([]byte); ok {
+			opts.DumpOnError(dump)
 		}
 	}
+	return resp, err
+}
+
+// ResolveEndpoint runs route rules and load balancing for a request and returns the address it would be sent to,
+// request will not be sent, it is useful to diagnose routing config
+func (ri *RestInvoker) ResolveEndpoint(ctx context.Context, req *http.Request, options ...InvocationOption) (string, error) {
+	if req.URL.Scheme != HTTP {
+		return "", fmt.Errorf("scheme invalid: %s, only support {http}://", req.URL.Scheme)
+	}
+	opts, err := ri.invokeOptions(req.Host, options)
+	if err != nil {
+		return "", err
+	}
+	if opts.Endpoint != "" {
+		return opts.Endpoint, nil
+	}
+	inv := ri.newInvocation(headerToContext(ctx, req), req, rest.NewResponse(), opts)
+	if len(inv.Filters) == 0 {
+		inv.Filters = ri.opts.Filters
+	}
+	ep, err := handler.ResolveEndpoint(inv)
+	if err != nil {
+		return "", err
+	}
+	return ep.Address, nil
+}
 
-	opts := getOpts(req.Host, options...)
+func (ri *RestInvoker) newInvocation(ctx context.Context, req *http.Request, resp *http.Response, opts InvokeOptions) *invocation.Invocation {
 	service, port, _ := util.ParseServiceAndPort(req.Host)
 	opts.Protocol = common.ProtocolRest
 	opts.Port = port
 
-	resp := rest.NewResponse()
-
 	inv := invocation.New(ctx)
 
 	wrapInvocationWithOpts(inv, opts)
@@ -78,11 +193,5 @@ func (ri *RestInvoker) ContextDo(ctx context.Context, req *http.Request, options
 	inv.URLPath = req.URL.Path
 
 	inv.SetMetadata(common.RestMethod, req.Method)
-
-	err := ri.invoke(inv)
-
-	if err == nil {
-		setCookieToCache(*inv, getNamespaceFromMetadata(opts.Metadata))
-	}
-	return resp, err
+	return inv
 }
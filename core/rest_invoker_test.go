@@ -0,0 +1,630 @@
+package core_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-chassis/go-chassis/v2/client/rest"
+	"github.com/go-chassis/go-chassis/v2/control"
+	_ "github.com/go-chassis/go-chassis/v2/control/servicecomb"
+	"github.com/go-chassis/go-chassis/v2/core"
+	"github.com/go-chassis/go-chassis/v2/core/common"
+	"github.com/go-chassis/go-chassis/v2/core/config"
+	"github.com/go-chassis/go-chassis/v2/core/config/model"
+	"github.com/go-chassis/go-chassis/v2/core/handler"
+	"github.com/go-chassis/go-chassis/v2/core/invocation"
+	"github.com/go-chassis/go-chassis/v2/core/loadbalancer"
+	"github.com/go-chassis/go-chassis/v2/core/registry"
+	mk "github.com/go-chassis/go-chassis/v2/core/registry/mock"
+	"github.com/go-chassis/go-chassis/v2/core/router"
+	_ "github.com/go-chassis/go-chassis/v2/core/router/servicecomb"
+	"github.com/go-chassis/go-chassis/v2/pkg/util/httputil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestRestInvoker_MaxTotalDuration(t *testing.T) {
+	release := make(chan struct{})
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-time.After(time.Second):
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer s.Close()
+	defer close(release)
+	addr := strings.TrimPrefix(s.URL, "http://")
+	invoker := newTransportInvoker(t)
+
+	t.Run("total duration is exceeded", func(t *testing.T) {
+		req, _ := rest.NewRequest("GET", "http://SlowServer/", nil)
+		start := time.Now()
+		_, err := invoker.ContextDo(context.TODO(), req, core.WithEndpoint(addr),
+			core.WithMaxTotalDuration(100*time.Millisecond))
+		assert.Error(t, err)
+		assert.True(t, time.Since(start) < 500*time.Millisecond)
+	})
+	t.Run("context deadline is shorter", func(t *testing.T) {
+		req, _ := rest.NewRequest("GET", "http://SlowServer/", nil)
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		defer cancel()
+		start := time.Now()
+		_, err := invoker.ContextDo(ctx, req, core.WithEndpoint(addr),
+			core.WithMaxTotalDuration(10*time.Second))
+		assert.Error(t, err)
+		assert.True(t, time.Since(start) < 500*time.Millisecond)
+	})
+}
+
+func TestRestInvoker_Singleflight(t *testing.T) {
+	var calls int32
+	release := make(chan struct{})
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		<-release
+		w.Header().Set("X-Call", "shared")
+		w.Write([]byte("hot key"))
+	}))
+	defer s.Close()
+	addr := strings.TrimPrefix(s.URL, "http://")
+	invoker := newTransportInvoker(t)
+
+	var wg sync.WaitGroup
+	bodies := make([]string, 100)
+	for n := 0; n < 100; n++ {
+		wg.Add(1)
+		go func(n int) {
+			defer wg.Done()
+			req, _ := rest.NewRequest("GET", "http://FlightServer/hot", nil)
+			resp, err := invoker.ContextDo(context.TODO(), req, core.WithEndpoint(addr), core.WithSingleflight(true))
+			if assert.NoError(t, err) {
+				b, _ := ioutil.ReadAll(resp.Body)
+				resp.Body.Close()
+				bodies[n] = string(b) + resp.Header.Get("X-Call")
+			}
+		}(n)
+	}
+	time.Sleep(300 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+	for _, b := range bodies {
+		assert.Equal(t, "hot keyshared", b)
+	}
+
+	t.Run("non idempotent request is not coalesced", func(t *testing.T) {
+		atomic.StoreInt32(&calls, 0)
+		for n := 0; n < 2; n++ {
+			req, _ := rest.NewRequest("POST", "http://FlightServer/hot", []byte("a"))
+			_, err := invoker.ContextDo(context.TODO(), req, core.WithEndpoint(addr), core.WithSingleflight(true))
+			assert.NoError(t, err)
+		}
+		assert.Equal(t, int32(2), atomic.LoadInt32(&calls))
+	})
+	t.Run("each caller waits until its own context is done", func(t *testing.T) {
+		atomic.StoreInt32(&calls, 0)
+		release = make(chan struct{})
+		do := func(ctx context.Context) (*http.Response, error) {
+			req, _ := rest.NewRequest("GET", "http://FlightServer/slow", nil)
+			return invoker.ContextDo(ctx, req, core.WithEndpoint(addr), core.WithSingleflight(true))
+		}
+		leaderCtx, cancelLeader := context.WithCancel(context.Background())
+		leaderErr := make(chan error, 1)
+		go func() {
+			_, err := do(leaderCtx)
+			leaderErr <- err
+		}()
+		time.Sleep(50 * time.Millisecond)
+		patient := make(chan string, 1)
+		go func() {
+			resp, err := do(context.Background())
+			if assert.NoError(t, err) {
+				b, _ := ioutil.ReadAll(resp.Body)
+				resp.Body.Close()
+				patient <- string(b)
+			}
+		}()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+		start := time.Now()
+		_, err := do(ctx)
+		assert.Equal(t, context.DeadlineExceeded, err)
+		assert.True(t, time.Since(start) < 200*time.Millisecond)
+
+		cancelLeader()
+		assert.Equal(t, context.Canceled, <-leaderErr)
+		close(release)
+		assert.Equal(t, "hot key", <-patient, "shared call is not canceled by leader")
+		assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+	})
+	t.Run("shared call is canceled at total duration of caller which starts it", func(t *testing.T) {
+		atomic.StoreInt32(&calls, 0)
+		release = make(chan struct{})
+		do := func(opts ...core.InvocationOption) (*http.Response, error) {
+			req, _ := rest.NewRequest("GET", "http://FlightServer/bounded", nil)
+			return invoker.ContextDo(context.Background(), req,
+				append(opts, core.WithEndpoint(addr), core.WithSingleflight(true))...)
+		}
+		leaderErr := make(chan error, 1)
+		go func() {
+			_, err := do(core.WithMaxTotalDuration(300 * time.Millisecond))
+			leaderErr <- err
+		}()
+		assert.Eventually(t, func() bool { return atomic.LoadInt32(&calls) == 1 }, time.Second, 5*time.Millisecond)
+		patient := make(chan string, 1)
+		go func() {
+			resp, err := do()
+			if assert.NoError(t, err) {
+				b, _ := ioutil.ReadAll(resp.Body)
+				resp.Body.Close()
+				patient <- string(b)
+			}
+		}()
+		assert.Error(t, <-leaderErr)
+		assert.Eventually(t, func() bool { return atomic.LoadInt32(&calls) == 2 }, time.Second, 10*time.Millisecond,
+			"caller which has more time calls on its own once shared call is canceled")
+		close(release)
+		assert.Equal(t, "hot key", <-patient)
+
+		atomic.StoreInt32(&calls, 0)
+		_, err := do()
+		assert.NoError(t, err)
+		assert.Equal(t, int32(1), atomic.LoadInt32(&calls), "canceled call is not shared any more")
+	})
+}
+
+func TestRestInvoker_WithCoalesceWindow(t *testing.T) {
+	var calls int32
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&calls, 1)
+		if r.URL.Path == "/private" {
+			w.Header().Set("Cache-Control", "no-store")
+		}
+		fmt.Fprintf(w, "call %d", n)
+	}))
+	defer s.Close()
+	addr := strings.TrimPrefix(s.URL, "http://")
+	invoker := newTransportInvoker(t)
+	get := func(path string, header ...string) string {
+		req, _ := rest.NewRequest("GET", "http://WindowServer"+path, nil)
+		if len(header) > 0 {
+			req.Header.Set("Cache-Control", header[0])
+		}
+		resp, err := invoker.ContextDo(context.TODO(), req, core.WithEndpoint(addr), core.WithCoalesceWindow(200*time.Millisecond))
+		if !assert.NoError(t, err) {
+			return ""
+		}
+		b, _ := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		return string(b)
+	}
+
+	first := get("/hot")
+	assert.Equal(t, "call 1", first)
+	assert.Equal(t, first, get("/hot"), "request within window shares result")
+	assert.Equal(t, first, get("/hot"))
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+	time.Sleep(300 * time.Millisecond)
+	assert.Equal(t, "call 2", get("/hot"), "request after window calls server")
+
+	t.Run("no-store disables window", func(t *testing.T) {
+		atomic.StoreInt32(&calls, 0)
+		assert.Equal(t, "call 1", get("/private"))
+		assert.Equal(t, "call 2", get("/private"), "response has no-store")
+		assert.Equal(t, "call 3", get("/cold", "no-store"))
+		assert.Equal(t, "call 4", get("/cold", "no-store"), "request has no-store")
+	})
+}
+
+func TestRestInvoker_WithAdmission(t *testing.T) {
+	var inServer int32
+	release := make(chan struct{})
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&inServer, 1)
+		<-release
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer s.Close()
+	addr := strings.TrimPrefix(s.URL, "http://")
+	invoker := newTransportInvoker(t)
+	core.SetAdmissionLimit("AdmissionServer", 2)
+	defer core.SetAdmissionLimit("AdmissionServer", 0)
+
+	call := func(ctx context.Context) error {
+		req, _ := rest.NewRequest("GET", "http://AdmissionServer/", nil)
+		resp, err := invoker.ContextDo(ctx, req, core.WithEndpoint(addr), core.WithAdmission(1))
+		if err == nil {
+			resp.Body.Close()
+		}
+		return err
+	}
+	waitInServer := func(n int32) bool {
+		deadline := time.Now().Add(time.Second)
+		for time.Now().Before(deadline) {
+			if atomic.LoadInt32(&inServer) == n {
+				return true
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		return false
+	}
+	errs := make(chan error, 3)
+	for n := 0; n < 2; n++ {
+		go func() { errs <- call(context.TODO()) }()
+	}
+	assert.True(t, waitInServer(2))
+
+	t.Run("call waits until context deadline", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		defer cancel()
+		assert.Equal(t, context.DeadlineExceeded, call(ctx))
+	})
+
+	go func() { errs <- call(context.TODO()) }()
+	time.Sleep(200 * time.Millisecond)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&inServer), "third call should be blocked")
+	release <- struct{}{}
+	assert.NoError(t, <-errs)
+	assert.True(t, waitInServer(3), "third call should proceed once one completes")
+	close(release)
+	assert.NoError(t, <-errs)
+	assert.NoError(t, <-errs)
+}
+
+func TestRestInvoker_WithContentSniffing(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// prevent server from detecting content type
+		w.Header()["Content-Type"] = nil
+		switch r.URL.Path {
+		case "/json":
+			w.Write([]byte(`{"name":"a"}`))
+		case "/xml":
+			w.Write([]byte(`<user><name>a</name></user>`))
+		default:
+			w.Write([]byte(`name=a`))
+		}
+	}))
+	defer s.Close()
+	addr := strings.TrimPrefix(s.URL, "http://")
+	invoker := newTransportInvoker(t)
+	call := func(path string, options ...core.InvocationOption) (string, string) {
+		req, _ := rest.NewRequest("GET", "http://SniffServer"+path, nil)
+		req.Header.Set("Content-Type", "text/plain")
+		resp, err := invoker.ContextDo(context.TODO(), req, append(options, core.WithEndpoint(addr))...)
+		assert.NoError(t, err)
+		b, _ := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		return resp.Header.Get("Content-Type"), string(b)
+	}
+	ct, body := call("/json", core.WithContentSniffing(true))
+	assert.Equal(t, "application/json", ct)
+	assert.Equal(t, `{"name":"a"}`, body)
+	ct, body = call("/xml", core.WithContentSniffing(true))
+	assert.Equal(t, "application/xml", ct)
+	assert.Equal(t, `<user><name>a</name></user>`, body)
+	ct, _ = call("/form", core.WithContentSniffing(true))
+	assert.Equal(t, "text/plain", ct, "content type of request is the fallback")
+	ct, _ = call("/json")
+	assert.Equal(t, "", ct, "sniffing is off by default")
+}
+
+func TestRestInvoker_WithPriority(t *testing.T) {
+	var mu sync.Mutex
+	var order []string
+	release := make(chan struct{})
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		order = append(order, r.URL.Path)
+		mu.Unlock()
+		<-release
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer s.Close()
+	addr := strings.TrimPrefix(s.URL, "http://")
+	invoker := newTransportInvoker(t)
+	core.SetAdmissionLimit("PriorityServer", 1)
+	defer core.SetAdmissionLimit("PriorityServer", 0)
+
+	errs := make(chan error, 5)
+	call := func(path string, p core.Priority) {
+		req, _ := rest.NewRequest("GET", "http://PriorityServer"+path, nil)
+		resp, err := invoker.ContextDo(context.TODO(), req, core.WithEndpoint(addr),
+			core.WithAdmission(1), core.WithPriority(p))
+		if err == nil {
+			resp.Body.Close()
+		}
+		errs <- err
+	}
+	go call("/first", core.PriorityNormal)
+	time.Sleep(100 * time.Millisecond)
+	for _, path := range []string{"/bulk1", "/bulk2", "/bulk3"} {
+		go call(path, core.PriorityNormal)
+		time.Sleep(50 * time.Millisecond)
+	}
+	go call("/health", core.PriorityHigh)
+	time.Sleep(50 * time.Millisecond)
+	for n := 0; n < 5; n++ {
+		release <- struct{}{}
+		assert.NoError(t, <-errs)
+	}
+	assert.Equal(t, []string{"/first", "/health", "/bulk1", "/bulk2", "/bulk3"}, order)
+}
+
+func TestRestInvoker_WithCapture(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := ioutil.ReadAll(r.Body)
+		w.Header().Set("X-Echo", r.Header.Get("X-Trace"))
+		w.WriteHeader(http.StatusCreated)
+		w.Write(append([]byte("echo:"), b...))
+	}))
+	defer s.Close()
+	addr := strings.TrimPrefix(s.URL, "http://")
+	invoker := newTransportInvoker(t)
+
+	req, _ := rest.NewRequest("POST", "http://CaptureServer/orders", []byte(`{"id":1}`))
+	req.Header.Set("X-Trace", "abc")
+	req.Header.Set("Authorization", "Bearer secret")
+	var reqOut, respOut []byte
+	resp, err := invoker.ContextDo(context.TODO(), req, core.WithEndpoint(addr), core.WithCapture(&reqOut, &respOut))
+	assert.NoError(t, err)
+	b, _ := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	assert.Equal(t, `echo:{"id":1}`, string(b))
+
+	assert.Contains(t, string(reqOut), "POST /orders HTTP/1.1\r\n")
+	assert.Contains(t, string(reqOut), "X-Trace: abc\r\n")
+	assert.Contains(t, string(reqOut), "Authorization: Bearer secret\r\n")
+	assert.True(t, strings.HasSuffix(string(reqOut), "\r\n\r\n"+`{"id":1}`))
+	assert.Contains(t, string(respOut), "HTTP/1.1 201 Created\r\n")
+	assert.Contains(t, string(respOut), "X-Echo: abc\r\n")
+	assert.True(t, strings.HasSuffix(string(respOut), `echo:{"id":1}`))
+
+	t.Run("metadata of caller is not changed", func(t *testing.T) {
+		md := map[string]interface{}{"tenant": "a"}
+		var wg sync.WaitGroup
+		for n := 0; n < 10; n++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				req, _ := rest.NewRequest("GET", "http://CaptureServer/orders", nil)
+				var reqOut []byte
+				_, err := invoker.ContextDo(context.TODO(), req, core.WithEndpoint(addr), core.WithMetadata(md),
+					core.WithCapture(&reqOut, nil), core.WithDumpOnError(func([]byte) {}))
+				assert.NoError(t, err)
+				assert.NotEmpty(t, reqOut)
+			}()
+		}
+		wg.Wait()
+		assert.Equal(t, map[string]interface{}{"tenant": "a"}, md)
+	})
+	t.Run("only response", func(t *testing.T) {
+		req, _ := rest.NewRequest("GET", "http://CaptureServer/orders", nil)
+		var respOut []byte
+		_, err := invoker.ContextDo(context.TODO(), req, core.WithEndpoint(addr), core.WithCapture(nil, &respOut))
+		assert.NoError(t, err)
+		assert.Contains(t, string(respOut), "201 Created")
+	})
+}
+
+func TestRestInvoker_ResolveEndpoint(t *testing.T) {
+	v1 := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("1.0"))
+	}))
+	defer v1.Close()
+	v2 := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("2.0"))
+	}))
+	defer v2.Close()
+	initenv()
+	config.GlobalDefinition = &model.GlobalCfg{}
+	assert.NoError(t, control.Init(control.Options{}))
+	assert.NoError(t, router.Init())
+	router.DefaultRouter.SetRouteRule(map[string][]*config.RouteRule{"RouteServer": {{
+		Precedence: 1,
+		Routes:     []*config.RouteTag{{Tags: map[string]string{"version": "2.0"}, Weight: 100}},
+		Match:      config.Match{Headers: map[string]map[string]string{"Os": {"exact": "ios"}}},
+	}}})
+	defer router.DefaultRouter.SetRouteRule(map[string][]*config.RouteRule{})
+	loadbalancer.Enable(loadbalancer.StrategyRoundRobin)
+	d := new(mk.DiscoveryMock)
+	old := registry.DefaultServiceDiscoveryService
+	registry.DefaultServiceDiscoveryService = d
+	defer func() { registry.DefaultServiceDiscoveryService = old }()
+	instance := func(id, addr string) []*registry.MicroServiceInstance {
+		return []*registry.MicroServiceInstance{{InstanceID: id,
+			EndpointsMap: map[string]*registry.Endpoint{"rest": {Address: strings.TrimPrefix(addr, "http://")}}}}
+	}
+	d.On("FindMicroServiceInstances", mock.Anything, mock.Anything, "RouteServer", "", "").Return(instance("v1", v1.URL), nil)
+	d.On("FindMicroServiceInstances", mock.Anything, mock.Anything, "RouteServer", "2.0", "").Return(instance("v2", v2.URL), nil)
+	err := handler.CreateChains(common.Consumer, map[string]string{"routing": strings.Join(
+		[]string{handler.Router, handler.Loadbalance, handler.Transport}, ",")})
+	assert.NoError(t, err)
+	invoker := core.NewRestInvoker(core.ChainName("routing"))
+
+	for _, os := range []string{"android", "ios"} {
+		req, _ := rest.NewRequest("GET", "http://RouteServer/", nil)
+		req.Header.Set("Os", os)
+		addr, err := invoker.ResolveEndpoint(context.TODO(), req)
+		assert.NoError(t, err)
+		resp, err := invoker.ContextDo(context.TODO(), req)
+		if assert.NoError(t, err, os) {
+			b, _ := ioutil.ReadAll(resp.Body)
+			resp.Body.Close()
+			want := map[string]string{"android": v1.URL, "ios": v2.URL}[os]
+			assert.Equal(t, strings.TrimPrefix(want, "http://"), addr, os)
+			assert.Equal(t, map[string]string{"android": "1.0", "ios": "2.0"}[os], string(b), os)
+		}
+	}
+}
+
+func TestRestInvoker_IdempotentMarker(t *testing.T) {
+	var marker []string
+	var hasMarker bool
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		marker, hasMarker = r.Header[httputil.HeaderXIdempotencyKey]
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer s.Close()
+	addr := strings.TrimPrefix(s.URL, "http://")
+	invoker := newTransportInvoker(t)
+
+	req, _ := rest.NewRequest(http.MethodPost, "http://OrderServer/orders", []byte("order"))
+	httputil.SetIdempotent(req, true)
+	resp, err := invoker.ContextDo(context.TODO(), req, core.WithEndpoint(addr))
+	assert.NoError(t, err)
+	resp.Body.Close()
+	assert.False(t, hasMarker, "marker header should not be sent, got %v", marker)
+}
+
+func TestRestInvoker_Middleware(t *testing.T) {
+	var header string
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header = r.Header.Get("X-Trace")
+		time.Sleep(10 * time.Millisecond)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer s.Close()
+	addr := strings.TrimPrefix(s.URL, "http://")
+	invoker := newTransportInvoker(t)
+
+	var order []string
+	var elapsed time.Duration
+	addHeader := func(next core.CallFunc) core.CallFunc {
+		return func(ctx context.Context, req *http.Request) (*http.Response, error) {
+			order = append(order, "header")
+			req.Header.Set("X-Trace", "1")
+			return next(ctx, req)
+		}
+	}
+	measure := func(next core.CallFunc) core.CallFunc {
+		return func(ctx context.Context, req *http.Request) (*http.Response, error) {
+			order = append(order, "measure")
+			start := time.Now()
+			resp, err := next(ctx, req)
+			elapsed = time.Since(start)
+			return resp, err
+		}
+	}
+	req, _ := rest.NewRequest(http.MethodGet, "http://OrderServer/orders", nil)
+	resp, err := invoker.ContextDo(context.TODO(), req, core.WithEndpoint(addr),
+		core.WithMiddleware(measure), core.WithMiddleware(addHeader))
+	assert.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, "1", header)
+	assert.Equal(t, []string{"measure", "header"}, order)
+	assert.True(t, elapsed >= 10*time.Millisecond, elapsed)
+
+	t.Run("short circuit", func(t *testing.T) {
+		header = ""
+		cached := func(next core.CallFunc) core.CallFunc {
+			return func(ctx context.Context, req *http.Request) (*http.Response, error) {
+				return &http.Response{StatusCode: http.StatusNoContent, Body: http.NoBody}, nil
+			}
+		}
+		req, _ := rest.NewRequest(http.MethodGet, "http://OrderServer/orders", nil)
+		resp, err := invoker.ContextDo(context.TODO(), req, core.WithEndpoint(addr), core.WithMiddleware(cached, addHeader))
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+		assert.Empty(t, header, "request should not be sent")
+	})
+}
+
+func TestRestInvoker_Profile(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer s.Close()
+	addr := strings.TrimPrefix(s.URL, "http://")
+	invoker := newTransportInvoker(t)
+	invoker.RegisterProfile("fast-read", core.WithEndpoint(addr), core.WithMaxTotalDuration(50*time.Millisecond))
+	invoker.RegisterProfile("bulk", core.WithEndpoint(addr), core.WithMaxTotalDuration(5*time.Second))
+
+	call := func(options ...core.InvocationOption) error {
+		req, _ := rest.NewRequest(http.MethodGet, "http://OrderServer/orders", nil)
+		resp, err := invoker.ContextDo(context.TODO(), req, options...)
+		if err == nil {
+			resp.Body.Close()
+		}
+		return err
+	}
+	assert.Error(t, call(core.WithProfile("fast-read")), "fast-read times out")
+	assert.NoError(t, call(core.WithProfile("bulk")))
+	assert.NoError(t, call(core.WithProfile("fast-read"), core.WithMaxTotalDuration(5*time.Second)),
+		"option of call overrides profile")
+	err := call(core.WithProfile("missing"))
+	assert.True(t, errors.Is(err, core.ErrUnknownProfile), "got %v", err)
+}
+
+// endStrategy picks the first or the last instance
+type endStrategy struct {
+	last      bool
+	instances []*registry.MicroServiceInstance
+}
+
+func (s *endStrategy) ReceiveData(inv *invocation.Invocation, instances []*registry.MicroServiceInstance, serviceKey string) {
+	s.instances = instances
+}
+
+func (s *endStrategy) Pick() (*registry.MicroServiceInstance, error) {
+	if len(s.instances) == 0 {
+		return nil, loadbalancer.ErrNoneAvailableInstance
+	}
+	if s.last {
+		return s.instances[len(s.instances)-1], nil
+	}
+	return s.instances[0], nil
+}
+
+func TestRestInvoker_RegisterServiceStrategy(t *testing.T) {
+	initenv()
+	config.GlobalDefinition = &model.GlobalCfg{}
+	assert.NoError(t, control.Init(control.Options{}))
+	assert.NoError(t, router.Init())
+	loadbalancer.Enable(loadbalancer.StrategyRoundRobin)
+	loadbalancer.InstallStrategy("first", func() loadbalancer.Strategy { return &endStrategy{} })
+	loadbalancer.InstallStrategy("last", func() loadbalancer.Strategy { return &endStrategy{last: true} })
+	d := new(mk.DiscoveryMock)
+	old := registry.DefaultServiceDiscoveryService
+	registry.DefaultServiceDiscoveryService = d
+	defer func() { registry.DefaultServiceDiscoveryService = old }()
+	instances := func(addrs ...string) []*registry.MicroServiceInstance {
+		var ins []*registry.MicroServiceInstance
+		for _, addr := range addrs {
+			ins = append(ins, &registry.MicroServiceInstance{InstanceID: addr,
+				EndpointsMap: map[string]*registry.Endpoint{"rest": {Address: addr}}})
+		}
+		return ins
+	}
+	d.On("FindMicroServiceInstances", mock.Anything, mock.Anything, "OrderServer", mock.Anything, "").
+		Return(instances("10.0.0.1:8080", "10.0.0.2:8080"), nil)
+	d.On("FindMicroServiceInstances", mock.Anything, mock.Anything, "StockServer", mock.Anything, "").
+		Return(instances("10.0.1.1:8080", "10.0.1.2:8080"), nil)
+
+	invoker := core.NewRestInvoker()
+	assert.NoError(t, invoker.RegisterServiceStrategy("OrderServer", "first"))
+	assert.NoError(t, invoker.RegisterServiceStrategy("StockServer", "last"))
+	assert.Error(t, invoker.RegisterServiceStrategy("StockServer", "nonexistent"), "strategy must be installed")
+	resolve := func(service string, options ...core.InvocationOption) string {
+		req, _ := rest.NewRequest(http.MethodGet, "http://"+service+"/items", nil)
+		addr, err := invoker.ResolveEndpoint(context.TODO(), req, options...)
+		assert.NoError(t, err)
+		return addr
+	}
+	for n := 0; n < 3; n++ {
+		assert.Equal(t, "10.0.0.1:8080", resolve("OrderServer"))
+		assert.Equal(t, "10.0.1.2:8080", resolve("StockServer"), "invalid strategy does not replace the registered one")
+	}
+	assert.Equal(t, "10.0.0.2:8080", resolve("OrderServer", core.WithStrategy("last")), "strategy of call wins")
+}
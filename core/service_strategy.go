@@ -0,0 +1,44 @@
+package core
+
+import (
+	"sync"
+
+	"github.com/go-chassis/go-chassis/v2/core/loadbalancer"
+	"github.com/go-chassis/go-chassis/v2/pkg/util"
+)
+
+// serviceStrategies are default load balancing strategies of services called by an invoker
+type serviceStrategies struct {
+	m  map[string]string
+	mu sync.RWMutex
+}
+
+func (s *serviceStrategies) get(service string) string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.m[service]
+}
+
+// RegisterServiceStrategy sets the default strategy of calls to service by this invoker, it overrides load balancing config,
+// WithStrategy of a call still wins. it fails if strategy is not installed, registering a service again replaces its strategy
+func (ri *RestInvoker) RegisterServiceStrategy(service, strategy string) error {
+	if _, err := loadbalancer.GetStrategyPlugin(strategy); err != nil {
+		return err
+	}
+	ri.strategies.mu.Lock()
+	defer ri.strategies.mu.Unlock()
+	if ri.strategies.m == nil {
+		ri.strategies.m = make(map[string]string)
+	}
+	ri.strategies.m[service] = strategy
+	return nil
+}
+
+// applyServiceStrategy sets strategy of service to options if call does not choose one
+func (ri *RestInvoker) applyServiceStrategy(host string, opts *InvokeOptions) {
+	if opts.StrategyFunc != "" {
+		return
+	}
+	service, _, _ := util.ParseServiceAndPort(host)
+	opts.StrategyFunc = ri.strategies.get(service)
+}
@@ -0,0 +1,185 @@
+package core
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// flightGroup coalesces concurrent identical requests into one call,
+// response body is buffered so that every caller gets its own copy.
+// with a coalesce window, a finished call is kept for the window and shared by requests arriving in it
+type flightGroup struct {
+	mu    sync.Mutex
+	calls map[string]*flightCall
+}
+
+type flightCall struct {
+	done chan struct{}
+	resp *http.Response
+	body []byte
+	err  error
+	// timedOut is set when call is stopped by deadline of the caller which starts it
+	timedOut bool
+	// expires is set when call finishes and its result is kept for a window
+	expires time.Time
+}
+
+func newFlightGroup() *flightGroup {
+	return &flightGroup{calls: make(map[string]*flightCall)}
+}
+
+// canCoalesce returns true for idempotent read requests without body,
+// requests which want their own raw request, response or decision trace can not share a call
+func canCoalesce(req *http.Request, opts InvokeOptions) bool {
+	if req.Method != http.MethodGet && req.Method != http.MethodHead {
+		return false
+	}
+	if opts.CaptureRequest != nil || opts.CaptureResponse != nil || opts.DumpOnError != nil || opts.DecisionTrace != nil {
+		return false
+	}
+	return req.Body == nil || req.Body == http.NoBody
+}
+
+// storable returns false if Cache-Control of header has no-store or no-cache,
+// such a request or response must not be shared after call finishes
+func storable(h http.Header) bool {
+	for _, v := range h["Cache-Control"] {
+		for _, d := range strings.Split(v, ",") {
+			d = strings.ToLower(strings.TrimSpace(d))
+			if d == "no-store" || d == "no-cache" {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// flightKey is method, url, endpoint, isolation key and all headers of request,
+// so that requests with different credential or tenant never share a response
+func flightKey(req *http.Request, opts InvokeOptions) string {
+	keys := make([]string, 0, len(req.Header))
+	for k := range req.Header {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var b strings.Builder
+	b.WriteString(req.Method)
+	b.WriteString(" ")
+	b.WriteString(req.URL.String())
+	b.WriteString(" ")
+	b.WriteString(opts.Endpoint)
+	b.WriteString(" ")
+	b.WriteString(opts.IsolationKey)
+	for _, k := range keys {
+		b.WriteString("\n")
+		b.WriteString(k)
+		b.WriteString(":")
+		b.WriteString(strings.Join(req.Header[k], ","))
+	}
+	return b.String()
+}
+
+// do runs fn once for concurrent callers of same key, fn runs on a context which is not canceled
+// by any caller, so that one caller giving up does not fail the others.
+// it has the deadline of the caller which starts it, such as of WithMaxTotalDuration, so that the shared call
+// and its retries are not longer than the call would be without singleflight. a caller which has time
+// after the deadline calls fn on its own. every caller waits for the result until its own ctx is done.
+// if window is positive, a successful result is kept for window after fn returns, unless the response is not storable
+func (g *flightGroup) do(ctx context.Context, key string, window time.Duration,
+	fn func(ctx context.Context) (*http.Response, error)) (*http.Response, error) {
+	g.mu.Lock()
+	c, ok := g.calls[key]
+	if ok && !c.expires.IsZero() && !time.Now().Before(c.expires) {
+		ok = false
+	}
+	if !ok {
+		c = &flightCall{done: make(chan struct{})}
+		g.calls[key] = c
+		callCtx, cancel := context.WithCancel(detach(ctx))
+		if deadline, ok := ctx.Deadline(); ok {
+			callCtx, cancel = context.WithDeadline(detach(ctx), deadline)
+		}
+		go g.run(callCtx, cancel, key, window, c, fn)
+	}
+	g.mu.Unlock()
+
+	select {
+	case <-c.done:
+		if c.timedOut && ctx.Err() == nil {
+			return fn(ctx)
+		}
+		return c.result()
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (g *flightGroup) run(ctx context.Context, cancel context.CancelFunc, key string, window time.Duration, c *flightCall,
+	fn func(ctx context.Context) (*http.Response, error)) {
+	defer cancel()
+	defer func() {
+		if r := recover(); r != nil {
+			c.resp, c.err = nil, fmt.Errorf("shared call panics: %v", r)
+		}
+		g.mu.Lock()
+		if window > 0 && c.err == nil && c.resp != nil && storable(c.resp.Header) {
+			c.expires = time.Now().Add(window)
+			time.AfterFunc(window, func() { g.forget(key, c) })
+		} else {
+			delete(g.calls, key)
+		}
+		g.mu.Unlock()
+		close(c.done)
+	}()
+	c.resp, c.err = fn(ctx)
+	if c.resp != nil && c.resp.Body != nil {
+		var readErr error
+		c.body, readErr = ioutil.ReadAll(c.resp.Body)
+		c.resp.Body.Close()
+		if readErr != nil && c.err == nil {
+			c.err = readErr
+		}
+	}
+	c.timedOut = c.err != nil && ctx.Err() != nil
+}
+
+// forget removes kept call c, key may already belong to a newer call
+func (g *flightGroup) forget(key string, c *flightCall) {
+	g.mu.Lock()
+	if g.calls[key] == c {
+		delete(g.calls, key)
+	}
+	g.mu.Unlock()
+}
+
+// detachedContext keeps values of parent, but it is never canceled and has no deadline
+type detachedContext struct {
+	parent context.Context
+}
+
+func detach(ctx context.Context) context.Context {
+	return detachedContext{parent: ctx}
+}
+
+func (detachedContext) Deadline() (time.Time, bool)         { return time.Time{}, false }
+func (detachedContext) Done() <-chan struct{}               { return nil }
+func (detachedContext) Err() error                          { return nil }
+func (d detachedContext) Value(key interface{}) interface{} { return d.parent.Value(key) }
+
+// result returns a copy of shared response with its own body reader
+func (c *flightCall) result() (*http.Response, error) {
+	if c.resp == nil {
+		return nil, c.err
+	}
+	r := *c.resp
+	r.Header = c.resp.Header.Clone()
+	r.Body = ioutil.NopCloser(bytes.NewReader(c.body))
+	return &r, c.err
+}
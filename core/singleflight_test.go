@@ -0,0 +1,34 @@
+package core
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/go-chassis/go-chassis/v2/core/invocation"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFlightGroup_Panic(t *testing.T) {
+	g := newFlightGroup()
+	_, err := g.do(context.Background(), "key", 0, func(ctx context.Context) (*http.Response, error) {
+		panic("boom")
+	})
+	assert.EqualError(t, err, "shared call panics: boom")
+	assert.Empty(t, g.calls, "key is removed after panic")
+
+	resp, err := g.do(context.Background(), "key", 0, func(ctx context.Context) (*http.Response, error) {
+		return &http.Response{StatusCode: http.StatusOK, Header: http.Header{}}, nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+}
+
+func TestCanCoalesce(t *testing.T) {
+	req, _ := http.NewRequest(http.MethodGet, "http://Server/", nil)
+	assert.True(t, canCoalesce(req, InvokeOptions{}))
+	var out []byte
+	assert.False(t, canCoalesce(req, InvokeOptions{CaptureResponse: &out}))
+	assert.False(t, canCoalesce(req, InvokeOptions{DumpOnError: func([]byte) {}}))
+	assert.False(t, canCoalesce(req, InvokeOptions{DecisionTrace: &invocation.DecisionTrace{}}))
+}
@@ -0,0 +1,96 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/go-chassis/go-chassis/v2/pkg/util/httputil"
+	"github.com/go-chassis/openlog"
+)
+
+// TokenProvider returns a new bearer token, prevToken is the token which is rejected by server,
+// it is empty if request did not carry a bearer token
+type TokenProvider func(ctx context.Context, prevToken string) (string, error)
+
+const bearerPrefix = "Bearer "
+
+// needTokenRefresh returns true if server rejects request with a Bearer challenge
+func needTokenRefresh(resp *http.Response) bool {
+	if resp == nil || resp.StatusCode != http.StatusUnauthorized {
+		return false
+	}
+	for _, c := range httputil.WWWAuthenticate(resp) {
+		if strings.EqualFold(c.Scheme, "Bearer") {
+			return true
+		}
+	}
+	return false
+}
+
+// retryWithNewToken refresh token once and send request again with the new token,
+// if token can not be refreshed or request body can not be replayed, the first response is returned
+func (ri *RestInvoker) retryWithNewToken(ctx context.Context, req *http.Request, opts InvokeOptions,
+	resp *http.Response, err error) (*http.Response, error) {
+	if req.Body != nil && req.Body != http.NoBody {
+		if req.GetBody == nil {
+			openlog.Warn("request body can not be replayed, skip token refresh")
+			return resp, err
+		}
+		body, bodyErr := req.GetBody()
+		if bodyErr != nil {
+			return resp, err
+		}
+		req.Body = body
+	}
+	prev := strings.TrimPrefix(req.Header.Get("Authorization"), bearerPrefix)
+	token, refreshErr := ri.refresher.refresh(ctx, prev, opts.TokenProvider)
+	if refreshErr != nil {
+		openlog.Error(fmt.Sprintf("can not refresh token: %s", refreshErr))
+		return resp, err
+	}
+	if resp.Body != nil {
+		resp.Body.Close()
+	}
+	req.Header.Set("Authorization", bearerPrefix+token)
+	return ri.do(ctx, req, opts)
+}
+
+// tokenRefresher makes sure concurrent calls rejected with the same token share one refresh
+type tokenRefresher struct {
+	mu    sync.Mutex
+	calls map[string]*refreshCall
+}
+
+type refreshCall struct {
+	wg    sync.WaitGroup
+	token string
+	err   error
+}
+
+func newTokenRefresher() *tokenRefresher {
+	return &tokenRefresher{calls: make(map[string]*refreshCall)}
+}
+
+func (r *tokenRefresher) refresh(ctx context.Context, prev string, p TokenProvider) (string, error) {
+	r.mu.Lock()
+	if c, ok := r.calls[prev]; ok {
+		r.mu.Unlock()
+		c.wg.Wait()
+		return c.token, c.err
+	}
+	c := &refreshCall{}
+	c.wg.Add(1)
+	r.calls[prev] = c
+	r.mu.Unlock()
+
+	c.token, c.err = p(ctx, prev)
+	c.wg.Done()
+
+	r.mu.Lock()
+	delete(r.calls, prev)
+	r.mu.Unlock()
+	return c.token, c.err
+}
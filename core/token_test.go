@@ -0,0 +1,97 @@
+package core_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-chassis/go-chassis/v2/client/rest"
+	"github.com/go-chassis/go-chassis/v2/core"
+	"github.com/go-chassis/go-chassis/v2/core/common"
+	"github.com/go-chassis/go-chassis/v2/core/config"
+	"github.com/go-chassis/go-chassis/v2/core/config/model"
+	"github.com/go-chassis/go-chassis/v2/core/handler"
+	"github.com/stretchr/testify/assert"
+)
+
+// newTransportInvoker returns an invoker which sends request to endpoint directly
+func newTransportInvoker(t *testing.T) *core.RestInvoker {
+	initenv()
+	config.GlobalDefinition = &model.GlobalCfg{}
+	err := handler.CreateChains(common.Consumer, map[string]string{"transport-only": handler.Transport})
+	assert.NoError(t, err)
+	return core.NewRestInvoker(core.ChainName("transport-only"))
+}
+
+func TestWithTokenProvider(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer new" {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="test", error="invalid_token"`)
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer s.Close()
+	addr := strings.TrimPrefix(s.URL, "http://")
+	invoker := newTransportInvoker(t)
+
+	var refreshed int32
+	provider := func(ctx context.Context, prevToken string) (string, error) {
+		atomic.AddInt32(&refreshed, 1)
+		assert.Equal(t, "old", prevToken)
+		return "new", nil
+	}
+	t.Run("first call is rejected, token is refreshed and retry succeeds", func(t *testing.T) {
+		req, _ := rest.NewRequest("POST", "http://TokenServer/", []byte("body"))
+		req.Header.Set("Authorization", "Bearer old")
+		resp, err := invoker.ContextDo(context.TODO(), req, core.WithEndpoint(addr), core.WithTokenProvider(provider))
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, int32(1), atomic.LoadInt32(&refreshed))
+	})
+	t.Run("provider is called once per call", func(t *testing.T) {
+		atomic.StoreInt32(&refreshed, 0)
+		req, _ := rest.NewRequest("GET", "http://TokenServer/", nil)
+		req.Header.Set("Authorization", "Bearer old")
+		resp, err := invoker.ContextDo(context.TODO(), req, core.WithEndpoint(addr),
+			core.WithTokenProvider(func(ctx context.Context, prevToken string) (string, error) {
+				atomic.AddInt32(&refreshed, 1)
+				return "still-wrong", nil
+			}))
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+		assert.Equal(t, int32(1), atomic.LoadInt32(&refreshed))
+	})
+	t.Run("concurrent calls share one refresh", func(t *testing.T) {
+		atomic.StoreInt32(&refreshed, 0)
+		start := make(chan struct{})
+		slowProvider := func(ctx context.Context, prevToken string) (string, error) {
+			<-start
+			atomic.AddInt32(&refreshed, 1)
+			return "new", nil
+		}
+		var wg sync.WaitGroup
+		for n := 0; n < 10; n++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				req, _ := rest.NewRequest("GET", "http://TokenServer/", nil)
+				req.Header.Set("Authorization", "Bearer old")
+				resp, err := invoker.ContextDo(context.TODO(), req, core.WithEndpoint(addr), core.WithTokenProvider(slowProvider))
+				assert.NoError(t, err)
+				assert.Equal(t, http.StatusOK, resp.StatusCode)
+			}()
+		}
+		// let all calls be rejected before the refresh finishes
+		time.Sleep(200 * time.Millisecond)
+		close(start)
+		wg.Wait()
+		assert.Equal(t, int32(1), atomic.LoadInt32(&refreshed))
+	})
+}
@@ -100,6 +100,7 @@ func FallbackErr(inv *invocation.Invocation, finish chan *invocation.Response) f
 				Message: fmt.Sprintf("API %s:%s:%s is isolated because of error: %s", inv.MicroServiceName,
 					inv.SchemaID, inv.OperationID, err.Error()),
 			}
+			inv.Trace(invocation.EventCircuitOpen, inv.Endpoint, err)
 		} else if err.Error() == hystrix.ErrMaxConcurrency.Error() {
 			// isolation happened, so lead to callback
 			openlog.Error(fmt.Sprintf("fallback for %s:%s:%s, error [%s]",
@@ -109,6 +110,7 @@ func FallbackErr(inv *invocation.Invocation, finish chan *invocation.Response) f
 				Message: fmt.Sprintf("API %s:%s:%s is reject because of error: %s", inv.MicroServiceName,
 					inv.SchemaID, inv.OperationID, err.Error()),
 			}
+			inv.Trace(invocation.EventCircuitOpen, inv.Endpoint, err)
 
 		} else {
 			//do nothing, just give original error
@@ -0,0 +1,62 @@
+package circuit
+
+import (
+	"sync"
+	"time"
+
+	"github.com/go-chassis/go-chassis/v2/pkg/metrics"
+	"github.com/go-chassis/go-chassis/v2/third_party/forked/afex/hystrix-go/hystrix"
+	"github.com/go-chassis/openlog"
+)
+
+// gauges of circuit breakers, they are labeled by circuit name,
+// with scope instance or instance-api a circuit protects one endpoint, so that gauges are per host
+const (
+	// MetricCircuitState is the state of circuit, 0 is closed, 1 is open, 2 is half open
+	MetricCircuitState = "circuit_breaker_state"
+	// MetricCircuitFailureRatio is errors divided by requests in rolling window
+	MetricCircuitFailureRatio = "circuit_breaker_failure_ratio"
+	// MetricCircuitRequests is request volume in rolling window
+	MetricCircuitRequests = "circuit_breaker_requests"
+)
+
+// GaugeReporter is the name of reporter which exports circuit gauges
+const GaugeReporter = "CircuitGauges"
+
+var gaugeOnce sync.Once
+
+func init() {
+	if err := hystrix.InstallReporter(GaugeReporter, ReportGauges); err != nil {
+		openlog.Error(err.Error())
+	}
+}
+
+// ReportGauges sets gauges of a circuit breaker to current state and window statistics,
+// it is called for every circuit by hystrix reporter periodically.
+// a pull sink is scraped by its own, a push sink sends the values set here.
+// it does nothing if metrics registry is not initialized
+func ReportGauges(cb *hystrix.CircuitBreaker) error {
+	if !metrics.Enabled() {
+		return nil
+	}
+	gaugeOnce.Do(func() {
+		for name, help := range map[string]string{
+			MetricCircuitState:        "state of circuit breaker, 0 is closed, 1 is open, 2 is half open",
+			MetricCircuitFailureRatio: "errors divided by requests in rolling window of circuit breaker",
+			MetricCircuitRequests:     "request volume in rolling window of circuit breaker",
+		} {
+			if err := metrics.CreateGauge(metrics.GaugeOpts{Name: name, Help: help, Labels: []string{"circuit"}}); err != nil {
+				openlog.Error(err.Error())
+			}
+		}
+	})
+	s := cb.Stats(time.Now())
+	labels := map[string]string{"circuit": s.Name}
+	if err := metrics.GaugeSet(MetricCircuitState, float64(s.State), labels); err != nil {
+		return err
+	}
+	if err := metrics.GaugeSet(MetricCircuitFailureRatio, s.FailureRatio, labels); err != nil {
+		return err
+	}
+	return metrics.GaugeSet(MetricCircuitRequests, s.Requests, labels)
+}
@@ -0,0 +1,92 @@
+package circuit_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/go-chassis/go-archaius"
+	"github.com/go-chassis/go-chassis/v2/middleware/circuit"
+	"github.com/go-chassis/go-chassis/v2/pkg/metrics"
+	"github.com/go-chassis/go-chassis/v2/third_party/forked/afex/hystrix-go/hystrix"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReportGauges(t *testing.T) {
+	archaius.Init(archaius.WithMemorySource())
+	if !metrics.Enabled() {
+		assert.NoError(t, metrics.Init())
+	}
+	gauge := func(name, circuitName string) float64 {
+		mfs, err := metrics.GetSystemPrometheusRegistry().Gather()
+		assert.NoError(t, err)
+		for _, mf := range mfs {
+			if mf.GetName() != name {
+				continue
+			}
+			for _, m := range mf.GetMetric() {
+				for _, l := range m.GetLabel() {
+					if l.GetName() == "circuit" && l.GetValue() == circuitName {
+						return m.GetGauge().GetValue()
+					}
+				}
+			}
+		}
+		return -1
+	}
+	call := func(name string, fail bool) {
+		hystrix.Do(name, func() error {
+			if fail {
+				return errors.New("induced failure")
+			}
+			return nil
+		}, nil)
+	}
+	report := func(name string, requests float64) {
+		cb, _, err := hystrix.GetCircuit(name)
+		assert.NoError(t, err)
+		// metrics of calls are counted asynchronously
+		assert.Eventually(t, func() bool {
+			return cb.Stats(time.Now()).Requests == requests
+		}, 3*time.Second, 10*time.Millisecond)
+		assert.NoError(t, circuit.ReportGauges(cb))
+	}
+
+	t.Run("failure ratio below threshold keeps circuit closed", func(t *testing.T) {
+		name := "Consumer.gauge.127.0.0.1:8080"
+		hystrix.FlushByName(name)
+		hystrix.ConfigureCommand(name, hystrix.CommandConfig{RequestVolumeThreshold: 20, ErrorPercentThreshold: 50,
+			SleepWindow: 10000, MaxConcurrentRequests: 100, CircuitBreakerEnabled: true, MetricsConsumerNum: 1})
+		for n := 0; n < 10; n++ {
+			call(name, n < 3)
+		}
+		report(name, 10)
+		assert.Equal(t, float64(hystrix.StateClosed), gauge(circuit.MetricCircuitState, name))
+		assert.InDelta(t, 0.3, gauge(circuit.MetricCircuitFailureRatio, name), 0.001)
+		assert.Equal(t, float64(10), gauge(circuit.MetricCircuitRequests, name))
+
+		for n := 0; n < 6; n++ {
+			call(name, true)
+		}
+		report(name, 16)
+		assert.Equal(t, float64(hystrix.StateClosed), gauge(circuit.MetricCircuitState, name),
+			"circuit approaching open is still closed")
+		assert.InDelta(t, 9.0/16, gauge(circuit.MetricCircuitFailureRatio, name), 0.001)
+	})
+	t.Run("circuit opens after failure ratio exceeds threshold", func(t *testing.T) {
+		name := "Consumer.gauge.127.0.0.1:8081"
+		hystrix.FlushByName(name)
+		hystrix.ConfigureCommand(name, hystrix.CommandConfig{RequestVolumeThreshold: 4, ErrorPercentThreshold: 50,
+			SleepWindow: 10000, MaxConcurrentRequests: 100, CircuitBreakerEnabled: true, MetricsConsumerNum: 1})
+		for n := 0; n < 4; n++ {
+			call(name, true)
+		}
+		report(name, 4)
+		assert.Equal(t, float64(1), gauge(circuit.MetricCircuitFailureRatio, name))
+		cb, _, _ := hystrix.GetCircuit(name)
+		assert.True(t, cb.IsOpen())
+		assert.NoError(t, circuit.ReportGauges(cb))
+		assert.Equal(t, float64(hystrix.StateOpen), gauge(circuit.MetricCircuitState, name))
+		assert.Contains(t, hystrix.AllStats(), name)
+	})
+}
@@ -32,16 +32,16 @@ func TestFilterAvailableZoneAffinity(t *testing.T) {
 	testData := []*registry.MicroServiceInstance{
 		{
 			EndpointsMap: map[string]*registry.Endpoint{"rest": {
-				false,
-				"127.0.0.1:80",
+				SSLEnabled: false,
+				Address:    "127.0.0.1:80",
 			}},
 			Metadata:       map[string]string{"key": "1"},
 			DataCenterInfo: dc,
 		},
 		{
 			EndpointsMap: map[string]*registry.Endpoint{"rest": {
-				false,
-				"127.0.0.1:80",
+				SSLEnabled: false,
+				Address:    "127.0.0.1:80",
 			}},
 			Metadata: map[string]string{"key": "1"},
 		},
@@ -55,8 +55,8 @@ func TestFilterAvailableZoneAffinity(t *testing.T) {
 	testData = []*registry.MicroServiceInstance{
 		{
 			EndpointsMap: map[string]*registry.Endpoint{"rest": {
-				false,
-				"127.0.0.1:80",
+				SSLEnabled: false,
+				Address:    "127.0.0.1:80",
 			}},
 			Metadata:       map[string]string{"key": "1"},
 			DataCenterInfo: dc,
@@ -72,8 +72,8 @@ func TestFilterAvailableZoneAffinity(t *testing.T) {
 	testData = []*registry.MicroServiceInstance{
 		{
 			EndpointsMap: map[string]*registry.Endpoint{"rest": {
-				false,
-				"127.0.0.1:80",
+				SSLEnabled: false,
+				Address:    "127.0.0.1:80",
 			}},
 			Metadata:       map[string]string{"key": "1"},
 			DataCenterInfo: dc,
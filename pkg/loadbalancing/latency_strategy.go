@@ -7,6 +7,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/go-chassis/go-chassis/v2/core/common"
 	"github.com/go-chassis/go-chassis/v2/core/config"
 	"github.com/go-chassis/go-chassis/v2/core/invocation"
 	"github.com/go-chassis/go-chassis/v2/core/loadbalancer"
@@ -50,12 +51,19 @@ func CalculateAvgLatency() {
 	loadbalancer.LatencyMapRWMutex.RUnlock()
 }
 
+// defaultLatencyMinSamples is the number of latencies an endpoint must have before the strategy acts on them,
+// if it is not set by common.LatencyMinSamplesKey. an endpoint with fewer samples is neutral,
+// it is picked by round robin like endpoints without latency, so that an unlucky first request
+// does not deprioritize a new instance
+const defaultLatencyMinSamples = 1
+
 // WeightedResponseStrategy is a strategy plugin
 type WeightedResponseStrategy struct {
 	instances   []*registry.MicroServiceInstance
 	serviceName string
 	protocol    string
 	tags        string
+	minSamples  int
 }
 
 func init() {
@@ -102,6 +110,15 @@ func (r *WeightedResponseStrategy) ReceiveData(inv *invocation.Invocation, insta
 
 	}
 	r.protocol = inv.Protocol
+	r.minSamples = defaultLatencyMinSamples
+	if n, _ := inv.Metadata[common.LatencyMinSamplesKey].(int); n > 0 {
+		r.minSamples = n
+	}
+}
+
+// ScansAllInstances tells instances are sampled for the strategy, see loadbalancer.Sampler
+func (r *WeightedResponseStrategy) ScansAllInstances() bool {
+	return true
 }
 
 // Pick return instance
@@ -109,8 +126,12 @@ func (r *WeightedResponseStrategy) Pick() (*registry.MicroServiceInstance, error
 	if rand.Intn(100) < 70 {
 		var instanceAddr string
 		loadbalancer.LatencyMapRWMutex.RLock()
-		if len(loadbalancer.ProtocolStatsMap[loadbalancer.BuildKey(r.serviceName, r.tags, r.protocol)]) != 0 {
-			instanceAddr = loadbalancer.ProtocolStatsMap[loadbalancer.BuildKey(r.serviceName, r.tags, r.protocol)][0].Addr
+		// stats are sorted by latency, endpoints without enough samples are neutral
+		for _, stats := range loadbalancer.ProtocolStatsMap[loadbalancer.BuildKey(r.serviceName, r.tags, r.protocol)] {
+			if stats.Samples >= r.minSamples {
+				instanceAddr = stats.Addr
+				break
+			}
 		}
 		loadbalancer.LatencyMapRWMutex.RUnlock()
 		for _, instance := range r.instances {
@@ -25,24 +25,24 @@ func TestWeightedResponseStrategy_Pick(t *testing.T) {
 		{
 			EndpointsMap: map[string]*registry.Endpoint{
 				"rest": {
-					false,
-					"127.0.0.1:8080",
+					SSLEnabled: false,
+					Address:    "127.0.0.1:8080",
 				},
 				"highway": {
-					false,
-					"127.0.0.1:9090",
+					SSLEnabled: false,
+					Address:    "127.0.0.1:9090",
 				},
 			},
 		},
 		{
 			EndpointsMap: map[string]*registry.Endpoint{
 				"rest": {
-					false,
-					"10.0.0.3:8080",
+					SSLEnabled: false,
+					Address:    "10.0.0.3:8080",
 				},
 				"highway": {
-					false,
-					"10.0.0.3:9090",
+					SSLEnabled: false,
+					Address:    "10.0.0.3:9090",
 				},
 			},
 		},
@@ -105,6 +105,52 @@ func TestWeightedResponseStrategy_Pick(t *testing.T) {
 		t.Error(count)
 	}
 }
+func TestWeightedResponseStrategy_MinSamples(t *testing.T) {
+	loadbalancer.LatencyMapRWMutex.Lock()
+	delete(loadbalancer.ProtocolStatsMap, loadbalancer.BuildKey("MinSamplesServer", utiltags.Tags{}.String(), common.ProtocolRest))
+	loadbalancer.LatencyMapRWMutex.Unlock()
+	instances := []*registry.MicroServiceInstance{
+		{EndpointsMap: map[string]*registry.Endpoint{"rest": {Address: "10.0.0.1:8080"}}},
+		{EndpointsMap: map[string]*registry.Endpoint{"rest": {Address: "10.0.0.2:8080"}}},
+	}
+	f, err := loadbalancer.GetStrategyPlugin(loadbalancer.StrategyLatency)
+	assert.NoError(t, err)
+	picks := func(minSamples int) int {
+		loadbalancing.CalculateAvgLatency()
+		loadbalancing.SortLatency()
+		s := f()
+		inv := &invocation.Invocation{Protocol: common.ProtocolRest}
+		if minSamples > 0 {
+			inv.SetMetadata(common.LatencyMinSamplesKey, minSamples)
+		}
+		s.ReceiveData(inv, instances, "MinSamplesServer")
+		slow := 0
+		for n := 0; n < 1000; n++ {
+			ins, err := s.Pick()
+			assert.NoError(t, err)
+			if ins.EndpointsMap["rest"].Address == "10.0.0.1:8080" {
+				slow++
+			}
+		}
+		return slow
+	}
+	loadbalancer.SetLatency(5*time.Second, "10.0.0.1:8080", "MinSamplesServer", utiltags.Tags{}, common.ProtocolRest)
+	loadbalancer.SetLatency(100*time.Millisecond, "10.0.0.2:8080", "MinSamplesServer", utiltags.Tags{}, common.ProtocolRest)
+	slow := picks(3)
+	assert.True(t, slow > 400, "instance with one slow sample is picked by round robin, got %d", slow)
+	slow = picks(0)
+	assert.True(t, slow < 300, "one sample is enough by default, got %d", slow)
+	slow = picks(3)
+	assert.True(t, slow > 400, "minimum is applied again once it is set, got %d", slow)
+
+	for n := 0; n < 2; n++ {
+		loadbalancer.SetLatency(5*time.Second, "10.0.0.1:8080", "MinSamplesServer", utiltags.Tags{}, common.ProtocolRest)
+		loadbalancer.SetLatency(100*time.Millisecond, "10.0.0.2:8080", "MinSamplesServer", utiltags.Tags{}, common.ProtocolRest)
+	}
+	slow = picks(3)
+	assert.True(t, slow < 300, "slow instance is deprioritized after minimum is reached, got %d", slow)
+}
+
 func TestCalculateAvgLatency(t *testing.T) {
 	defaultTags := utiltags.Tags{}
 	loadbalancer.SetLatency(2*time.Second, "127.0.0.1:3000", "Server1", defaultTags, common.ProtocolRest)
@@ -125,6 +125,11 @@ func Init() error {
 	return nil
 }
 
+//Enabled return true if metrics registry is initialized
+func Enabled() bool {
+	return defaultRegistry != nil
+}
+
 //GetSystemPrometheusRegistry return prometheus registry which go chassis use
 func GetSystemPrometheusRegistry() *prometheus.Registry {
 	return prometheusRegistry
@@ -0,0 +1,108 @@
+package httputil
+
+import (
+	"net/http"
+	"strings"
+)
+
+// Challenge is an authentication challenge of WWW-Authenticate header, like Basic, Bearer and Digest
+type Challenge struct {
+	// Scheme is auth scheme, like "Bearer" or "Digest"
+	Scheme string
+	// Params is auth params, names are in lower case, like realm, nonce or error
+	Params map[string]string
+	// Token68 is set when challenge carries token68 instead of auth params
+	Token68 string
+}
+
+// WWWAuthenticate parse WWW-Authenticate headers of response into challenges,
+// a header can carry multiple challenges.
+// it returns nil if response has no such header
+func WWWAuthenticate(resp *http.Response) []Challenge {
+	if resp == nil {
+		return nil
+	}
+	var challenges []Challenge
+	for _, h := range resp.Header[http.CanonicalHeaderKey("WWW-Authenticate")] {
+		challenges = append(challenges, ParseChallenges(h)...)
+	}
+	return challenges
+}
+
+// ParseChallenges parse value of WWW-Authenticate header, see RFC 7235
+func ParseChallenges(h string) []Challenge {
+	var challenges []Challenge
+	var current *Challenge
+	afterScheme := false
+	for len(h) > 0 {
+		h = strings.TrimLeft(h, " \t")
+		if strings.HasPrefix(h, ",") {
+			h = h[1:]
+			afterScheme = false
+			continue
+		}
+		var t string
+		t, h = readToken(h)
+		if t == "" {
+			// invalid character, skip it
+			h = h[1:]
+			continue
+		}
+		rest := strings.TrimLeft(h, " \t")
+		if !strings.HasPrefix(rest, "=") {
+			challenges = append(challenges, Challenge{Scheme: t, Params: map[string]string{}})
+			current = &challenges[len(challenges)-1]
+			afterScheme = true
+			continue
+		}
+		if current == nil {
+			// param without scheme, skip it
+			h = rest[1:]
+			continue
+		}
+		value := strings.TrimLeft(rest[1:], " \t")
+		if afterScheme && (value == "" || value[0] == '=' || value[0] == ',') {
+			// token68 like "abc==" has trailing "="
+			padding := len(rest) - len(strings.TrimLeft(rest, "="))
+			current.Token68 = t + rest[:padding]
+			h = rest[padding:]
+			afterScheme = false
+			continue
+		}
+		var v string
+		if strings.HasPrefix(value, `"`) {
+			v, h = readQuoted(value[1:])
+		} else {
+			v, h = readToken(value)
+		}
+		current.Params[strings.ToLower(t)] = v
+		afterScheme = false
+	}
+	return challenges
+}
+
+func readToken(s string) (string, string) {
+	i := strings.IndexAny(s, " \t,=\"")
+	if i == -1 {
+		return s, ""
+	}
+	return s[:i], s[i:]
+}
+
+func readQuoted(s string) (string, string) {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			if i+1 < len(s) {
+				i++
+				b.WriteByte(s[i])
+			}
+		case '"':
+			return b.String(), s[i+1:]
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+	return b.String(), ""
+}
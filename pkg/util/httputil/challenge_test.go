@@ -0,0 +1,50 @@
+package httputil_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/go-chassis/go-chassis/v2/pkg/util/httputil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWWWAuthenticate(t *testing.T) {
+	t.Run("basic", func(t *testing.T) {
+		c := httputil.ParseChallenges(`Basic realm="my realm", charset="UTF-8"`)
+		assert.Equal(t, []httputil.Challenge{{Scheme: "Basic",
+			Params: map[string]string{"realm": "my realm", "charset": "UTF-8"}}}, c)
+	})
+	t.Run("bearer", func(t *testing.T) {
+		c := httputil.ParseChallenges(`Bearer realm="example", error="invalid_token", error_description="The access token expired"`)
+		assert.Equal(t, 1, len(c))
+		assert.Equal(t, "Bearer", c[0].Scheme)
+		assert.Equal(t, "invalid_token", c[0].Params["error"])
+		assert.Equal(t, "The access token expired", c[0].Params["error_description"])
+	})
+	t.Run("digest", func(t *testing.T) {
+		c := httputil.ParseChallenges(`Digest realm="http-auth@example.org", qop="auth, auth-int", algorithm=SHA-256, nonce="7ypf/xlj9XXwfDPEoM4URrv/xwf94BcCAzFZH4GiTo0v", opaque="FQhe/qaU925kfnzjCev0ciny7QMkPqMAFRtzCUYo5tdS"`)
+		assert.Equal(t, 1, len(c))
+		assert.Equal(t, "Digest", c[0].Scheme)
+		assert.Equal(t, "auth, auth-int", c[0].Params["qop"])
+		assert.Equal(t, "SHA-256", c[0].Params["algorithm"])
+		assert.Equal(t, "7ypf/xlj9XXwfDPEoM4URrv/xwf94BcCAzFZH4GiTo0v", c[0].Params["nonce"])
+	})
+	t.Run("multiple challenges", func(t *testing.T) {
+		resp := &http.Response{Header: http.Header{}}
+		resp.Header.Add("WWW-Authenticate", `Newauth realm="apps", type=1, title="Login to \"apps\"", Basic realm="simple"`)
+		resp.Header.Add("WWW-Authenticate", `Negotiate abc==, Bearer`)
+		c := httputil.WWWAuthenticate(resp)
+		assert.Equal(t, 4, len(c))
+		assert.Equal(t, "Newauth", c[0].Scheme)
+		assert.Equal(t, `Login to "apps"`, c[0].Params["title"])
+		assert.Equal(t, "1", c[0].Params["type"])
+		assert.Equal(t, "Basic", c[1].Scheme)
+		assert.Equal(t, "simple", c[1].Params["realm"])
+		assert.Equal(t, "Negotiate", c[2].Scheme)
+		assert.Equal(t, "abc==", c[2].Token68)
+		assert.Equal(t, "Bearer", c[3].Scheme)
+	})
+	t.Run("no header", func(t *testing.T) {
+		assert.Nil(t, httputil.WWWAuthenticate(&http.Response{Header: http.Header{}}))
+	})
+}
@@ -9,6 +9,9 @@ import (
 	"github.com/go-chassis/openlog"
 	"io/ioutil"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 )
 
 //ErrInvalidReq invalid input
@@ -50,6 +53,17 @@ func SetContentType(req *http.Request, ct string) {
 	req.Header.Set("Content-Type", ct)
 }
 
+// SetConnectionClose makes the connection of the request closed after response is read,
+// so that it will not be reused by other requests
+func SetConnectionClose(req *http.Request, close bool) {
+	req.Close = close
+	if close {
+		req.Header.Set("Connection", "close")
+		return
+	}
+	req.Header.Del("Connection")
+}
+
 // GetContentType is a method used for getting content-type in a request
 func GetContentType(req *http.Request) string {
 	return req.Header.Get("Content-Type")
@@ -96,3 +110,90 @@ func GetRespCookie(resp *http.Response, key string) []byte {
 func SetRespCookie(resp *http.Response, cookie *http.Cookie) {
 	resp.Header.Add("Set-Cookie", cookie.String())
 }
+
+// RedirectChain returns urls of requests sent by following redirects in order, the last one is url of response.
+// it is empty if no redirect is followed. url has the address request is sent to as host
+func RedirectChain(resp *http.Response) []string {
+	var chain []string
+	for req := resp.Request; req != nil && req.Response != nil; req = req.Response.Request {
+		chain = append(chain, req.URL.String())
+	}
+	for i, j := 0, len(chain)-1; i < j; i, j = i+1, j-1 {
+		chain[i], chain[j] = chain[j], chain[i]
+	}
+	return chain
+}
+
+// CloseDelimited tells if body of response has neither Content-Length nor chunked encoding,
+// such body ends when server closes connection, it is read to the end and connection is never returned to pool
+func CloseDelimited(resp *http.Response) bool {
+	if resp.ContentLength >= 0 || len(resp.TransferEncoding) > 0 || resp.Uncompressed {
+		// decoded body has no length even if the encoded one has
+		return false
+	}
+	return resp.Body != nil && resp.Body != http.NoBody
+}
+
+// maxAge is the value of Age which is too large to be represented, as RFC 9111 says
+const maxAge = 1 << 31 * time.Second
+
+// Age returns the time response has been in caches of intermediaries, it is the Age header.
+// ok is false if response has no Age or the first one is not a non-negative integer of seconds
+func Age(resp *http.Response) (age time.Duration, ok bool) {
+	v := resp.Header["Age"]
+	if len(v) == 0 {
+		return 0, false
+	}
+	s := strings.TrimSpace(v[0])
+	if s == "" || strings.TrimLeft(s, "0123456789") != "" {
+		return 0, false
+	}
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || n >= int64(maxAge/time.Second) {
+		// all digits, it is out of range
+		return maxAge, true
+	}
+	return time.Duration(n) * time.Second, true
+}
+
+// header keys which mark a request idempotent, same as net/http
+const (
+	HeaderIdempotencyKey  = "Idempotency-Key"
+	HeaderXIdempotencyKey = "X-Idempotency-Key"
+)
+
+// SetIdempotent marks a request idempotent so that it can be retried whatever its method is,
+// the marker is a nil value X-Idempotency-Key header which is not sent, it is the net/http convention.
+// an Idempotency-Key header with value also marks the request
+func SetIdempotent(req *http.Request, idempotent bool) {
+	if req.Header == nil {
+		req.Header = http.Header{}
+	}
+	if idempotent {
+		if _, ok := req.Header[HeaderIdempotencyKey]; ok {
+			return
+		}
+		if _, ok := req.Header[HeaderXIdempotencyKey]; !ok {
+			req.Header[HeaderXIdempotencyKey] = nil
+		}
+		return
+	}
+	for _, k := range []string{HeaderIdempotencyKey, HeaderXIdempotencyKey} {
+		if v, ok := req.Header[k]; ok && v == nil {
+			delete(req.Header, k)
+		}
+	}
+}
+
+// IsIdempotent returns true if request method is idempotent or request is marked idempotent
+func IsIdempotent(req *http.Request) bool {
+	switch req.Method {
+	case "", http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	if _, ok := req.Header[HeaderIdempotencyKey]; ok {
+		return true
+	}
+	_, ok := req.Header[HeaderXIdempotencyKey]
+	return ok
+}
@@ -8,6 +8,7 @@ import (
 	"io/ioutil"
 	"net/http"
 	"testing"
+	"time"
 
 	"github.com/go-chassis/go-chassis/v2/client/rest"
 	"github.com/go-chassis/go-chassis/v2/core/common"
@@ -197,3 +198,51 @@ func TestGetRespCookie(t *testing.T) {
 		assert.Nil(t, b)
 	})
 }
+
+func TestSetIdempotent(t *testing.T) {
+	req, _ := http.NewRequest(http.MethodPost, "http://127.0.0.1/orders", nil)
+	assert.False(t, httputil.IsIdempotent(req))
+	httputil.SetIdempotent(req, true)
+	assert.True(t, httputil.IsIdempotent(req))
+	assert.Equal(t, "", req.Header.Get(httputil.HeaderXIdempotencyKey), "marker is not sent")
+	httputil.SetIdempotent(req, false)
+	assert.False(t, httputil.IsIdempotent(req))
+
+	req.Header.Set(httputil.HeaderIdempotencyKey, "order-1")
+	assert.True(t, httputil.IsIdempotent(req))
+	httputil.SetIdempotent(req, false)
+	assert.True(t, httputil.IsIdempotent(req), "idempotency key with value is kept")
+
+	req, _ = http.NewRequest(http.MethodPut, "http://127.0.0.1/orders", nil)
+	assert.True(t, httputil.IsIdempotent(req))
+}
+
+func TestAge(t *testing.T) {
+	tests := []struct {
+		name  string
+		age   []string
+		want  time.Duration
+		found bool
+	}{
+		{"valid", []string{"120"}, 2 * time.Minute, true},
+		{"zero", []string{"0"}, 0, true},
+		{"first one is used", []string{"5", "60"}, 5 * time.Second, true},
+		{"too large", []string{"99999999999999999999"}, 1 << 31 * time.Second, true},
+		{"missing", nil, 0, false},
+		{"empty", []string{""}, 0, false},
+		{"negative", []string{"-1"}, 0, false},
+		{"not seconds", []string{"1.5"}, 0, false},
+		{"not a number", []string{"ten"}, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := rest.NewResponse()
+			if tt.age != nil {
+				resp.Header["Age"] = tt.age
+			}
+			age, ok := httputil.Age(resp)
+			assert.Equal(t, tt.found, ok)
+			assert.Equal(t, tt.want, age)
+		})
+	}
+}
@@ -0,0 +1,63 @@
+package httputil
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+)
+
+// ContentLengthError means the body of request is not as long as its declared Content-Length
+type ContentLengthError struct {
+	Declared int64
+	// Actual is the length of body, for a streamed body it is the number of bytes read when mismatch is found
+	Actual int64
+}
+
+func (e *ContentLengthError) Error() string {
+	return fmt.Sprintf("declared Content-Length %d, body has %d bytes", e.Declared, e.Actual)
+}
+
+// SetContentLength declares the length of request body, so that it is sent with Content-Length instead of chunked.
+// body is not buffered. length of a body which can be got again, such as the one of NewRequest, is checked at once,
+// a streamed body fails with ContentLengthError while it is sent if it ends before n bytes or has more
+func SetContentLength(req *http.Request, n int64) error {
+	if n < 0 {
+		return fmt.Errorf("invalid Content-Length %d", n)
+	}
+	if req.Body == nil || req.Body == http.NoBody {
+		if n != 0 {
+			return &ContentLengthError{Declared: n}
+		}
+	} else if req.GetBody != nil {
+		if req.ContentLength != n {
+			return &ContentLengthError{Declared: n, Actual: req.ContentLength}
+		}
+	} else {
+		req.Body = &lengthCheckedBody{ReadCloser: req.Body, declared: n}
+	}
+	req.ContentLength = n
+	if req.Header == nil {
+		req.Header = http.Header{}
+	}
+	req.Header.Set("Content-Length", strconv.FormatInt(n, 10))
+	return nil
+}
+
+// lengthCheckedBody fails instead of ending if body is shorter or longer than declared length
+type lengthCheckedBody struct {
+	io.ReadCloser
+	declared, read int64
+}
+
+func (b *lengthCheckedBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.read += int64(n)
+	if b.read > b.declared {
+		return n, &ContentLengthError{Declared: b.declared, Actual: b.read}
+	}
+	if err == io.EOF && b.read < b.declared {
+		return n, &ContentLengthError{Declared: b.declared, Actual: b.read}
+	}
+	return n, err
+}
@@ -0,0 +1,83 @@
+package httputil_test
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chassis/go-chassis/v2/pkg/util/httputil"
+	"github.com/stretchr/testify/assert"
+)
+
+// stream hides the type of reader, so that length of body is not known to net/http
+type stream struct{ io.Reader }
+
+func TestSetContentLength(t *testing.T) {
+	type received struct {
+		length  int64
+		chunked bool
+		body    string
+	}
+	got := make(chan received, 1)
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := ioutil.ReadAll(r.Body)
+		select {
+		case got <- received{r.ContentLength, len(r.TransferEncoding) > 0, string(b)}:
+		default:
+			// a failed request is not checked
+		}
+	}))
+	defer s.Close()
+
+	t.Run("streamed body is sent with fixed length", func(t *testing.T) {
+		req, _ := http.NewRequest(http.MethodPost, s.URL, stream{strings.NewReader("hello")})
+		assert.NoError(t, httputil.SetContentLength(req, 5))
+		assert.Equal(t, "5", req.Header.Get("Content-Length"))
+		resp, err := http.DefaultClient.Do(req)
+		if assert.NoError(t, err) {
+			resp.Body.Close()
+			assert.Equal(t, received{5, false, "hello"}, <-got)
+		}
+	})
+	t.Run("streamed body without declared length is chunked", func(t *testing.T) {
+		req, _ := http.NewRequest(http.MethodPost, s.URL, stream{strings.NewReader("hello")})
+		resp, err := http.DefaultClient.Do(req)
+		if assert.NoError(t, err) {
+			resp.Body.Close()
+			assert.Equal(t, received{-1, true, "hello"}, <-got)
+		}
+	})
+	for name, n := range map[string]int64{"under declared": 3, "over declared": 8} {
+		t.Run(name+" streamed body fails", func(t *testing.T) {
+			req, _ := http.NewRequest(http.MethodPost, s.URL, stream{strings.NewReader("hello")})
+			assert.NoError(t, httputil.SetContentLength(req, n))
+			_, err := http.DefaultClient.Do(req)
+			select {
+			case <-got:
+			default:
+			}
+			var lengthErr *httputil.ContentLengthError
+			if assert.True(t, errors.As(err, &lengthErr), "got %v", err) {
+				assert.Equal(t, n, lengthErr.Declared)
+			}
+		})
+		t.Run(name+" known body fails before sending", func(t *testing.T) {
+			req, _ := http.NewRequest(http.MethodPost, s.URL, strings.NewReader("hello"))
+			err := httputil.SetContentLength(req, n)
+			assert.Equal(t, &httputil.ContentLengthError{Declared: n, Actual: 5}, err)
+			assert.Equal(t, int64(5), req.ContentLength, "request is not changed")
+		})
+	}
+	t.Run("known body", func(t *testing.T) {
+		req, _ := http.NewRequest(http.MethodPost, s.URL, strings.NewReader("hello"))
+		assert.NoError(t, httputil.SetContentLength(req, 5))
+		req, _ = http.NewRequest(http.MethodPost, s.URL, nil)
+		assert.NoError(t, httputil.SetContentLength(req, 0))
+		assert.Error(t, httputil.SetContentLength(req, 1), "request has no body")
+		assert.Error(t, httputil.SetContentLength(req, -1))
+	})
+}
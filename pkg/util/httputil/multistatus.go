@@ -0,0 +1,102 @@
+package httputil
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// ErrNotMultiStatus means response is not 207 Multi-Status, its body has no statuses of resources
+var ErrNotMultiStatus = errors.New("response is not 207 Multi-Status")
+
+// ResourceStatus is the status of a resource in 207 Multi-Status body, see RFC 4918.
+// if status is about some properties of resource, Props has their names
+type ResourceStatus struct {
+	Href        string
+	StatusCode  int
+	Props       []string
+	Description string
+}
+
+// xml elements of multistatus body, they are in DAV: namespace
+type davMultiStatus struct {
+	Responses []davResponse `xml:"DAV: response"`
+}
+
+type davResponse struct {
+	Hrefs       []string      `xml:"DAV: href"`
+	Status      string        `xml:"DAV: status"`
+	PropStats   []davPropStat `xml:"DAV: propstat"`
+	Description string        `xml:"DAV: responsedescription"`
+}
+
+type davPropStat struct {
+	Props       davProp `xml:"DAV: prop"`
+	Status      string  `xml:"DAV: status"`
+	Description string  `xml:"DAV: responsedescription"`
+}
+
+type davProp struct {
+	Any []struct {
+		XMLName xml.Name
+	} `xml:",any"`
+}
+
+// DecodeMultiStatus reads 207 Multi-Status body and returns statuses of resources in it, body is closed.
+// a resource with status of each property group has a ResourceStatus for each group.
+// it fails with ErrNotMultiStatus if status code is not 207
+func DecodeMultiStatus(resp *http.Response) ([]ResourceStatus, error) {
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusMultiStatus {
+		return nil, ErrNotMultiStatus
+	}
+	var ms davMultiStatus
+	if err := xml.NewDecoder(resp.Body).Decode(&ms); err != nil {
+		return nil, fmt.Errorf("decode multistatus body: %w", err)
+	}
+	var statuses []ResourceStatus
+	for _, r := range ms.Responses {
+		if len(r.Hrefs) == 0 {
+			return nil, errors.New("multistatus response has no href")
+		}
+		if r.Status != "" {
+			code, err := parseStatusLine(r.Status)
+			if err != nil {
+				return nil, err
+			}
+			for _, href := range r.Hrefs {
+				statuses = append(statuses, ResourceStatus{Href: href, StatusCode: code, Description: r.Description})
+			}
+			continue
+		}
+		for _, ps := range r.PropStats {
+			code, err := parseStatusLine(ps.Status)
+			if err != nil {
+				return nil, err
+			}
+			s := ResourceStatus{Href: r.Hrefs[0], StatusCode: code, Description: ps.Description}
+			if s.Description == "" {
+				s.Description = r.Description
+			}
+			for _, p := range ps.Props.Any {
+				s.Props = append(s.Props, p.XMLName.Local)
+			}
+			statuses = append(statuses, s)
+		}
+	}
+	return statuses, nil
+}
+
+// parseStatusLine returns code of status line, such as HTTP/1.1 404 Not Found
+func parseStatusLine(line string) (int, error) {
+	fields := strings.Fields(line)
+	if len(fields) >= 2 && strings.HasPrefix(fields[0], "HTTP/") {
+		if code, err := strconv.Atoi(fields[1]); err == nil && code >= 100 && code <= 999 {
+			return code, nil
+		}
+	}
+	return 0, fmt.Errorf("invalid status [%s] in multistatus body", line)
+}
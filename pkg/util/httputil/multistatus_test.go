@@ -0,0 +1,66 @@
+package httputil_test
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/go-chassis/go-chassis/v2/pkg/util/httputil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDecodeMultiStatus(t *testing.T) {
+	resp := func(code int, body string) *http.Response {
+		return &http.Response{StatusCode: code, Body: ioutil.NopCloser(strings.NewReader(body))}
+	}
+	t.Run("statuses of resources and property groups", func(t *testing.T) {
+		body := `<?xml version="1.0" encoding="utf-8"?>
+<D:multistatus xmlns:D="DAV:" xmlns:Z="http://example.com/ns">
+  <D:response>
+    <D:href>/container/a</D:href>
+    <D:href>/container/b</D:href>
+    <D:status>HTTP/1.1 200 OK</D:status>
+  </D:response>
+  <D:response>
+    <D:href>/container/c</D:href>
+    <D:status>HTTP/1.1 423 Locked</D:status>
+    <D:responsedescription>locked by another client</D:responsedescription>
+  </D:response>
+  <D:response>
+    <D:href>/container/d</D:href>
+    <D:propstat>
+      <D:prop><D:getetag/><Z:owner/></D:prop>
+      <D:status>HTTP/1.1 200 OK</D:status>
+    </D:propstat>
+    <D:propstat>
+      <D:prop><Z:quota/></D:prop>
+      <D:status>HTTP/1.1 403 Forbidden</D:status>
+    </D:propstat>
+  </D:response>
+</D:multistatus>`
+		statuses, err := httputil.DecodeMultiStatus(resp(http.StatusMultiStatus, body))
+		assert.NoError(t, err)
+		assert.Equal(t, []httputil.ResourceStatus{
+			{Href: "/container/a", StatusCode: http.StatusOK},
+			{Href: "/container/b", StatusCode: http.StatusOK},
+			{Href: "/container/c", StatusCode: http.StatusLocked, Description: "locked by another client"},
+			{Href: "/container/d", StatusCode: http.StatusOK, Props: []string{"getetag", "owner"}},
+			{Href: "/container/d", StatusCode: http.StatusForbidden, Props: []string{"quota"}},
+		}, statuses)
+	})
+	t.Run("response which is not 207", func(t *testing.T) {
+		_, err := httputil.DecodeMultiStatus(resp(http.StatusOK, "<html/>"))
+		assert.True(t, errors.Is(err, httputil.ErrNotMultiStatus))
+	})
+	t.Run("invalid status line", func(t *testing.T) {
+		body := `<multistatus xmlns="DAV:"><response><href>/a</href><status>unknown</status></response></multistatus>`
+		_, err := httputil.DecodeMultiStatus(resp(http.StatusMultiStatus, body))
+		assert.EqualError(t, err, "invalid status [unknown] in multistatus body")
+	})
+	t.Run("invalid xml", func(t *testing.T) {
+		_, err := httputil.DecodeMultiStatus(resp(http.StatusMultiStatus, "<multistatus"))
+		assert.Error(t, err)
+	})
+}
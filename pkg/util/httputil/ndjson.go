@@ -0,0 +1,64 @@
+package httputil
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+)
+
+// MaxDrainBytes is the most bytes JSONStream.Close discards from unread body,
+// so that connection can be reused. if more bytes remain, connection is closed instead
+var MaxDrainBytes int64 = 64 * 1024
+
+// JSONStream decodes a newline delimited JSON (ndjson) response one record at a time,
+// so that huge response is not buffered in memory
+type JSONStream struct {
+	r    *bufio.Reader
+	body io.Closer
+	line int
+}
+
+// NewJSONStream returns a stream which reads records from response body
+func NewJSONStream(resp *http.Response) *JSONStream {
+	return &JSONStream{r: bufio.NewReader(resp.Body), body: resp.Body}
+}
+
+// Line returns the line number of last decoded record
+func (s *JSONStream) Line() int {
+	return s.line
+}
+
+// Scan decodes next record into target, blank lines are skipped.
+// it returns io.EOF after last record, decode error tells the line number
+func (s *JSONStream) Scan(target interface{}) error {
+	for {
+		b, err := s.r.ReadBytes('\n')
+		if len(b) == 0 && err != nil {
+			return err
+		}
+		s.line++
+		b = bytes.TrimSpace(b)
+		if len(b) == 0 {
+			if err != nil {
+				return err
+			}
+			continue
+		}
+		if decodeErr := json.Unmarshal(b, target); decodeErr != nil {
+			return fmt.Errorf("ndjson line %d: %w", s.line, decodeErr)
+		}
+		return nil
+	}
+}
+
+// Close closes response body, caller can close stream before all records are read,
+// remaining body is discarded if it is not larger than MaxDrainBytes, otherwise connection is closed
+func (s *JSONStream) Close() error {
+	// body which is not read to EOF makes transport close connection
+	io.CopyN(ioutil.Discard, s.r, MaxDrainBytes+1)
+	return s.body.Close()
+}
@@ -0,0 +1,98 @@
+package httputil_test
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/go-chassis/go-chassis/v2/pkg/util/httputil"
+	"github.com/stretchr/testify/assert"
+)
+
+type record struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+func TestJSONStream_Scan(t *testing.T) {
+	t.Run("records are decoded one by one", func(t *testing.T) {
+		body := "{\"id\":1,\"name\":\"a\"}\n\n{\"id\":2,\"name\":\"b\"}\r\n{\"id\":3,\"name\":\"c\"}"
+		s := httputil.NewJSONStream(&http.Response{Body: ioutil.NopCloser(strings.NewReader(body))})
+		defer s.Close()
+		var got []record
+		for {
+			var r record
+			err := s.Scan(&r)
+			if err == io.EOF {
+				break
+			}
+			assert.NoError(t, err)
+			got = append(got, r)
+		}
+		assert.Equal(t, []record{{1, "a"}, {2, "b"}, {3, "c"}}, got)
+	})
+	t.Run("decode error tells line number", func(t *testing.T) {
+		body := "{\"id\":1}\n{\"id\":\"x\"}\n{\"id\":3}\n"
+		s := httputil.NewJSONStream(&http.Response{Body: ioutil.NopCloser(strings.NewReader(body))})
+		var r record
+		assert.NoError(t, s.Scan(&r))
+		err := s.Scan(&r)
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "line 2")
+		var typeErr *json.UnmarshalTypeError
+		assert.True(t, errors.As(err, &typeErr))
+		assert.NoError(t, s.Scan(&r))
+		assert.Equal(t, 3, r.ID)
+		assert.Equal(t, io.EOF, s.Scan(&r))
+	})
+}
+
+func TestJSONStream_Close(t *testing.T) {
+	var conns int32
+	s := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n, _ := strconv.Atoi(r.URL.Query().Get("records"))
+		for i := 0; i < n; i++ {
+			fmt.Fprintf(w, "{\"id\":%d,\"name\":\"%s\"}\n", i, strings.Repeat("x", 100))
+		}
+	}))
+	s.Config.ConnState = func(c net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			atomic.AddInt32(&conns, 1)
+		}
+	}
+	s.Start()
+	defer s.Close()
+	old := httputil.MaxDrainBytes
+	httputil.MaxDrainBytes = 4096
+	defer func() { httputil.MaxDrainBytes = old }()
+	c := &http.Client{Transport: &http.Transport{}}
+
+	// get reads first record of stream and closes it
+	get := func(records int) {
+		resp, err := c.Get(s.URL + "?records=" + strconv.Itoa(records))
+		assert.NoError(t, err)
+		stream := httputil.NewJSONStream(resp)
+		var r record
+		assert.NoError(t, stream.Scan(&r))
+		assert.NoError(t, stream.Close())
+	}
+	t.Run("small remaining body is drained and connection is reused", func(t *testing.T) {
+		get(10)
+		get(10)
+		assert.Equal(t, int32(1), atomic.LoadInt32(&conns))
+	})
+	t.Run("large remaining body is not drained and connection is closed", func(t *testing.T) {
+		get(5000)
+		get(10)
+		assert.Equal(t, int32(2), atomic.LoadInt32(&conns))
+	})
+}
@@ -0,0 +1,34 @@
+package httputil
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+)
+
+// PeekBody reads at most n bytes from the start of response body without consuming them,
+// body of response still returns the whole content, peeked bytes first, the rest is streamed.
+// head is shorter than n if body is shorter, err is the error of reading, except io.EOF
+func PeekBody(resp *http.Response, n int) (head []byte, err error) {
+	if resp == nil || resp.Body == nil || resp.Body == http.NoBody || n <= 0 {
+		return nil, nil
+	}
+	head = make([]byte, n)
+	read, err := io.ReadFull(resp.Body, head)
+	head = head[:read]
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		err = nil
+	}
+	resp.Body = &peekedBody{Reader: io.MultiReader(bytes.NewReader(head), resp.Body), closer: resp.Body}
+	return head, err
+}
+
+// peekedBody reads peeked bytes and then the rest of body, closing it closes the original body
+type peekedBody struct {
+	io.Reader
+	closer io.Closer
+}
+
+func (b *peekedBody) Close() error {
+	return b.closer.Close()
+}
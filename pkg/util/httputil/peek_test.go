@@ -0,0 +1,31 @@
+package httputil_test
+
+import (
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/go-chassis/go-chassis/v2/pkg/util/httputil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPeekBody(t *testing.T) {
+	resp := &http.Response{Body: ioutil.NopCloser(strings.NewReader("ERR:42 rest of stream"))}
+	head, err := httputil.PeekBody(resp, 4)
+	assert.NoError(t, err)
+	assert.Equal(t, "ERR:", string(head))
+	b, _ := ioutil.ReadAll(resp.Body)
+	assert.Equal(t, "ERR:42 rest of stream", string(b), "peeked bytes are prepended to body")
+
+	resp = &http.Response{Body: ioutil.NopCloser(strings.NewReader("ok"))}
+	head, err = httputil.PeekBody(resp, 8)
+	assert.NoError(t, err)
+	assert.Equal(t, "ok", string(head), "body is shorter than n")
+	b, _ = ioutil.ReadAll(resp.Body)
+	assert.Equal(t, "ok", string(b))
+
+	head, err = httputil.PeekBody(&http.Response{Body: http.NoBody}, 8)
+	assert.NoError(t, err)
+	assert.Empty(t, head)
+}
@@ -0,0 +1,64 @@
+package httputil
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/go-chassis/go-chassis/v2/core/common"
+)
+
+// XML is the content type which is guessed for markup body
+const XML = "application/xml"
+
+// sniffLen is the max bytes peeked to guess content type
+const sniffLen = 512
+
+// SniffContentType guesses content type of response by first bytes of body if server omits Content-Type,
+// leading '{' or '[' means JSON, '<' means XML or HTML, fallback is used if body is not empty but can not be guessed.
+// it is best-effort, Content-Type header is set if it is not empty, body is not consumed
+func SniffContentType(resp *http.Response, fallback string) string {
+	if resp == nil || resp.Body == nil || resp.Header.Get("Content-Type") != "" {
+		return ""
+	}
+	br := bufio.NewReaderSize(resp.Body, sniffLen)
+	resp.Body = struct {
+		io.Reader
+		io.Closer
+	}{br, resp.Body}
+	head, _ := br.Peek(sniffLen)
+	if len(head) == 0 {
+		return ""
+	}
+	ct := sniff(head)
+	if ct == "" {
+		ct = fallback
+	}
+	if ct != "" {
+		if resp.Header == nil {
+			resp.Header = http.Header{}
+		}
+		resp.Header.Set("Content-Type", ct)
+	}
+	return ct
+}
+
+func sniff(head []byte) string {
+	head = bytes.TrimPrefix(head, []byte("\xef\xbb\xbf"))
+	head = bytes.TrimLeft(head, " \t\r\n")
+	if len(head) == 0 {
+		return ""
+	}
+	switch head[0] {
+	case '{', '[':
+		return common.JSON
+	case '<':
+		if ct := http.DetectContentType(head); strings.HasPrefix(ct, "text/html") {
+			return ct
+		}
+		return XML
+	}
+	return ""
+}
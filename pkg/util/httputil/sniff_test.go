@@ -0,0 +1,53 @@
+package httputil_test
+
+import (
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/go-chassis/go-chassis/v2/core/common"
+	"github.com/go-chassis/go-chassis/v2/pkg/util/httputil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSniffContentType(t *testing.T) {
+	newResp := func(body string, header http.Header) *http.Response {
+		return &http.Response{Header: header, Body: ioutil.NopCloser(strings.NewReader(body))}
+	}
+	cases := []struct {
+		name string
+		body string
+		want string
+	}{
+		{"json object", `{"name":"a"}`, common.JSON},
+		{"json array with spaces", "\n  [1,2,3]", common.JSON},
+		{"json with bom", "\xef\xbb\xbf{}", common.JSON},
+		{"xml", `<?xml version="1.0"?><a>1</a>`, httputil.XML},
+		{"xml without declaration", `<user><name>a</name></user>`, httputil.XML},
+		{"html", `<!DOCTYPE html><html></html>`, "text/html; charset=utf-8"},
+		{"plain text", `hello`, ""},
+		{"empty", ``, ""},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			resp := newResp(c.body, http.Header{})
+			assert.Equal(t, c.want, httputil.SniffContentType(resp, ""))
+			assert.Equal(t, c.want, resp.Header.Get("Content-Type"))
+			b, err := ioutil.ReadAll(resp.Body)
+			assert.NoError(t, err)
+			assert.Equal(t, c.body, string(b), "body should not be consumed")
+		})
+	}
+	t.Run("content type of server is kept", func(t *testing.T) {
+		resp := newResp(`{}`, http.Header{"Content-Type": {"text/plain"}})
+		assert.Equal(t, "", httputil.SniffContentType(resp, ""))
+		assert.Equal(t, "text/plain", resp.Header.Get("Content-Type"))
+	})
+	t.Run("fallback is used for unknown body", func(t *testing.T) {
+		resp := newResp(`hello`, http.Header{})
+		assert.Equal(t, "text/plain", httputil.SniffContentType(resp, "text/plain"))
+		resp = newResp(``, http.Header{})
+		assert.Equal(t, "", httputil.SniffContentType(resp, "text/plain"), "empty body has no content type")
+	})
+}
@@ -0,0 +1,187 @@
+package httputil
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// ErrInvalidTemplateBody means the body rendered from template is not valid JSON
+var ErrInvalidTemplateBody = errors.New("body rendered from template is not valid JSON")
+
+// RequestEncodeError means a param can not be encoded into request body, such as a cyclic structure,
+// request is not changed, so that nothing is sent
+type RequestEncodeError struct {
+	// Codec is the encoding of body, it is json
+	Codec string
+	// Param is the name of placeholder
+	Param string
+	Err   error
+}
+
+// Error returns error message
+func (e *RequestEncodeError) Error() string {
+	return fmt.Sprintf("can not encode param [%s] as %s: %s", e.Param, e.Codec, e.Err)
+}
+
+// Unwrap returns the encoding failure
+func (e *RequestEncodeError) Unwrap() error {
+	return e.Err
+}
+
+// a placeholder is at value position if template is valid with both, "" fails inside string, 0 fails as key
+var placeholderHolders = [][]byte{[]byte(`""`), []byte(`0`)}
+
+// BodyTemplate is a JSON body with placeholders, such as {"id": {{id}}, "name": {{name}}},
+// it is parsed and validated once and rendered for each call. placeholders must be at value positions,
+// param values are encoded as JSON, string is quoted, so that rendered body is always valid JSON
+type BodyTemplate struct {
+	// parts are the text around placeholders, len(parts) == len(names)+1
+	parts [][]byte
+	names []string
+	size  int
+}
+
+// NewBodyTemplate parses a template, it fails if a placeholder is not closed or has no name,
+// or template is not valid JSON when placeholders are values
+func NewBodyTemplate(tmpl string) (*BodyTemplate, error) {
+	t := &BodyTemplate{}
+	rest := tmpl
+	for {
+		start := strings.Index(rest, "{{")
+		if start < 0 {
+			break
+		}
+		end := strings.Index(rest[start:], "}}")
+		if end < 0 {
+			return nil, fmt.Errorf("placeholder at %d is not closed", len(tmpl)-len(rest)+start)
+		}
+		name := strings.TrimSpace(rest[start+2 : start+end])
+		if name == "" {
+			return nil, fmt.Errorf("placeholder at %d has no name", len(tmpl)-len(rest)+start)
+		}
+		t.parts = append(t.parts, []byte(rest[:start]))
+		t.names = append(t.names, name)
+		rest = rest[start+end+2:]
+	}
+	t.parts = append(t.parts, []byte(rest))
+	t.size = len(tmpl)
+	// a param is always encoded as one JSON value, result is valid if template is valid with any value
+	for _, h := range placeholderHolders {
+		if !json.Valid(bytes.Join(t.parts, h)) {
+			return nil, ErrInvalidTemplateBody
+		}
+	}
+	return t, nil
+}
+
+// Render fills placeholders with params, every placeholder must have a param,
+// it fails with *RequestEncodeError if a param can not be encoded
+func (t *BodyTemplate) Render(params map[string]interface{}) ([]byte, error) {
+	b := make([]byte, 0, t.size+16*len(t.names))
+	for n, name := range t.names {
+		b = append(b, t.parts[n]...)
+		v, ok := params[name]
+		if !ok {
+			return nil, fmt.Errorf("no param for placeholder [%s]", name)
+		}
+		var err error
+		if b, err = appendJSONValue(b, v); err != nil {
+			return nil, &RequestEncodeError{Codec: "json", Param: name, Err: err}
+		}
+	}
+	return append(b, t.parts[len(t.parts)-1]...), nil
+}
+
+// SetBodyTemplate renders template with params and sets the result as body of request,
+// Content-Type is set to application/json if request has none
+func SetBodyTemplate(req *http.Request, t *BodyTemplate, params map[string]interface{}) error {
+	body, err := t.Render(params)
+	if err != nil {
+		return err
+	}
+	req.Body = ioutil.NopCloser(bytes.NewReader(body))
+	req.ContentLength = int64(len(body))
+	req.GetBody = func() (io.ReadCloser, error) {
+		return ioutil.NopCloser(bytes.NewReader(body)), nil
+	}
+	if req.Header == nil {
+		req.Header = http.Header{}
+	}
+	if req.Header.Get("Content-Type") == "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	return nil
+}
+
+// appendJSONValue encodes common types without reflection, others are encoded by encoding/json
+func appendJSONValue(b []byte, v interface{}) ([]byte, error) {
+	switch x := v.(type) {
+	case nil:
+		return append(b, "null"...), nil
+	case string:
+		return appendJSONString(b, x), nil
+	case bool:
+		return strconv.AppendBool(b, x), nil
+	case int:
+		return strconv.AppendInt(b, int64(x), 10), nil
+	case int32:
+		return strconv.AppendInt(b, int64(x), 10), nil
+	case int64:
+		return strconv.AppendInt(b, x, 10), nil
+	case uint:
+		return strconv.AppendUint(b, uint64(x), 10), nil
+	case uint32:
+		return strconv.AppendUint(b, uint64(x), 10), nil
+	case uint64:
+		return strconv.AppendUint(b, x, 10), nil
+	}
+	encoded, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return append(b, encoded...), nil
+}
+
+const hexDigits = "0123456789abcdef"
+
+// appendJSONString quotes s as JSON string, invalid UTF-8 is replaced by U+FFFD as encoding/json does
+func appendJSONString(b []byte, s string) []byte {
+	b = append(b, '"')
+	for i := 0; i < len(s); {
+		c := s[i]
+		if c < utf8.RuneSelf {
+			switch {
+			case c == '"' || c == '\\':
+				b = append(b, '\\', c)
+			case c == '\n':
+				b = append(b, '\\', 'n')
+			case c == '\r':
+				b = append(b, '\\', 'r')
+			case c == '\t':
+				b = append(b, '\\', 't')
+			case c < 0x20:
+				b = append(b, '\\', 'u', '0', '0', hexDigits[c>>4], hexDigits[c&0xf])
+			default:
+				b = append(b, c)
+			}
+			i++
+			continue
+		}
+		r, size := utf8.DecodeRuneInString(s[i:])
+		if r == utf8.RuneError && size == 1 {
+			b = append(b, "\ufffd"...)
+		} else {
+			b = append(b, s[i:i+size]...)
+		}
+		i += size
+	}
+	return append(b, '"')
+}
@@ -0,0 +1,130 @@
+package httputil_test
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"testing"
+
+	"github.com/go-chassis/go-chassis/v2/pkg/util/httputil"
+	"github.com/stretchr/testify/assert"
+)
+
+type order struct {
+	ID       int64    `json:"id"`
+	Customer string   `json:"customer"`
+	Paid     bool     `json:"paid"`
+	Items    []string `json:"items"`
+	Note     *string  `json:"note"`
+}
+
+const orderTemplate = `{"id": {{id}}, "customer": {{customer}}, "paid": {{paid}}, "items": {{items}}, "note": {{note}}}`
+
+func TestSetBodyTemplate(t *testing.T) {
+	tmpl, err := httputil.NewBodyTemplate(orderTemplate)
+	assert.NoError(t, err)
+
+	t.Run("rendered body equals marshaled struct", func(t *testing.T) {
+		req, _ := http.NewRequest(http.MethodPost, "http://Server/orders", nil)
+		err := httputil.SetBodyTemplate(req, tmpl, map[string]interface{}{
+			"id":       int64(42),
+			"customer": "tom \"the cat\"\n\x01\xff",
+			"paid":     true,
+			"items":    []string{"a", "b"},
+			"note":     nil,
+		})
+		assert.NoError(t, err)
+		b, _ := ioutil.ReadAll(req.Body)
+		var got order
+		assert.NoError(t, json.Unmarshal(b, &got))
+		assert.Equal(t, order{ID: 42, Customer: "tom \"the cat\"\n\x01\ufffd", Paid: true, Items: []string{"a", "b"}}, got)
+		assert.Equal(t, int64(len(b)), req.ContentLength)
+		assert.Equal(t, "application/json", req.Header.Get("Content-Type"))
+		body, err := req.GetBody()
+		assert.NoError(t, err)
+		replay, _ := ioutil.ReadAll(body)
+		assert.Equal(t, b, replay)
+	})
+	t.Run("missing param", func(t *testing.T) {
+		req, _ := http.NewRequest(http.MethodPost, "http://Server/orders", nil)
+		err := httputil.SetBodyTemplate(req, tmpl, map[string]interface{}{"id": 1})
+		assert.EqualError(t, err, "no param for placeholder [customer]")
+	})
+	t.Run("cyclic param", func(t *testing.T) {
+		type node struct {
+			Next *node
+		}
+		cyclic := &node{}
+		cyclic.Next = cyclic
+		req, _ := http.NewRequest(http.MethodPost, "http://Server/orders", nil)
+		err := httputil.SetBodyTemplate(req, tmpl, map[string]interface{}{
+			"id": 1, "customer": "tom", "paid": true, "items": cyclic, "note": nil,
+		})
+		var encodeErr *httputil.RequestEncodeError
+		if assert.True(t, errors.As(err, &encodeErr)) {
+			assert.Equal(t, "json", encodeErr.Codec)
+			assert.Equal(t, "items", encodeErr.Param)
+			var unsupported *json.UnsupportedValueError
+			assert.True(t, errors.As(err, &unsupported))
+		}
+		assert.Nil(t, req.Body, "request is not changed")
+		assert.Empty(t, req.Header.Get("Content-Type"))
+	})
+	t.Run("result must be valid JSON", func(t *testing.T) {
+		_, err := httputil.NewBodyTemplate(`{"id": {{id}}`)
+		assert.Equal(t, httputil.ErrInvalidTemplateBody, err)
+		_, err = httputil.NewBodyTemplate(`{"id": "{{id}}"}`)
+		assert.Equal(t, httputil.ErrInvalidTemplateBody, err, "placeholder must be a value")
+		_, err = httputil.NewBodyTemplate(`{ {{key}}: 1}`)
+		assert.Equal(t, httputil.ErrInvalidTemplateBody, err, "placeholder can not be a key")
+		for _, v := range []interface{}{"", "\\\"", "\u2028<>&", uint(7), -1.5, false, map[string]int{"a": 1}, json.RawMessage(`[1]`)} {
+			b, err := tmpl.Render(map[string]interface{}{"id": v, "customer": v, "paid": v, "items": v, "note": v})
+			assert.NoError(t, err)
+			assert.True(t, json.Valid(b), string(b))
+		}
+	})
+	t.Run("bad template", func(t *testing.T) {
+		_, err := httputil.NewBodyTemplate(`{"id": {{id}`)
+		assert.Error(t, err)
+		_, err = httputil.NewBodyTemplate(`{"id": {{ }}}`)
+		assert.Error(t, err)
+	})
+}
+
+// shipment is a typical templated body, only id and customer differ between calls
+type shipment struct {
+	ID       int64             `json:"id"`
+	Customer string            `json:"customer"`
+	Carrier  string            `json:"carrier"`
+	Priority int               `json:"priority"`
+	Insured  bool              `json:"insured"`
+	Region   string            `json:"region"`
+	Tags     []string          `json:"tags"`
+	Labels   map[string]string `json:"labels"`
+}
+
+const shipmentTemplate = `{"id": {{id}}, "customer": {{customer}}, "carrier": "express", "priority": 3, "insured": true,` +
+	` "region": "eu-west", "tags": ["fragile", "gift"], "labels": {"channel": "web", "source": "checkout"}}`
+
+func BenchmarkBodyTemplate_Render(b *testing.B) {
+	tmpl, _ := httputil.NewBodyTemplate(shipmentTemplate)
+	b.ReportAllocs()
+	for n := 0; n < b.N; n++ {
+		if _, err := tmpl.Render(map[string]interface{}{"id": int64(n), "customer": "tom"}); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkBodyTemplate_Marshal(b *testing.B) {
+	tags := []string{"fragile", "gift"}
+	labels := map[string]string{"channel": "web", "source": "checkout"}
+	b.ReportAllocs()
+	for n := 0; n < b.N; n++ {
+		if _, err := json.Marshal(&shipment{ID: int64(n), Customer: "tom", Carrier: "express", Priority: 3,
+			Insured: true, Region: "eu-west", Tags: tags, Labels: labels}); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
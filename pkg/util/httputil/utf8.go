@@ -0,0 +1,151 @@
+package httputil
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// UTF8Mode tells how DecodeJSON handles invalid UTF-8 in body
+type UTF8Mode int
+
+const (
+	// UTF8Strict fails with *InvalidUTF8Error, it is the default
+	UTF8Strict UTF8Mode = iota
+	// UTF8Sanitize replaces invalid UTF-8 with U+FFFD before decoding
+	UTF8Sanitize
+)
+
+// InvalidUTF8Error tells where the invalid UTF-8 is, encoding/json would silently replace it
+type InvalidUTF8Error struct {
+	// Offset is the byte offset in body
+	Offset int
+	// Field is the path of the value, such as user.tags[1], it is empty if invalid byte is not in a value of object or array
+	Field string
+}
+
+// Error returns error message
+func (e *InvalidUTF8Error) Error() string {
+	if e.Field == "" {
+		return fmt.Sprintf("invalid UTF-8 in JSON at offset %d", e.Offset)
+	}
+	return fmt.Sprintf("invalid UTF-8 in JSON field [%s] at offset %d", e.Field, e.Offset)
+}
+
+// DecodeJSON reads response body and decodes it into target, body is closed.
+// invalid UTF-8 is handled by mode
+func DecodeJSON(resp *http.Response, target interface{}, mode UTF8Mode) error {
+	b, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return err
+	}
+	if !utf8.Valid(b) {
+		if mode != UTF8Sanitize {
+			return invalidUTF8(b)
+		}
+		b = bytes.ToValidUTF8(b, []byte("\uFFFD"))
+	}
+	return json.Unmarshal(b, target)
+}
+
+// jsonScope is an object or array which contains the byte being scanned
+type jsonScope struct {
+	array bool
+	index int
+	key   string
+	// inValue is true if colon after key of object is seen
+	inValue bool
+}
+
+// invalidUTF8 finds the first invalid UTF-8 in b and the path of JSON value it is in,
+// b is scanned as JSON without validating its grammar
+func invalidUTF8(b []byte) error {
+	var scopes []jsonScope
+	inString, isKey := false, false
+	var str []byte
+	for i := 0; i < len(b); {
+		c := b[i]
+		if c >= utf8.RuneSelf {
+			r, size := utf8.DecodeRune(b[i:])
+			if r == utf8.RuneError && size == 1 {
+				return &InvalidUTF8Error{Offset: i, Field: jsonPath(scopes, inString && isKey)}
+			}
+			if inString {
+				str = append(str, b[i:i+size]...)
+			}
+			i += size
+			continue
+		}
+		i++
+		if inString {
+			switch c {
+			case '\\':
+				if i < len(b) {
+					str = append(str, c, b[i])
+					i++
+				}
+			case '"':
+				inString = false
+				if isKey {
+					if s, err := strconv.Unquote(`"` + string(str) + `"`); err == nil {
+						scopes[len(scopes)-1].key = s
+					} else {
+						scopes[len(scopes)-1].key = string(str)
+					}
+				}
+			default:
+				str = append(str, c)
+			}
+			continue
+		}
+		switch c {
+		case '"':
+			inString, str = true, str[:0]
+			isKey = len(scopes) > 0 && !scopes[len(scopes)-1].array && !scopes[len(scopes)-1].inValue
+		case '{':
+			scopes = append(scopes, jsonScope{})
+		case '[':
+			scopes = append(scopes, jsonScope{array: true})
+		case '}', ']':
+			if len(scopes) > 0 {
+				scopes = scopes[:len(scopes)-1]
+			}
+		case ':':
+			if len(scopes) > 0 {
+				scopes[len(scopes)-1].inValue = true
+			}
+		case ',':
+			if len(scopes) > 0 {
+				s := &scopes[len(scopes)-1]
+				s.index++
+				s.inValue = false
+			}
+		}
+	}
+	return &InvalidUTF8Error{Offset: len(b)}
+}
+
+// jsonPath joins keys and indexes of scopes, the key of innermost object is not included if it is being scanned
+func jsonPath(scopes []jsonScope, inKey bool) string {
+	var sb strings.Builder
+	for n, s := range scopes {
+		if s.array {
+			sb.WriteString("[" + strconv.Itoa(s.index) + "]")
+			continue
+		}
+		if n == len(scopes)-1 && inKey {
+			break
+		}
+		if sb.Len() > 0 {
+			sb.WriteByte('.')
+		}
+		sb.WriteString(s.key)
+	}
+	return sb.String()
+}
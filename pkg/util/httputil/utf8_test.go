@@ -0,0 +1,52 @@
+package httputil_test
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"testing"
+
+	"github.com/go-chassis/go-chassis/v2/pkg/util/httputil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDecodeJSON(t *testing.T) {
+	type user struct {
+		Name string   `json:"name"`
+		Tags []string `json:"tags"`
+	}
+	type reply struct {
+		ID   int  `json:"id"`
+		User user `json:"user"`
+	}
+	resp := func(body string) *http.Response {
+		return &http.Response{Body: ioutil.NopCloser(bytes.NewReader([]byte(body)))}
+	}
+	body := "{\"id\": 1, \"user\": {\"name\": \"tom\", \"tags\": [\"a\", \"b\xffc\"]}}"
+
+	t.Run("strict mode points at field", func(t *testing.T) {
+		var r reply
+		err := httputil.DecodeJSON(resp(body), &r, httputil.UTF8Strict)
+		var invalid *httputil.InvalidUTF8Error
+		if assert.True(t, errors.As(err, &invalid), "got %v", err) {
+			assert.Equal(t, "user.tags[1]", invalid.Field)
+			assert.Equal(t, bytes.IndexByte([]byte(body), 0xff), invalid.Offset)
+			assert.EqualError(t, err, "invalid UTF-8 in JSON field [user.tags[1]] at offset 50")
+		}
+		err = httputil.DecodeJSON(resp("{\"id\": 1, \"na\xffme\": \"tom\"}"), &r, httputil.UTF8Strict)
+		if assert.True(t, errors.As(err, &invalid)) {
+			assert.Equal(t, "", invalid.Field, "invalid byte in key of top level object")
+		}
+	})
+	t.Run("sanitize mode replaces invalid bytes", func(t *testing.T) {
+		var r reply
+		assert.NoError(t, httputil.DecodeJSON(resp(body), &r, httputil.UTF8Sanitize))
+		assert.Equal(t, reply{ID: 1, User: user{Name: "tom", Tags: []string{"a", "b\uFFFDc"}}}, r)
+	})
+	t.Run("valid body", func(t *testing.T) {
+		var r reply
+		assert.NoError(t, httputil.DecodeJSON(resp(`{"id": 2, "user": {"name": "töm \"x\", y"}}`), &r, httputil.UTF8Strict))
+		assert.Equal(t, "töm \"x\", y", r.User.Name)
+	})
+}
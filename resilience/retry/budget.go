@@ -0,0 +1,110 @@
+package retry
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// budgetWindow and budgetWindowCalls decide how many tokens a budget can save,
+// it is the tokens refilled in budgetWindow plus the tokens deposited by budgetWindowCalls successful calls
+const (
+	budgetWindow      = 10 * time.Second
+	budgetWindowCalls = 100
+)
+
+// Budget is a token bucket which limits retries to a ratio of successful calls,
+// so that retries can not amplify the load when upstream is failing.
+// each success deposits ratio tokens, each retry withdraws one token,
+// and minPerSecond tokens are refilled every second even if no call succeeds
+type Budget struct {
+	mu           sync.Mutex
+	ratio        float64
+	minPerSecond float64
+	max          float64
+	tokens       float64
+	last         time.Time
+	now          func() time.Time
+}
+
+// NewBudget creates a retry budget, for example ratio 0.1 allows one retry for every 10 successful calls.
+// it saves at most the tokens of 100 successful calls and 10 seconds of refill, at least 1 token,
+// the budget is full when it is created
+func NewBudget(ratio float64, minPerSecond int) *Budget {
+	if ratio < 0 {
+		ratio = 0
+	}
+	if minPerSecond < 0 {
+		minPerSecond = 0
+	}
+	max := ratio*budgetWindowCalls + float64(minPerSecond)*budgetWindow.Seconds()
+	if max < 1 {
+		max = 1
+	}
+	b := &Budget{
+		ratio:        ratio,
+		minPerSecond: float64(minPerSecond),
+		max:          max,
+		tokens:       max,
+		now:          time.Now,
+	}
+	b.last = b.now()
+	return b
+}
+
+// refill must be called with lock held
+func (b *Budget) refill() {
+	now := b.now()
+	b.tokens += now.Sub(b.last).Seconds() * b.minPerSecond
+	b.last = now
+	if b.tokens > b.max {
+		b.tokens = b.max
+	}
+}
+
+// Deposit records a successful call
+func (b *Budget) Deposit() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.refill()
+	b.tokens += b.ratio
+	if b.tokens > b.max {
+		b.tokens = b.max
+	}
+}
+
+// Withdraw takes a token for a retry, it returns false if budget is exhausted and the call should not be retried.
+// a fraction of token is spent with the same probability, so that small ratio limits retries evenly
+func (b *Budget) Withdraw() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.refill()
+	if b.tokens >= 1 {
+		b.tokens--
+		return true
+	}
+	if b.tokens > 0 && rand.Float64() < b.tokens {
+		b.tokens = 0
+		return true
+	}
+	return false
+}
+
+var (
+	budgetMu      sync.RWMutex
+	defaultBudget *Budget
+)
+
+// SetBudget sets the retry budget shared by all calls of client, nil removes it, and retries are not limited
+func SetBudget(b *Budget) {
+	budgetMu.Lock()
+	defaultBudget = b
+	budgetMu.Unlock()
+}
+
+// GetBudget returns the retry budget shared by all calls of client, it returns nil if there is none
+func GetBudget() *Budget {
+	budgetMu.RLock()
+	defer budgetMu.RUnlock()
+	return defaultBudget
+}
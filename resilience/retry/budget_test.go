@@ -0,0 +1,57 @@
+package retry
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBudget(t *testing.T) {
+	now := time.Now()
+	b := NewBudget(0.5, 1)
+	b.now = func() time.Time { return now }
+	b.last = now
+
+	// 50 tokens of 100 successful calls and 10 tokens refilled in 10 seconds
+	for n := 0; n < 60; n++ {
+		assert.True(t, b.Withdraw(), "budget is full at first")
+	}
+	assert.False(t, b.Withdraw())
+
+	t.Run("successes refill budget by ratio", func(t *testing.T) {
+		b.Deposit()
+		b.Deposit()
+		assert.True(t, b.Withdraw())
+		assert.False(t, b.Withdraw())
+	})
+	t.Run("min per second refills budget", func(t *testing.T) {
+		now = now.Add(2 * time.Second)
+		assert.True(t, b.Withdraw())
+		assert.True(t, b.Withdraw())
+		assert.False(t, b.Withdraw())
+	})
+	t.Run("budget is capped", func(t *testing.T) {
+		now = now.Add(time.Hour)
+		for n := 0; n < 1000; n++ {
+			b.Deposit()
+		}
+		for n := 0; n < 60; n++ {
+			assert.True(t, b.Withdraw())
+		}
+		assert.False(t, b.Withdraw())
+	})
+}
+
+func TestBudget_BurstAfterSuccesses(t *testing.T) {
+	b := NewBudget(0.1, 0)
+	for b.Withdraw() {
+	}
+	for n := 0; n < 1000; n++ {
+		b.Deposit()
+	}
+	for n := 0; n < 10; n++ {
+		assert.True(t, b.Withdraw(), "tokens of many successes are saved without min per second")
+	}
+	assert.False(t, b.Withdraw())
+}
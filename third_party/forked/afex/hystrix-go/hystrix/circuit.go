@@ -232,3 +232,66 @@ func (circuit *CircuitBreaker) ReportEvent(eventTypes []string, start time.Time,
 
 	return nil
 }
+
+// CircuitState is the state of a circuit breaker
+type CircuitState int
+
+// states of circuit breaker, values are exported as gauge
+const (
+	// StateClosed means requests are allowed
+	StateClosed CircuitState = iota
+	// StateOpen means requests are rejected
+	StateOpen
+	// StateHalfOpen means circuit is open and sleep window is over, a single test request is allowed
+	StateHalfOpen
+)
+
+// CircuitStats is the current state and rolling window statistics of a circuit breaker
+type CircuitStats struct {
+	Name  string
+	State CircuitState
+	// FailureRatio is errors divided by requests in the window, from 0 to 1
+	FailureRatio float64
+	// Requests is the request volume in the window
+	Requests float64
+}
+
+// State returns the current state, unlike IsOpen it never opens the circuit
+func (circuit *CircuitBreaker) State() CircuitState {
+	circuit.mutex.RLock()
+	defer circuit.mutex.RUnlock()
+	if circuit.forceOpen {
+		return StateOpen
+	}
+	if !circuit.open {
+		return StateClosed
+	}
+	if time.Now().UnixNano() > atomic.LoadInt64(&circuit.openedOrLastTestedTime)+getSettings(circuit.Name).SleepWindow.Nanoseconds() {
+		return StateHalfOpen
+	}
+	return StateOpen
+}
+
+// Stats returns the current state and rolling window statistics
+func (circuit *CircuitBreaker) Stats(now time.Time) CircuitStats {
+	s := CircuitStats{Name: circuit.Name, State: circuit.State()}
+	circuit.Metrics.Mutex.RLock()
+	defer circuit.Metrics.Mutex.RUnlock()
+	s.Requests = circuit.Metrics.requestsLocked().Sum(now)
+	if s.Requests > 0 {
+		s.FailureRatio = circuit.Metrics.DefaultCollector().Errors().Sum(now) / s.Requests
+	}
+	return s
+}
+
+// AllStats returns statistics of all circuit breakers, key is circuit name
+func AllStats() map[string]CircuitStats {
+	now := time.Now()
+	circuitBreakersMutex.RLock()
+	defer circuitBreakersMutex.RUnlock()
+	stats := make(map[string]CircuitStats, len(circuitBreakers))
+	for name, cb := range circuitBreakers {
+		stats[name] = cb.Stats(now)
+	}
+	return stats
+}